@@ -0,0 +1,234 @@
+/*
+Package biblecorpus reads the chapter-file corpus the scraper writes
+(<root>/<lang>/<lang>_<BOOK>_<Name>_<CCC>.txt). It is the one place that knows
+the corpus layout, so every tool that reads the corpus goes through it.
+*/
+package biblecorpus
+
+import (
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/zrygan.nlp/bible_cleaning/canon"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// Chapter is one chapter file of the corpus.
+type Chapter struct {
+	types.ChapterFile
+	Path string
+}
+
+// Verse is a verse read from the corpus, with the chapter it belongs to.
+type Verse struct {
+	Language string
+	Book     string
+	Chapter  int
+	types.Verse
+}
+
+// Index lists the chapter files of a corpus by language, in canonical book order.
+type Index struct {
+	Root     string
+	Skipped  []string // files under Root that are not named like chapter files
+	chapters map[string][]Chapter
+}
+
+/*
+Open indexes the corpus at root. A missing root is an error, so a tool
+pointed at the wrong folder fails instead of finding no languages.
+*/
+func Open(root string) (*Index, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("corpus %s is not a directory", root)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(root, "*", "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	ix := &Index{Root: root, chapters: make(map[string][]Chapter)}
+	for _, path := range paths {
+		file, err := types.ParseChapterFileName(path)
+		if err != nil || file.Language != filepath.Base(filepath.Dir(path)) {
+			ix.Skipped = append(ix.Skipped, path)
+			continue
+		}
+		ix.chapters[file.Language] = append(ix.chapters[file.Language], Chapter{ChapterFile: file, Path: path})
+	}
+
+	for _, chapters := range ix.chapters {
+		sortChapters(chapters)
+	}
+	return ix, nil
+}
+
+// sortChapters orders chapters by canonical book order, unknown books last, then chapter.
+func sortChapters(chapters []Chapter) {
+	position := func(book string) int {
+		if pos := canon.Position(book); pos >= 0 {
+			return pos
+		}
+		return len(canon.Books)
+	}
+
+	sort.Slice(chapters, func(i, j int) bool {
+		a, b := chapters[i], chapters[j]
+		if pa, pb := position(a.Book), position(b.Book); pa != pb {
+			return pa < pb
+		}
+		if a.Book != b.Book {
+			return a.Book < b.Book
+		}
+		return a.Chapter < b.Chapter
+	})
+}
+
+// Languages returns the languages in the corpus, sorted.
+func (ix *Index) Languages() []string {
+	langs := make([]string, 0, len(ix.chapters))
+	for lang := range ix.chapters {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Chapters returns the chapters of a language in canonical order.
+func (ix *Index) Chapters(lang string) []Chapter {
+	return ix.chapters[lang]
+}
+
+// Lookup finds a chapter of a language by its "BOOK_CCC" ID.
+func (ix *Index) Lookup(lang, id string) (Chapter, bool) {
+	for _, chapter := range ix.chapters[lang] {
+		if chapter.ID() == id {
+			return chapter, true
+		}
+	}
+	return Chapter{}, false
+}
+
+// FileMap returns the chapter paths by language, then by "BOOK_CCC" chapter ID.
+func (ix *Index) FileMap() map[string]map[string]string {
+	files := make(map[string]map[string]string, len(ix.chapters))
+	for lang, chapters := range ix.chapters {
+		files[lang] = make(map[string]string, len(chapters))
+		for _, chapter := range chapters {
+			files[lang][chapter.ID()] = chapter.Path
+		}
+	}
+	return files
+}
+
+// filter returns a copy of the index keeping the chapters keep accepts.
+func (ix *Index) filter(keep func(Chapter) bool) *Index {
+	out := &Index{Root: ix.Root, Skipped: ix.Skipped, chapters: make(map[string][]Chapter)}
+	for lang, chapters := range ix.chapters {
+		for _, chapter := range chapters {
+			if keep(chapter) {
+				out.chapters[lang] = append(out.chapters[lang], chapter)
+			}
+		}
+	}
+	return out
+}
+
+// WithLanguages keeps only the given languages.
+func (ix *Index) WithLanguages(langs ...string) *Index {
+	return ix.filter(func(c Chapter) bool {
+		return slices.Contains(langs, c.Language)
+	})
+}
+
+// WithBooks keeps only the chapters of the given books.
+func (ix *Index) WithBooks(books []canon.Book) *Index {
+	codes := make(map[string]bool, len(books))
+	for _, b := range books {
+		codes[b.Code] = true
+	}
+	return ix.filter(func(c Chapter) bool {
+		return codes[c.Book]
+	})
+}
+
+// WithTestaments keeps only the chapters of the books of the given testaments.
+func (ix *Index) WithTestaments(testaments ...canon.Testament) *Index {
+	return ix.WithBooks(canon.ByTestament(testaments...))
+}
+
+// Verses streams the verses of the chapter, one line of the file at a time.
+func (c Chapter) Verses() iter.Seq2[types.Verse, error] {
+	return types.ScanChapterFile(c.Path)
+}
+
+/*
+Verses iterates over every verse of a language in canonical order, holding no
+more than one line of the corpus in memory. A chapter file that cannot be read
+is yielded as an error; the caller may keep going or stop.
+*/
+func (ix *Index) Verses(lang string) iter.Seq2[Verse, error] {
+	return func(yield func(Verse, error) bool) {
+		for _, chapter := range ix.chapters[lang] {
+			for v, err := range chapter.Verses() {
+				if err != nil {
+					if !yield(Verse{}, fmt.Errorf("%s: %w", chapter.Path, err)) {
+						return
+					}
+					break
+				}
+
+				verse := Verse{Language: lang, Book: chapter.Book, Chapter: chapter.Chapter, Verse: v}
+				if !yield(verse, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Sentence is a sentence of a by_sentences corpus, numbered from 1 within its verse.
+type Sentence struct {
+	Verse
+	Index int
+}
+
+/*
+Sentences iterates over every sentence of a language of a by_sentences corpus,
+where each row of a chapter file is one sentence of the verse it is labelled
+with. Errors are yielded as in Verses.
+*/
+func (ix *Index) Sentences(lang string) iter.Seq2[Sentence, error] {
+	return func(yield func(Sentence, error) bool) {
+		var previous Verse
+		index := 0
+		for verse, err := range ix.Verses(lang) {
+			if err != nil {
+				if !yield(Sentence{}, err) {
+					return
+				}
+				continue
+			}
+
+			if verse.Book == previous.Book && verse.Chapter == previous.Chapter && verse.Number == previous.Number {
+				index++
+			} else {
+				index = 1
+			}
+			previous = verse
+
+			if !yield(Sentence{Verse: verse, Index: index}, nil) {
+				return
+			}
+		}
+	}
+}
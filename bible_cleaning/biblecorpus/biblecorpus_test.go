@@ -0,0 +1,238 @@
+package biblecorpus
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/canon"
+)
+
+// writeCorpus lays out a small by_verses tree under a temporary root.
+func writeCorpus(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+var testCorpus = map[string]string{
+	"tgl/tgl_MAT_Mateo_001.txt":    "verse\tcontent\n001\tAng aklat ng lahi ni Jesucristo.\n",
+	"tgl/tgl_GEN_Genesis_010.txt":  "verse\tcontent\n001\tIto nga ang mga lahi.\n",
+	"tgl/tgl_GEN_Genesis_002.txt":  "verse\tcontent\n001\tAt nayari ang langit.\n002-003\tAt nang ikapitong araw.\n",
+	"tgl/tgl_TOB_Tobit_001.txt":    "verse\tcontent\n001\tAng aklat ni Tobit.\n",
+	"tgl/tgl_XYZ_Wala_001.txt":     "verse\tcontent\n001\tHindi kilalang aklat.\n",
+	"ceb/ceb_JHN_Juan_011.txt":     "Karon may usa ka tawo nga nagmasakiton.\n\nMihilak si Jesus.\n",
+	"tgl/notes.txt":                "hindi kabanata\n",
+	"ceb/tgl_GEN_Genesis_001.txt":  "verse\tcontent\n001\tNasa maling wika.\n",
+	"README.txt":                   "not in a language folder\n",
+	"ilo/ilo_GEN_Genesis_001.json": "{}",
+}
+
+func chapterIDs(chapters []Chapter) []string {
+	var ids []string
+	for _, c := range chapters {
+		ids = append(ids, c.ID())
+	}
+	return ids
+}
+
+func TestOpen(t *testing.T) {
+	root := writeCorpus(t, testCorpus)
+	ix, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if langs := ix.Languages(); !slices.Equal(langs, []string{"ceb", "tgl"}) {
+		t.Errorf("languages %v, want [ceb tgl]", langs)
+	}
+
+	want := []string{"GEN_002", "GEN_010", "MAT_001", "TOB_001", "XYZ_001"}
+	if got := chapterIDs(ix.Chapters("tgl")); !slices.Equal(got, want) {
+		t.Errorf("tgl chapters %v, want %v in canonical order, unknown books last", got, want)
+	}
+
+	var skipped []string
+	for _, path := range ix.Skipped {
+		rel, _ := filepath.Rel(root, path)
+		skipped = append(skipped, filepath.ToSlash(rel))
+	}
+	slices.Sort(skipped)
+	if !slices.Equal(skipped, []string{"ceb/tgl_GEN_Genesis_001.txt", "tgl/notes.txt"}) {
+		t.Errorf("skipped %v, want the misnamed and misplaced files", skipped)
+	}
+
+	if chapter, ok := ix.Lookup("ceb", "JHN_011"); !ok || chapter.Name != "Juan" || chapter.Chapter != 11 {
+		t.Errorf("Lookup(ceb, JHN_011) = %+v, %v", chapter, ok)
+	}
+	if _, ok := ix.Lookup("ceb", "GEN_001"); ok {
+		t.Error("Lookup(ceb, GEN_001) found the file misplaced under ceb")
+	}
+	if files := ix.FileMap(); files["tgl"]["GEN_002"] != filepath.Join(root, "tgl", "tgl_GEN_Genesis_002.txt") || len(files["tgl"]) != 5 {
+		t.Errorf("file map %v", files)
+	}
+
+	if _, err := Open(filepath.Join(root, "by_verses")); err == nil {
+		t.Error("Open of a missing root made an index, want an error")
+	}
+	if _, err := Open(filepath.Join(root, "README.txt")); err == nil {
+		t.Error("Open of a file made an index, want an error")
+	}
+}
+
+func TestFilters(t *testing.T) {
+	ix, err := Open(writeCorpus(t, testCorpus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis, err := canon.Select("GEN,JHN")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		index *Index
+		want  map[string][]string
+	}{
+		{"books", ix.WithBooks(genesis), map[string][]string{"tgl": {"GEN_002", "GEN_010"}, "ceb": {"JHN_011"}}},
+		{"Old Testament", ix.WithTestaments(canon.OldTestament), map[string][]string{"tgl": {"GEN_002", "GEN_010"}}},
+		{"New Testament and deuterocanon", ix.WithTestaments(canon.NewTestament, canon.Deuterocanon), map[string][]string{"tgl": {"MAT_001", "TOB_001"}, "ceb": {"JHN_011"}}},
+		{"languages", ix.WithLanguages("ceb", "ilo"), map[string][]string{"ceb": {"JHN_011"}}},
+		{"chained", ix.WithLanguages("tgl").WithTestaments(canon.NewTestament), map[string][]string{"tgl": {"MAT_001"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var langs []string
+			for lang := range tt.want {
+				langs = append(langs, lang)
+			}
+			slices.Sort(langs)
+			if got := tt.index.Languages(); !slices.Equal(got, langs) {
+				t.Errorf("languages %v, want %v", got, langs)
+			}
+			for lang, want := range tt.want {
+				if got := chapterIDs(tt.index.Chapters(lang)); !slices.Equal(got, want) {
+					t.Errorf("%s chapters %v, want %v", lang, got, want)
+				}
+			}
+			if len(tt.index.Skipped) != len(ix.Skipped) {
+				t.Errorf("%d skipped files, want the %d of the whole corpus", len(tt.index.Skipped), len(ix.Skipped))
+			}
+		})
+	}
+
+	if got := chapterIDs(ix.Chapters("tgl")); len(got) != 5 {
+		t.Errorf("filtering changed the original index: tgl has %v", got)
+	}
+}
+
+func TestVerses(t *testing.T) {
+	ix, err := Open(writeCorpus(t, testCorpus))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for verse, err := range ix.WithBooks(canon.Books[:1]).Verses("tgl") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, verse.Language+" "+verse.Book+" "+verse.Number.String()+" "+verse.Text)
+		if verse.Chapter != 2 && verse.Chapter != 10 {
+			t.Errorf("verse %+v of chapter %d", verse, verse.Chapter)
+		}
+	}
+	want := []string{
+		"tgl GEN 001 At nayari ang langit.",
+		"tgl GEN 002-003 At nang ikapitong araw.",
+		"tgl GEN 001 Ito nga ang mga lahi.",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("verses\n%q\nwant\n%q", got, want)
+	}
+
+	// the old one-verse-per-line format numbers verses by line
+	var numbers []string
+	for verse, err := range ix.Verses("ceb") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, verse.Number.String())
+	}
+	if !slices.Equal(numbers, []string{"001", "003"}) {
+		t.Errorf("ceb verses %v, want 001 and 003", numbers)
+	}
+
+	// stopping early ends the iteration
+	count := 0
+	for range ix.Verses("tgl") {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("read %d verses after breaking at 2", count)
+	}
+}
+
+func TestVersesError(t *testing.T) {
+	ix, err := Open(writeCorpus(t, map[string]string{
+		"tgl/tgl_GEN_Genesis_001.txt": "verse\tcontent\n001\tNang pasimula.\nisa\tSira.\n002\tHindi na mababasa.\n",
+		"tgl/tgl_GEN_Genesis_002.txt": "verse\tcontent\n001\tAt nayari ang langit.\n",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var texts, errs []string
+	for verse, err := range ix.Verses("tgl") {
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		texts = append(texts, verse.Text)
+	}
+	if !slices.Equal(texts, []string{"Nang pasimula.", "At nayari ang langit."}) {
+		t.Errorf("verses %q, want the one before the error and the next chapter", texts)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0], "tgl_GEN_Genesis_001.txt:3") {
+		t.Errorf("errors %q, want one naming the file and line", errs)
+	}
+}
+
+func TestSentences(t *testing.T) {
+	ix, err := Open(writeCorpus(t, map[string]string{
+		"tgl/tgl_GEN_Genesis_001.txt": "verse\tcontent\n001\tNang pasimula.\n001\tNilikha ng Dios.\n002\tAt ang lupa.\n",
+		"tgl/tgl_GEN_Genesis_002.txt": "verse\tcontent\n002\tAt nayari.\n",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for sentence, err := range ix.Sentences("tgl") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, sentence.Book+" "+sentence.Number.String()+" "+strings.Repeat("#", sentence.Index))
+	}
+	want := []string{"GEN 001 #", "GEN 001 ##", "GEN 002 #", "GEN 002 #"}
+	if !slices.Equal(got, want) {
+		t.Errorf("sentences %q, want %q", got, want)
+	}
+}
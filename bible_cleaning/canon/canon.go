@@ -0,0 +1,298 @@
+package canon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Testament string
+
+const (
+	OldTestament Testament = "OT"
+	NewTestament Testament = "NT"
+	Deuterocanon Testament = "DC"
+)
+
+// Book is one book of the canon, identified by its USFM code as bible.com uses it.
+type Book struct {
+	Code      string
+	Name      string
+	Chapters  int
+	Testament Testament
+}
+
+/*
+Books lists the canon in reading order: the 39 books of the Old Testament,
+the 27 of the New Testament, then the deuterocanonical books.
+Chapter counts follow the English (KJV) versification, and for the
+deuterocanon the USFM one (eng.vrs): Greek Esther keeps its additions as
+chapters 11-16. Translations that follow the Hebrew versification (e.g. Joel
+with 4 chapters) show up as extra chapters.
+*/
+var Books = []Book{
+	{"GEN", "Genesis", 50, OldTestament},
+	{"EXO", "Exodus", 40, OldTestament},
+	{"LEV", "Leviticus", 27, OldTestament},
+	{"NUM", "Numbers", 36, OldTestament},
+	{"DEU", "Deuteronomy", 34, OldTestament},
+	{"JOS", "Joshua", 24, OldTestament},
+	{"JDG", "Judges", 21, OldTestament},
+	{"RUT", "Ruth", 4, OldTestament},
+	{"1SA", "1 Samuel", 31, OldTestament},
+	{"2SA", "2 Samuel", 24, OldTestament},
+	{"1KI", "1 Kings", 22, OldTestament},
+	{"2KI", "2 Kings", 25, OldTestament},
+	{"1CH", "1 Chronicles", 29, OldTestament},
+	{"2CH", "2 Chronicles", 36, OldTestament},
+	{"EZR", "Ezra", 10, OldTestament},
+	{"NEH", "Nehemiah", 13, OldTestament},
+	{"EST", "Esther", 10, OldTestament},
+	{"JOB", "Job", 42, OldTestament},
+	{"PSA", "Psalms", 150, OldTestament},
+	{"PRO", "Proverbs", 31, OldTestament},
+	{"ECC", "Ecclesiastes", 12, OldTestament},
+	{"SNG", "Song of Songs", 8, OldTestament},
+	{"ISA", "Isaiah", 66, OldTestament},
+	{"JER", "Jeremiah", 52, OldTestament},
+	{"LAM", "Lamentations", 5, OldTestament},
+	{"EZK", "Ezekiel", 48, OldTestament},
+	{"DAN", "Daniel", 12, OldTestament},
+	{"HOS", "Hosea", 14, OldTestament},
+	{"JOL", "Joel", 3, OldTestament},
+	{"AMO", "Amos", 9, OldTestament},
+	{"OBA", "Obadiah", 1, OldTestament},
+	{"JON", "Jonah", 4, OldTestament},
+	{"MIC", "Micah", 7, OldTestament},
+	{"NAM", "Nahum", 3, OldTestament},
+	{"HAB", "Habakkuk", 3, OldTestament},
+	{"ZEP", "Zephaniah", 3, OldTestament},
+	{"HAG", "Haggai", 2, OldTestament},
+	{"ZEC", "Zechariah", 14, OldTestament},
+	{"MAL", "Malachi", 4, OldTestament},
+
+	{"MAT", "Matthew", 28, NewTestament},
+	{"MRK", "Mark", 16, NewTestament},
+	{"LUK", "Luke", 24, NewTestament},
+	{"JHN", "John", 21, NewTestament},
+	{"ACT", "Acts", 28, NewTestament},
+	{"ROM", "Romans", 16, NewTestament},
+	{"1CO", "1 Corinthians", 16, NewTestament},
+	{"2CO", "2 Corinthians", 13, NewTestament},
+	{"GAL", "Galatians", 6, NewTestament},
+	{"EPH", "Ephesians", 6, NewTestament},
+	{"PHP", "Philippians", 4, NewTestament},
+	{"COL", "Colossians", 4, NewTestament},
+	{"1TH", "1 Thessalonians", 5, NewTestament},
+	{"2TH", "2 Thessalonians", 3, NewTestament},
+	{"1TI", "1 Timothy", 6, NewTestament},
+	{"2TI", "2 Timothy", 4, NewTestament},
+	{"TIT", "Titus", 3, NewTestament},
+	{"PHM", "Philemon", 1, NewTestament},
+	{"HEB", "Hebrews", 13, NewTestament},
+	{"JAS", "James", 5, NewTestament},
+	{"1PE", "1 Peter", 5, NewTestament},
+	{"2PE", "2 Peter", 3, NewTestament},
+	{"1JN", "1 John", 5, NewTestament},
+	{"2JN", "2 John", 1, NewTestament},
+	{"3JN", "3 John", 1, NewTestament},
+	{"JUD", "Jude", 1, NewTestament},
+	{"REV", "Revelation", 22, NewTestament},
+
+	{"TOB", "Tobit", 14, Deuterocanon},
+	{"JDT", "Judith", 16, Deuterocanon},
+	{"ESG", "Esther (Greek)", 16, Deuterocanon},
+	{"WIS", "Wisdom of Solomon", 19, Deuterocanon},
+	{"SIR", "Sirach", 51, Deuterocanon},
+	{"BAR", "Baruch", 6, Deuterocanon},
+	{"LJE", "Letter of Jeremiah", 1, Deuterocanon},
+	{"S3Y", "Song of the Three Young Men", 1, Deuterocanon},
+	{"SUS", "Susanna", 1, Deuterocanon},
+	{"BEL", "Bel and the Dragon", 1, Deuterocanon},
+	{"1MA", "1 Maccabees", 16, Deuterocanon},
+	{"2MA", "2 Maccabees", 15, Deuterocanon},
+	{"1ES", "1 Esdras", 9, Deuterocanon},
+	{"2ES", "2 Esdras", 16, Deuterocanon},
+	{"MAN", "Prayer of Manasseh", 1, Deuterocanon},
+	{"PS2", "Psalm 151", 1, Deuterocanon},
+	{"3MA", "3 Maccabees", 7, Deuterocanon},
+	{"4MA", "4 Maccabees", 18, Deuterocanon},
+}
+
+var byCode = func() map[string]Book {
+	index := make(map[string]Book, len(Books))
+	for _, b := range Books {
+		index[b.Code] = b
+	}
+	return index
+}()
+
+// positions is the canonical index of every book, by code; sorting pair IDs looks it up on every comparison.
+var positions = func() map[string]int {
+	index := make(map[string]int, len(Books))
+	for i, b := range Books {
+		index[b.Code] = i
+	}
+	return index
+}()
+
+// Lookup finds a book by its USFM code.
+func Lookup(code string) (Book, bool) {
+	b, ok := byCode[strings.ToUpper(code)]
+	return b, ok
+}
+
+// Position returns the index of a book in canonical order, or -1 if it is unknown.
+func Position(code string) int {
+	if i, ok := positions[code]; ok {
+		return i
+	}
+	return -1
+}
+
+// ByTestament returns the books of the given testaments in canonical order.
+func ByTestament(testaments ...Testament) []Book {
+	var books []Book
+	for _, b := range Books {
+		for _, t := range testaments {
+			if b.Testament == t {
+				books = append(books, b)
+				break
+			}
+		}
+	}
+	return books
+}
+
+/*
+Select parses a comma-separated book selection. Each item is either a USFM
+book code ("GEN", "1CO") or a group: "ot", "nt", "dc", "protestant" (OT and NT)
+or "all". Duplicates are dropped and canonical order is kept.
+*/
+func Select(spec string) ([]Book, error) {
+	selected := make(map[string]bool)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var books []Book
+		switch strings.ToLower(item) {
+		case "ot":
+			books = ByTestament(OldTestament)
+		case "nt":
+			books = ByTestament(NewTestament)
+		case "dc":
+			books = ByTestament(Deuterocanon)
+		case "protestant":
+			books = ByTestament(OldTestament, NewTestament)
+		case "all":
+			books = Books
+		default:
+			b, ok := Lookup(item)
+			if !ok {
+				return nil, fmt.Errorf("unknown book %q", item)
+			}
+			books = []Book{b}
+		}
+
+		for _, b := range books {
+			selected[b.Code] = true
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("empty book selection %q", spec)
+	}
+
+	var books []Book
+	for _, b := range Books {
+		if selected[b.Code] {
+			books = append(books, b)
+		}
+	}
+	return books, nil
+}
+
+// Coverage compares the chapters a translation has against the canon.
+type Coverage struct {
+	MissingBooks    []string         // books with none of their chapters
+	MissingChapters map[string][]int // partially present books and the chapters they lack
+	ExtraChapters   map[string][]int // chapters beyond the canon's count, or of unknown books
+	Chapters        int              // chapters found of the compared books, within their count
+	Expected        int              // chapters in the compared books
+}
+
+/*
+CheckCoverage reports which of the given books and chapters are absent from
+found, a map of book code to the chapter numbers a translation has. Books of
+the canon that were not compared are left out; chapters of unknown books are extra.
+*/
+func CheckCoverage(books []Book, found map[string][]int) Coverage {
+	coverage := Coverage{
+		MissingChapters: make(map[string][]int),
+		ExtraChapters:   make(map[string][]int),
+	}
+
+	compared := make(map[string]bool, len(books))
+	for _, b := range books {
+		compared[b.Code] = true
+		coverage.Expected += b.Chapters
+
+		have := make(map[int]bool)
+		for _, ch := range found[b.Code] {
+			have[ch] = true
+		}
+
+		if len(have) == 0 {
+			coverage.MissingBooks = append(coverage.MissingBooks, b.Code)
+			continue
+		}
+
+		for ch := 1; ch <= b.Chapters; ch++ {
+			if have[ch] {
+				coverage.Chapters++
+			} else {
+				coverage.MissingChapters[b.Code] = append(coverage.MissingChapters[b.Code], ch)
+			}
+		}
+		for ch := range have {
+			if ch < 1 || ch > b.Chapters {
+				coverage.ExtraChapters[b.Code] = append(coverage.ExtraChapters[b.Code], ch)
+			}
+		}
+		sort.Ints(coverage.ExtraChapters[b.Code])
+	}
+
+	for code, chapters := range found {
+		if _, known := Lookup(code); !known && !compared[code] {
+			coverage.ExtraChapters[code] = append([]int(nil), chapters...)
+			sort.Ints(coverage.ExtraChapters[code])
+		}
+	}
+
+	return coverage
+}
+
+// FormatChapters renders chapter numbers compactly, e.g. "1-3,7,9-10".
+func FormatChapters(chapters []int) string {
+	sorted := append([]int(nil), chapters...)
+	sort.Ints(sorted)
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprint(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,128 @@
+package canon
+
+import (
+	"slices"
+	"testing"
+)
+
+func codes(books []Book) []string {
+	var codes []string
+	for _, b := range books {
+		codes = append(codes, b.Code)
+	}
+	return codes
+}
+
+func TestBooks(t *testing.T) {
+	counts := map[Testament]int{}
+	for i, b := range Books {
+		counts[b.Testament]++
+		if Position(b.Code) != i {
+			t.Errorf("Position(%s) = %d, want %d", b.Code, Position(b.Code), i)
+		}
+	}
+	if counts[OldTestament] != 39 || counts[NewTestament] != 27 {
+		t.Errorf("%d OT and %d NT books, want 39 and 27", counts[OldTestament], counts[NewTestament])
+	}
+	if Position("XYZ") != -1 {
+		t.Errorf("Position(XYZ) = %d, want -1", Position("XYZ"))
+	}
+	if esg, _ := Lookup("esg"); esg.Chapters != 16 {
+		t.Errorf("Greek Esther has %d chapters, want 16", esg.Chapters)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		spec  string
+		first []string // the first books selected
+		count int
+	}{
+		{"GEN", []string{"GEN"}, 1},
+		{"mat, gen", []string{"GEN", "MAT"}, 2}, // canonical order, any case
+		{"GEN,GEN,ot", []string{"GEN", "EXO"}, 39},
+		{"nt", []string{"MAT", "MRK"}, 27},
+		{"dc", []string{"TOB", "JDT"}, len(Books) - 66},
+		{"protestant", []string{"GEN"}, 66},
+		{"nt,ot", []string{"GEN"}, 66},
+		{"all", []string{"GEN"}, len(Books)},
+		{"TOB,,nt,", []string{"MAT"}, 28},
+	}
+	for _, tt := range tests {
+		books, err := Select(tt.spec)
+		if err != nil {
+			t.Errorf("Select(%q): %v", tt.spec, err)
+			continue
+		}
+		got := codes(books)
+		if len(got) != tt.count || !slices.Equal(got[:len(tt.first)], tt.first) {
+			t.Errorf("Select(%q) = %d books starting %v, want %d starting %v", tt.spec, len(got), got[:min(len(got), 3)], tt.count, tt.first)
+		}
+		if !slices.IsSortedFunc(books, func(a, b Book) int { return Position(a.Code) - Position(b.Code) }) {
+			t.Errorf("Select(%q) is not in canonical order", tt.spec)
+		}
+	}
+
+	for _, spec := range []string{"", " , ", "GEN,XYZ", "apocrypha", "Genesis"} {
+		if books, err := Select(spec); err == nil {
+			t.Errorf("Select(%q) = %v, want an error", spec, codes(books))
+		}
+	}
+}
+
+func TestCheckCoverage(t *testing.T) {
+	books, err := Select("RUT,JOL,OBA,JUD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string][]int{
+		"RUT": {1, 2, 3, 4},
+		"JOL": {1, 2, 3, 4}, // Hebrew versification
+		"JUD": {1},
+		"TOB": {1, 2, 3}, // in the corpus, but not compared
+		"XYZ": {2, 1},
+	}
+
+	coverage := CheckCoverage(books, found)
+	if coverage.Expected != 4+3+1+1 {
+		t.Errorf("expected %d chapters, want 9", coverage.Expected)
+	}
+	if coverage.Chapters != 4+3+1 {
+		t.Errorf("found %d chapters, want 8 of the compared books within their count", coverage.Chapters)
+	}
+	if !slices.Equal(coverage.MissingBooks, []string{"OBA"}) {
+		t.Errorf("missing books %v, want [OBA]", coverage.MissingBooks)
+	}
+	if len(coverage.MissingChapters) != 0 {
+		t.Errorf("missing chapters %v, want none", coverage.MissingChapters)
+	}
+	if !slices.Equal(coverage.ExtraChapters["JOL"], []int{4}) || !slices.Equal(coverage.ExtraChapters["XYZ"], []int{1, 2}) {
+		t.Errorf("extra chapters %v, want JOL 4 and XYZ 1-2", coverage.ExtraChapters)
+	}
+	if _, ok := coverage.ExtraChapters["TOB"]; ok {
+		t.Errorf("extra chapters %v count TOB, which was not compared", coverage.ExtraChapters)
+	}
+
+	partial := CheckCoverage(books[:1], map[string][]int{"RUT": {1, 3}})
+	if !slices.Equal(partial.MissingChapters["RUT"], []int{2, 4}) || partial.Chapters != 2 {
+		t.Errorf("RUT 1 and 3: %+v, want 2 found and 2 and 4 missing", partial)
+	}
+}
+
+func TestFormatChapters(t *testing.T) {
+	tests := []struct {
+		chapters []int
+		want     string
+	}{
+		{nil, ""},
+		{[]int{7}, "7"},
+		{[]int{1, 2, 3, 7, 9, 10}, "1-3,7,9-10"},
+		{[]int{10, 9, 3, 1, 2}, "1-3,9-10"},
+		{[]int{4, 6}, "4,6"},
+	}
+	for _, tt := range tests {
+		if got := FormatChapters(tt.chapters); got != tt.want {
+			t.Errorf("FormatChapters(%v) = %q, want %q", tt.chapters, got, tt.want)
+		}
+	}
+}
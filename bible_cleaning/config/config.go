@@ -1,14 +1,32 @@
 package config
 
 const (
+	REGISTRY_FILE                      = "bibles.json" // translations the corpus is built from
+	CLEANING_RULES_FILE                = "cleaning_rules.json"
+	ALIGNER_CONFIG_FILE                = "aligner.json"  // sentence aligner settings written by tune
+	MANIFEST_FILE                      = "manifest.json" // how every artifact was built, for incremental rebuilds
+	PIPELINE_VERSION                   = 1               // bump when a change to the code changes what a stage writes
 	SRC_PATH                           = "corpus"
 	CORPUS_VERSES_FOLDER               = "corpus/by_verses"
 	CORPUS_SENTENCES_FOLDER            = "corpus/by_sentences"
+	VERSE_RECORDS_FILE                 = "corpus/verses"      // one record per verse, extension set by the export format
+	ANNOTATIONS_FOLDER                 = "corpus/annotations" // headings, notes and cross references per chapter
+	HTML_CACHE_FOLDER                  = "corpus/html_cache"
+	FAILED_CHAPTERS_FILE               = "corpus/failed_chapters.tsv"
+	CHECKPOINT_FILE                    = "corpus/checkpoint.json"
+	COVERAGE_FILE                      = "corpus/coverage.tsv"
+	DROPPED_CHARACTERS_FILE            = "corpus/dropped_characters.tsv"
+	CHECKPOINT_INTERVAL                = 10 // save the crawl frontier every N chapters
 	DST_PATH                           = "parallel_corpus"
 	PARALLEL_VERSES_FOLDER             = "parallel_corpus/by_verses"
 	PARALLEL_SENTENCES_FOLDER          = "parallel_corpus/by_sentences"
-	WORKER_REPORT_INTERVAL_MS          = 50000 // milliseconds
-	THREAD_POOL_SIZE                   = 12   // number of worker threads
+	WORD_ALIGNMENTS_FOLDER             = "parallel_corpus/word_alignments"
+	LEXICONS_FOLDER                    = "parallel_corpus/lexicons"
+	PARALLEL_PIVOT_FOLDER              = "parallel_corpus/by_pivot" // triangulated corpora, in a folder per pivot
+	MULTIWAY_FILE                      = "parallel_corpus/multiway" // one row per verse, extension set by the format
+	DATA_SPLITS_FOLDER                 = "parallel_corpus/splits"   // train, valid and test sets, in a folder per parallel corpora
+	WORKER_REPORT_INTERVAL_MS          = 50000                      // milliseconds
+	THREAD_POOL_SIZE                   = 12                         // number of worker threads
 	IS_DETAILED                        = false
 	USE_PROGRESS_BAR                   = false
 	WORKER_THREAD_REPORT_PROGRESS_RATE = 10000 // report progress every N items processed
@@ -20,7 +38,40 @@ const (
 	TOKEN_SPACE                  = "<SPACE>"
 	TOKEN_TAB                    = "<TAB>"
 	TOKEN_RETURN                 = "<RETURN>"
+	SIMILARITY_NGRAM             = 3 // order of the character n-grams of the sentence similarity
 	NGRAMS_DICE_SIMILARITY_BIAS  = 0.5
 	LENGTH_RATIO_SIMILARITY_BIAS = 0.3
 	PROPER_NOUNS_SIMILARITY_BIAS = 0.2
+	ALIGNER_SCORING              = "similarity" // "similarity" or "gale-church"
+	ALIGNER_MAX_MERGE            = 5            // most sentences merged per side by the similarity scoring
+	ALIGNER_NULL_PENALTY         = 0.05         // similarity given up by leaving a sentence unaligned (1-0, 0-1)
+	ALIGNER_BAND                 = 4            // sentences an alignment may stray from the diagonal of the verse
+	GALE_CHURCH_MEAN             = 1.0          // expected target characters per source character
+	GALE_CHURCH_VARIANCE         = 6.8          // variance of that ratio per character
+)
+
+const (
+	WORD_ALIGNMENT_MODEL          = "ibm2"            // "ibm1" or "ibm2" (fast_align)
+	WORD_ALIGNMENT_ITERATIONS     = 5                 // EM iterations of the word aligner
+	WORD_ALIGNMENT_TENSION        = 4.0               // how sharply IBM2 favours the diagonal
+	WORD_ALIGNMENT_NULL_PROB      = 0.08              // IBM2 prior of aligning a word to NULL
+	WORD_ALIGNMENT_SYMMETRIZATION = "grow-diag-final" // or "none" for source to target only
+	LEXICAL_TABLE_MIN_PROB        = 0.01              // p(t|s) below this is left out of the lexical tables
+)
+
+const (
+	LEXICON_ASSOCIATION         = "dice" // "dice" or "pmi"
+	LEXICON_ORTHOGRAPHIC_WEIGHT = 0.3    // share of a candidate's score given to orthographic similarity
+	LEXICON_MIN_COUNT           = 3      // pairs a word must occur in to get candidates
+	LEXICON_MIN_COOCCURRENCE    = 3      // pairs a word and its candidate must share
+	LEXICON_COGNATE_SIMILARITY  = 0.85   // Jaro-Winkler similarity from which a candidate is a cognate
+	LEXICON_TOP_N               = 3      // candidates kept per source word
+	LEXICON_EXAMPLES            = 3      // example pair IDs per candidate
+)
+
+const (
+	DATA_SPLIT_UNIT  = "verse" // "verse", "chapter" or "book": what is held out as a whole
+	DATA_SPLIT_SEED  = 42      // same seed, same splits
+	DATA_SPLIT_VALID = 0.05    // share of the units held out for validation
+	DATA_SPLIT_TEST  = 0.05    // share of the units held out for testing
 )
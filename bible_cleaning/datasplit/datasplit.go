@@ -0,0 +1,180 @@
+/*
+Package datasplit partitions parallel corpora into train, validation and test
+sets. A pair's split depends only on its verse, chapter or book and the seed,
+never on the corpus it is in, so a verse held out of one language pair is
+held out of every other and a multilingual model never trains on it.
+*/
+package datasplit
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// Split is a partition of the data, by the name fairseq gives it.
+type Split string
+
+const (
+	Train Split = "train"
+	Valid Split = "valid"
+	Test  Split = "test"
+)
+
+// Splits are the partitions in the order they are reported.
+var Splits = []Split{Train, Valid, Test}
+
+// Unit is what is assigned to a split as a whole.
+type Unit string
+
+const (
+	UnitVerse   Unit = "verse"   // verses at random; the sentences of a verse stay together
+	UnitChapter Unit = "chapter" // whole chapters held out
+	UnitBook    Unit = "book"    // whole books held out
+)
+
+// Config chooses how pairs are assigned to splits.
+type Config struct {
+	Unit       Unit
+	Seed       int64
+	Valid      float64  // share of the units held out for validation
+	Test       float64  // share of the units held out for testing
+	ValidBooks []string // by book: these books are the validation set, instead of a share
+	TestBooks  []string // by book: these books are the test set, instead of a share
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Unit:  config.DATA_SPLIT_UNIT,
+		Seed:  config.DATA_SPLIT_SEED,
+		Valid: config.DATA_SPLIT_VALID,
+		Test:  config.DATA_SPLIT_TEST,
+	}
+}
+
+// heldOutBooks reports whether the held-out books are listed rather than drawn.
+func (cfg Config) heldOutBooks() bool {
+	return len(cfg.ValidBooks) > 0 || len(cfg.TestBooks) > 0
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Unit != UnitVerse && cfg.Unit != UnitChapter && cfg.Unit != UnitBook {
+		return fmt.Errorf("unknown split unit %q", cfg.Unit)
+	}
+	if cfg.heldOutBooks() {
+		if cfg.Unit != UnitBook {
+			return fmt.Errorf("held-out books need splitting by book, not by %s", cfg.Unit)
+		}
+		for _, book := range cfg.ValidBooks {
+			if slices.Contains(cfg.TestBooks, book) {
+				return fmt.Errorf("book %s is held out for both validation and testing", book)
+			}
+		}
+		return nil
+	}
+	if cfg.Valid < 0 || cfg.Test < 0 || cfg.Valid+cfg.Test >= 1 {
+		return fmt.Errorf("validation and test shares must be non-negative and leave some training data, got %g and %g", cfg.Valid, cfg.Test)
+	}
+	return nil
+}
+
+// draw is where a unit falls in [0, 1) for the seed: the first 8 bytes of the SHA-256 of both.
+func (cfg Config) draw(key string) float64 {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(cfg.Seed, 10) + "\x00" + key))
+	return float64(binary.BigEndian.Uint64(sum[:8])) / math.Exp2(64)
+}
+
+// unitSplit is the split of a unit by its key.
+func (cfg Config) unitSplit(book, key string) Split {
+	if cfg.heldOutBooks() {
+		switch {
+		case slices.Contains(cfg.TestBooks, book):
+			return Test
+		case slices.Contains(cfg.ValidBooks, book):
+			return Valid
+		}
+		return Train
+	}
+
+	u := cfg.draw(key)
+	switch {
+	case u < cfg.Test:
+		return Test
+	case u < cfg.Test+cfg.Valid:
+		return Valid
+	}
+	return Train
+}
+
+/*
+Assign is the split of a pair by its ID. A span of verses ("003-004") is in a
+split only if every verse of it is; ok is false for a span whose verses
+fall in different splits, which belongs in none without leaking one of them.
+*/
+func (cfg Config) Assign(id types.PairID) (split Split, ok bool) {
+	switch cfg.Unit {
+	case UnitBook:
+		return cfg.unitSplit(id.Book, id.Book), true
+	case UnitChapter:
+		return cfg.unitSplit(id.Book, fmt.Sprintf("%s_%s", id.Book, id.ChapterLabel())), true
+	}
+
+	for v := id.Verse.Start; v <= id.Verse.End; v++ {
+		verse := types.PairID{Book: id.Book, Chapter: id.Chapter, Verse: types.VerseNumber{Start: v, End: v}}
+		s := cfg.unitSplit(id.Book, verse.String())
+		if v > id.Verse.Start && s != split {
+			return "", false
+		}
+		split = s
+	}
+	return split, true
+}
+
+// Partition is a parallel corpus divided into splits.
+type Partition struct {
+	Splits     map[Split]*types.ParallelCorpusEntry
+	Missing    int // pairs left out for a side labelled as a missing translation
+	Straddling int // pairs left out for a span of verses in different splits
+	Unlabelled int // pairs left out for an ID that is not a pair ID
+}
+
+/*
+Divide assigns every pair of an entry to its split, in corpus order. Pairs
+with a missing side have nothing to train or test on and are left out, as are
+pairs the split cannot be told of.
+*/
+func Divide(entry *types.ParallelCorpusEntry, cfg Config) *Partition {
+	p := &Partition{Splits: make(map[Split]*types.ParallelCorpusEntry, len(Splits))}
+	for _, split := range Splits {
+		p.Splits[split] = &types.ParallelCorpusEntry{
+			SourceLang: entry.SourceLang,
+			TargetLang: entry.TargetLang,
+			Pairs:      types.TextPairArray{},
+		}
+	}
+
+	for _, pair := range entry.Pairs {
+		if pair.SourceText == config.TOKEN_MISSING_TRANSLATION || pair.TargetText == config.TOKEN_MISSING_TRANSLATION {
+			p.Missing++
+			continue
+		}
+		id, err := types.ParsePairID(pair.ID)
+		if err != nil {
+			p.Unlabelled++
+			continue
+		}
+		split, ok := cfg.Assign(id)
+		if !ok {
+			p.Straddling++
+			continue
+		}
+		p.Splits[split].Pairs = append(p.Splits[split].Pairs, pair)
+	}
+	return p
+}
@@ -0,0 +1,132 @@
+package datasplit
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// testVerses are the IDs of two chapters each of GEN and MAT.
+func testVerses() []types.PairID {
+	var ids []types.PairID
+	for _, book := range []string{"GEN", "MAT"} {
+		for chapter := 1; chapter <= 2; chapter++ {
+			for v := 1; v <= 30; v++ {
+				ids = append(ids, types.PairID{Book: book, Chapter: chapter, Verse: types.VerseNumber{Start: v, End: v}})
+			}
+		}
+	}
+	return ids
+}
+
+// testCorpus is a corpus of the IDs, its texts naming the language and the verse.
+func testCorpus(src, tgt string, ids []types.PairID) *types.ParallelCorpusEntry {
+	entry := &types.ParallelCorpusEntry{SourceLang: src, TargetLang: tgt, Pairs: types.TextPairArray{}}
+	for _, id := range ids {
+		entry.Pairs = append(entry.Pairs, types.NewTextPair(id, src+" "+id.String(), tgt+" "+id.String()))
+	}
+	return entry
+}
+
+// splitsByID is the split every pair of a partition landed in, by its ID.
+func splitsByID(t *testing.T, p *Partition) map[string]Split {
+	t.Helper()
+
+	splits := make(map[string]Split)
+	for split, entry := range p.Splits {
+		for _, pair := range entry.Pairs {
+			if prev, ok := splits[pair.ID]; ok {
+				t.Errorf("%s in both %s and %s", pair.ID, prev, split)
+			}
+			splits[pair.ID] = split
+		}
+	}
+	return splits
+}
+
+/*
+straddlingSpan finds two verses next to each other that fall in different
+splits, and returns the span of both.
+*/
+func straddlingSpan(t *testing.T, cfg Config, ids []types.PairID) types.PairID {
+	t.Helper()
+
+	for i := 1; i < len(ids); i++ {
+		prev, id := ids[i-1], ids[i]
+		if prev.Book != id.Book || prev.Chapter != id.Chapter {
+			continue
+		}
+		a, _ := cfg.Assign(prev)
+		b, _ := cfg.Assign(id)
+		if a != b {
+			return types.PairID{Book: id.Book, Chapter: id.Chapter, Verse: types.VerseNumber{Start: prev.Verse.Start, End: id.Verse.Start}}
+		}
+	}
+	t.Fatal("no two verses next to each other fall in different splits")
+	return types.PairID{}
+}
+
+func TestDivideIsTheSameAcrossCorpora(t *testing.T) {
+	for _, unit := range []Unit{UnitVerse, UnitChapter, UnitBook} {
+		t.Run(string(unit), func(t *testing.T) {
+			cfg := Config{Unit: unit, Seed: 7, Valid: 0.2, Test: 0.2}
+			if err := cfg.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			ids := testVerses()
+			shuffled := append([]types.PairID(nil), ids...)
+			rand.New(rand.NewPCG(1, 2)).Shuffle(len(shuffled), func(i, j int) {
+				shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+			})
+
+			// the same verses, in another order and the other way round
+			cebTgl := Divide(testCorpus("ceb", "tgl", ids), cfg)
+			iloTgl := Divide(testCorpus("tgl", "ilo", shuffled).Reversed(), cfg)
+
+			first, second := splitsByID(t, cebTgl), splitsByID(t, iloTgl)
+			if len(first) != len(ids) || len(second) != len(ids) {
+				t.Fatalf("%d and %d pairs split, want %d", len(first), len(second), len(ids))
+			}
+			for _, id := range ids {
+				want, _ := cfg.Assign(id)
+				if first[id.String()] != want || second[id.String()] != want {
+					t.Errorf("%s in %s and %s, want %s in both", id, first[id.String()], second[id.String()], want)
+				}
+			}
+
+			if unit == UnitVerse {
+				for _, split := range Splits {
+					if len(cebTgl.Splits[split].Pairs) == 0 {
+						t.Errorf("no verse in %s", split)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDivideDropsStraddlingSpans(t *testing.T) {
+	cfg := Config{Unit: UnitVerse, Seed: 7, Valid: 0.2, Test: 0.2}
+	ids := testVerses()
+	span := straddlingSpan(t, cfg, ids)
+
+	if split, ok := cfg.Assign(span); ok {
+		t.Errorf("span %s assigned to %s, want it in none", span, split)
+	}
+
+	p := Divide(testCorpus("ceb", "tgl", append(ids, span)), cfg)
+	if p.Straddling != 1 {
+		t.Errorf("%d straddling pairs, want 1", p.Straddling)
+	}
+	if _, ok := splitsByID(t, p)[span.String()]; ok {
+		t.Errorf("span %s was kept", span)
+	}
+
+	// by chapter the span is whole again
+	cfg.Unit = UnitChapter
+	if p := Divide(testCorpus("ceb", "tgl", []types.PairID{span}), cfg); p.Straddling != 0 {
+		t.Errorf("span %s straddles by chapter", span)
+	}
+}
@@ -0,0 +1,271 @@
+package lexicon
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/xrash/smetrics"
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+	"github.com/zrygan.nlp/bible_cleaning/wordalignment"
+)
+
+// Association is the co-occurrence statistic the score is built on.
+type Association string
+
+const (
+	AssociationDice Association = "dice" // Dice coefficient of the verses the words occur in
+	AssociationPMI  Association = "pmi"  // normalized pointwise mutual information, negatives as 0
+)
+
+// Config chooses how candidates are scored and which are kept.
+type Config struct {
+	Association        Association
+	OrthographicWeight float64 // share of the score given to orthographic similarity, the rest to association
+	MinCount           int     // pairs a word must occur in to be considered
+	MinCooccurrence    int     // pairs a candidate must share
+	CognateSimilarity  float64 // orthographic similarity from which a candidate is marked a cognate
+	TopN               int     // candidates kept per source word
+	Examples           int     // pair IDs listed per candidate
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Association:        config.LEXICON_ASSOCIATION,
+		OrthographicWeight: config.LEXICON_ORTHOGRAPHIC_WEIGHT,
+		MinCount:           config.LEXICON_MIN_COUNT,
+		MinCooccurrence:    config.LEXICON_MIN_COOCCURRENCE,
+		CognateSimilarity:  config.LEXICON_COGNATE_SIMILARITY,
+		TopN:               config.LEXICON_TOP_N,
+		Examples:           config.LEXICON_EXAMPLES,
+	}
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Association != AssociationDice && cfg.Association != AssociationPMI {
+		return fmt.Errorf("unknown association %q", cfg.Association)
+	}
+	if cfg.OrthographicWeight < 0 || cfg.OrthographicWeight > 1 {
+		return fmt.Errorf("orthographic weight must be from 0 to 1, got %g", cfg.OrthographicWeight)
+	}
+	if cfg.MinCount < 1 || cfg.MinCooccurrence < 1 || cfg.TopN < 1 {
+		return fmt.Errorf("minimum counts and candidates per word must be at least 1")
+	}
+	return nil
+}
+
+// OrthographicSimilarity is the Jaro-Winkler similarity of two words, as proper nouns are matched across languages.
+func OrthographicSimilarity(a, b string) float64 {
+	return smetrics.JaroWinkler(a, b, 0.7, 4)
+}
+
+// Entry is a candidate translation of a source word.
+type Entry struct {
+	Source, Target string
+	Rank           int     // of the target among the candidates of the source word, from 1
+	Score          float64 // association and orthographic similarity combined, from 0 to 1
+	Dice           float64
+	PMI            float64 // normalized, from -1 to 1
+	Orthographic   float64
+	Cooccurrences  int      // pairs the two words share
+	Cognate        bool     // spelled alike enough to be a cognate or shared loanword
+	Examples       []string // IDs of pairs the two words share
+}
+
+// Lexicon is the bilingual dictionary induced from a parallel corpus, best candidates first.
+type Lexicon struct {
+	SourceLang, TargetLang string
+	Entries                []Entry
+}
+
+// pairWords is the distinct words of each side of a pair, by ID.
+type pairWords struct {
+	id       string
+	src, tgt []int
+}
+
+type vocabulary struct {
+	ids   map[string]int
+	words []string
+	count []int // pairs the word occurs in
+}
+
+func (v *vocabulary) distinct(tokens []string) []int {
+	seen := make(map[int]bool, len(tokens))
+	var ids []int
+	for _, w := range tokens {
+		id, ok := v.ids[w]
+		if !ok {
+			id = len(v.words)
+			v.ids[w] = id
+			v.words = append(v.words, w)
+			v.count = append(v.count, 0)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+			v.count[id]++
+		}
+	}
+	return ids
+}
+
+func pairKey(s, t int) uint64 {
+	return uint64(s)<<32 | uint64(t)
+}
+
+/*
+Induce mines candidate translations from the pairs of a parallel corpus. Two words
+are associated by how often they occur in the same pairs, by Dice or normalized
+PMI, and the score mixes that with their orthographic similarity, which favours
+cognates between related languages. Pairs with a missing side are skipped; words
+are tokenized as for word alignment.
+*/
+func Induce(entry *types.ParallelCorpusEntry, cfg Config) *Lexicon {
+	src := &vocabulary{ids: make(map[string]int)}
+	tgt := &vocabulary{ids: make(map[string]int)}
+
+	var pairs []pairWords
+	for _, pair := range entry.Pairs {
+		if pair.SourceText == config.TOKEN_MISSING_TRANSLATION || pair.TargetText == config.TOKEN_MISSING_TRANSLATION {
+			continue
+		}
+		pairs = append(pairs, pairWords{
+			id:  pair.ID,
+			src: src.distinct(wordalignment.Tokenize(pair.SourceText)),
+			tgt: tgt.distinct(wordalignment.Tokenize(pair.TargetText)),
+		})
+	}
+
+	// co-occurrences of the words frequent enough to judge
+	cooccurrences := make(map[uint64]int)
+	for _, p := range pairs {
+		for _, s := range p.src {
+			if src.count[s] < cfg.MinCount {
+				continue
+			}
+			for _, t := range p.tgt {
+				if tgt.count[t] >= cfg.MinCount {
+					cooccurrences[pairKey(s, t)]++
+				}
+			}
+		}
+	}
+
+	n := float64(len(pairs))
+	candidates := make(map[int][]Entry)
+	for key, c := range cooccurrences {
+		if c < cfg.MinCooccurrence {
+			continue
+		}
+		s, t := int(key>>32), int(key&math.MaxUint32)
+		cs, ct := float64(src.count[s]), float64(tgt.count[t])
+
+		e := Entry{
+			Source:        src.words[s],
+			Target:        tgt.words[t],
+			Dice:          2 * float64(c) / (cs + ct),
+			Orthographic:  OrthographicSimilarity(src.words[s], tgt.words[t]),
+			Cooccurrences: c,
+		}
+		if pJoint := float64(c) / n; pJoint < 1 {
+			e.PMI = math.Log(pJoint/((cs/n)*(ct/n))) / -math.Log(pJoint)
+		} else {
+			e.PMI = 1 // both words are in every pair
+		}
+
+		association := e.Dice
+		if cfg.Association == AssociationPMI {
+			association = max(e.PMI, 0)
+		}
+		e.Score = (1-cfg.OrthographicWeight)*association + cfg.OrthographicWeight*e.Orthographic
+		e.Cognate = e.Orthographic >= cfg.CognateSimilarity
+		candidates[s] = append(candidates[s], e)
+	}
+
+	lexicon := &Lexicon{SourceLang: entry.SourceLang, TargetLang: entry.TargetLang}
+	for _, entries := range candidates {
+		slices.SortFunc(entries, compareEntries)
+		entries = entries[:min(cfg.TopN, len(entries))]
+		for i := range entries {
+			entries[i].Rank = i + 1
+		}
+		lexicon.Entries = append(lexicon.Entries, entries...)
+	}
+	slices.SortFunc(lexicon.Entries, compareEntries)
+
+	lexicon.addExamples(pairs, src, tgt, cfg.Examples)
+	return lexicon
+}
+
+func compareEntries(a, b Entry) int {
+	return cmp.Or(
+		cmp.Compare(b.Score, a.Score),
+		cmp.Compare(b.Cooccurrences, a.Cooccurrences),
+		cmp.Compare(a.Source, b.Source),
+		cmp.Compare(a.Target, b.Target),
+	)
+}
+
+// addExamples lists the first pairs, in corpus order, each kept candidate occurs in.
+func (l *Lexicon) addExamples(pairs []pairWords, src, tgt *vocabulary, limit int) {
+	if limit <= 0 {
+		return
+	}
+
+	bySource := make(map[int][]*Entry)
+	for i := range l.Entries {
+		s := src.ids[l.Entries[i].Source]
+		bySource[s] = append(bySource[s], &l.Entries[i])
+	}
+
+	for _, p := range pairs {
+		targets := make(map[string]bool, len(p.tgt))
+		for _, t := range p.tgt {
+			targets[tgt.words[t]] = true
+		}
+		for _, s := range p.src {
+			for _, e := range bySource[s] {
+				if len(e.Examples) < limit && targets[e.Target] {
+					e.Examples = append(e.Examples, p.id)
+				}
+			}
+		}
+	}
+}
+
+/*
+Save writes the lexicon to outDir as "src_tgt.tsv", best candidates first, the
+example pair IDs joined by commas.
+*/
+func (l *Lexicon) Save(outDir string) error {
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	path := filepath.Join(outDir, fmt.Sprintf("%s_%s.tsv", l.SourceLang, l.TargetLang))
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create lexicon file: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if _, err := w.WriteString("source\ttarget\trank\tscore\tdice\tpmi\torthographic\tcooccurrences\tcognate\texamples\n"); err != nil {
+		return fmt.Errorf("failed to write header to lexicon file: %w", err)
+	}
+	for _, e := range l.Entries {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%d\t%.4f\t%.4f\t%.4f\t%.4f\t%d\t%t\t%s\n",
+			e.Source, e.Target, e.Rank, e.Score, e.Dice, e.PMI, e.Orthographic, e.Cooccurrences, e.Cognate, strings.Join(e.Examples, ","))
+		if err != nil {
+			return fmt.Errorf("failed to write to lexicon file: %w", err)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,155 @@
+package lexicon
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// toyCorpus has five Tagalog-Cebuano pairs of a word or two per side, and one pair missing its Cebuano side.
+func toyCorpus() *types.ParallelCorpusEntry {
+	entry := &types.ParallelCorpusEntry{SourceLang: "tgl", TargetLang: "ceb"}
+	entry.Add("Dios lumikha", "Dios nagbuhat", "GEN_001_001")
+	entry.Add("Dios, langit.", "Dios langit", "GEN_001_002")
+	entry.Add("lumikha lupa", "nagbuhat yuta", "GEN_001_003")
+	entry.Add("lupa langit", "yuta langit", "GEN_001_004")
+	entry.Add("tao", "tawo", "GEN_001_005")
+	entry.Add("Dios", config.TOKEN_MISSING_TRANSLATION, "GEN_001_006")
+	return entry
+}
+
+func testConfig() Config {
+	return Config{
+		Association:       AssociationDice,
+		MinCount:          1,
+		MinCooccurrence:   1,
+		CognateSimilarity: 0.9,
+		TopN:              10,
+		Examples:          10,
+	}
+}
+
+func find(l *Lexicon, source, target string) (Entry, bool) {
+	for _, e := range l.Entries {
+		if e.Source == source && e.Target == target {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+func TestInduceAssociation(t *testing.T) {
+	lexicon := Induce(toyCorpus(), testConfig())
+
+	// of the five pairs with both sides, dios is in two on each side
+	tests := []struct {
+		source, target string
+		cooccurrences  int
+		dice, pmi      float64
+	}{
+		{"dios", "dios", 2, 1, 1},
+		{"lumikha", "nagbuhat", 2, 1, 1},
+		{"dios", "nagbuhat", 1, 0.5, math.Log(0.2/(0.4*0.4)) / -math.Log(0.2)},
+		{"tao", "tawo", 1, 1, 1},
+	}
+	for _, tt := range tests {
+		e, ok := find(lexicon, tt.source, tt.target)
+		if !ok {
+			t.Errorf("no entry %s -> %s", tt.source, tt.target)
+			continue
+		}
+		if e.Cooccurrences != tt.cooccurrences || math.Abs(e.Dice-tt.dice) > 1e-9 || math.Abs(e.PMI-tt.pmi) > 1e-9 {
+			t.Errorf("%s -> %s: %d shared, Dice %g, PMI %g; want %d, %g, %g", tt.source, tt.target, e.Cooccurrences, e.Dice, e.PMI, tt.cooccurrences, tt.dice, tt.pmi)
+		}
+		if e.Score != e.Dice {
+			t.Errorf("%s -> %s scored %g with no orthographic weight, want the Dice %g", tt.source, tt.target, e.Score, e.Dice)
+		}
+	}
+
+	if _, ok := find(lexicon, "dios", "tawo"); ok {
+		t.Error("entry dios -> tawo, which never share a pair")
+	}
+
+	cfg := testConfig()
+	cfg.Association = AssociationPMI
+	cfg.OrthographicWeight = 0.5
+	e, _ := find(Induce(toyCorpus(), cfg), "dios", "nagbuhat")
+	if want := 0.5*e.PMI + 0.5*e.Orthographic; math.Abs(e.Score-want) > 1e-9 {
+		t.Errorf("PMI score %g, want half the PMI and half the orthographic similarity, %g", e.Score, want)
+	}
+}
+
+func TestInduceCutoffs(t *testing.T) {
+	cfg := testConfig()
+	cfg.MinCount = 2
+	cfg.MinCooccurrence = 2
+
+	var got []string
+	for _, e := range Induce(toyCorpus(), cfg).Entries {
+		got = append(got, e.Source+"-"+e.Target)
+	}
+	slices.Sort(got)
+	want := []string{"dios-dios", "langit-langit", "lumikha-nagbuhat", "lupa-yuta"}
+	if !slices.Equal(got, want) {
+		t.Errorf("entries %v, want the words in two pairs sharing both", got)
+	}
+}
+
+func TestInduceTopN(t *testing.T) {
+	cfg := testConfig()
+	cfg.TopN = 2
+	lexicon := Induce(toyCorpus(), cfg)
+
+	var dios []string
+	for _, e := range lexicon.Entries {
+		if e.Source == "dios" {
+			dios = append(dios, e.Target)
+			if e.Rank != len(dios) {
+				t.Errorf("dios -> %s ranked %d, want %d", e.Target, e.Rank, len(dios))
+			}
+		}
+	}
+	// nagbuhat and langit tie on score and co-occurrences, so the target's spelling decides
+	if !slices.Equal(dios, []string{"dios", "langit"}) {
+		t.Errorf("candidates of dios %v, want [dios langit]", dios)
+	}
+
+	if !slices.IsSortedFunc(lexicon.Entries, compareEntries) {
+		t.Error("entries are not sorted best first")
+	}
+}
+
+func TestInduceCognatesAndExamples(t *testing.T) {
+	cfg := testConfig()
+	cfg.Examples = 1
+	lexicon := Induce(toyCorpus(), cfg)
+
+	for _, tt := range []struct {
+		source, target string
+		cognate        bool
+	}{
+		{"dios", "dios", true},
+		{"tao", "tawo", true},
+		{"lumikha", "nagbuhat", false},
+	} {
+		if e, _ := find(lexicon, tt.source, tt.target); e.Cognate != tt.cognate {
+			t.Errorf("%s -> %s cognate %v (orthographic %.3f), want %v", tt.source, tt.target, e.Cognate, e.Orthographic, tt.cognate)
+		}
+	}
+
+	if e, _ := find(lexicon, "dios", "dios"); !slices.Equal(e.Examples, []string{"GEN_001_001"}) {
+		t.Errorf("examples %v, want the first pair alone", e.Examples)
+	}
+
+	cfg.Examples = 10
+	lexicon = Induce(toyCorpus(), cfg)
+	if e, _ := find(lexicon, "langit", "langit"); !slices.Equal(e.Examples, []string{"GEN_001_002", "GEN_001_004"}) {
+		t.Errorf("examples %v, want both pairs in corpus order", e.Examples)
+	}
+	if e, _ := find(lexicon, "dios", "dios"); slices.Contains(e.Examples, "GEN_001_006") {
+		t.Errorf("examples %v include the pair missing a side", e.Examples)
+	}
+}
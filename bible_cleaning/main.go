@@ -1,99 +1,281 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
+	"iter"
 	"os"
-	"regexp"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/zrygan.nlp/bible_cleaning/biblecorpus"
+	"github.com/zrygan.nlp/bible_cleaning/canon"
 	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/datasplit"
+	"github.com/zrygan.nlp/bible_cleaning/lexicon"
+	"github.com/zrygan.nlp/bible_cleaning/manifest"
 	parallelcorpus "github.com/zrygan.nlp/bible_cleaning/parallelbuilder"
+	"github.com/zrygan.nlp/bible_cleaning/registry"
 	"github.com/zrygan.nlp/bible_cleaning/scraper"
+	"github.com/zrygan.nlp/bible_cleaning/sentencealignment"
 	"github.com/zrygan.nlp/bible_cleaning/sentencecleaning"
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
 	"github.com/zrygan.nlp/bible_cleaning/types"
+	"github.com/zrygan.nlp/bible_cleaning/versestore"
+	"github.com/zrygan.nlp/bible_cleaning/wordalignment"
 )
 
-// initialize sets up the initial parameters for the webscraping process
-func initialize() (int, map[string]string, map[string]int) {
+// initialize sets up the initial parameters for the webscraping process from the registry
+func initialize(reg *registry.Registry) (int, map[string]int) {
 	chapterLimit := 30000
 
-	bibles := map[string]string{
-		//  ISO 639: Root URL
-		"tgl": "https://www.bible.com/bible/2195/GEN.1.ABTAG01",
-		"ceb": "https://www.bible.com/bible/562/GEN.1.RCPV",
-		"ilo": "https://www.bible.com/bible/782/GEN.1.RIPV",
-		"jil": "https://www.bible.com/bible/2190/GEN.1.MBBHIL12",
-		"bik": "https://www.bible.com/bible/890/GEN.1.MBBBIK92",
-		"war": "https://www.bible.com/bible/2198/GEN.1.MBBSAM",
-		"pam": "https://www.bible.com/bible/1141/GEN.1.PMPV",
-		"pag": "https://www.bible.com/bible/1166/GEN.1.PNPV",
-
-		"tiu": "https://www.bible.com/bible/2812/MAT.1.YBT",
-		"cbk": "https://www.bible.com/bible/1129/MAT.1.CBKNT",
-		"prf": "https://www.bible.com/bible/438/MAT.1.PRF",
-		"tsg": "https://www.bible.com/bible/1319/MAT.1.TSG",
-		"rol": "https://www.bible.com/bible/2244/MAT.1.BKR",
-		"msb": "https://www.bible.com/bible/1222/MAT.1.MSB",
-		"krj": "https://www.bible.com/bible/1489/MAT.1.KRJNT",
-		"tao": "https://www.bible.com/bible/2364/MAT.1.SNT",
-	}
-
-	corpusSizes := map[string]int{
-		"tgl": 0,
-		"ceb": 0,
-		"ilo": 0,
-		"jil": 0,
-		"bik": 0,
-		"war": 0,
-		"pam": 0,
-		"pag": 0,
-		"tiu": 0,
-		"cbk": 0,
-		"prf": 0,
-		"tsg": 0,
-		"rol": 0,
-		"msb": 0,
-		"krj": 0,
-		"tao": 0,
-	}
-
-	return chapterLimit, bibles, corpusSizes
+	corpusSizes := make(map[string]int, len(reg.Translations))
+	for _, t := range reg.Translations {
+		corpusSizes[t.ISO] = 0
+	}
+
+	return chapterLimit, corpusSizes
+}
+
+// loadRegistry reads the translation registry every subcommand works from
+func loadRegistry() *registry.Registry {
+	reg, err := registry.Load(config.REGISTRY_FILE)
+	if err != nil {
+		panic(err)
+	}
+	return reg
+}
+
+// languageCleaner builds the cleaning pipeline of a translation: its allowed characters,
+// then the rule file's rules enabled for it followed by the registry's own rules for it
+func languageCleaner(rules []*textcleaning.Rule, t registry.Translation, dropped *textcleaning.DropReport) *textcleaning.Cleaner {
+	allowed, err := t.CharSet()
+	if err != nil {
+		panic(err)
+	}
+
+	own, err := textcleaning.CompileRules(t.Cleaning)
+	if err != nil {
+		panic(err)
+	}
+
+	return &textcleaning.Cleaner{
+		Language: t.ISO,
+		Allowed:  allowed,
+		Rules:    append(textcleaning.ForLanguage(rules, t.ISO), own...),
+		Dropped:  dropped,
+	}
+}
+
+// scrapeOptions carries the scraping flags from the command line to webscrapeBibles.
+type scrapeOptions struct {
+	crawl      *scraper.CrawlConfig
+	checkpoint *scraper.Checkpoint
+	books      []canon.Book // nil follows "Next Chapter" links from each root URL
+	rules      []*textcleaning.Rule
+	dropped    *textcleaning.DropReport
+	workers    int // chapters scraped at once per language in book mode
 }
 
 // webscrapeBibles handles concurrent webscraping of multiple bibles
 func webscrapeBibles(
-	bibleURLs map[string]string,
+	translations []registry.Translation,
 	corpusSizes map[string]int,
-	cleaningConfig []types.FindReplaceTuple[*regexp.Regexp],
 	chapterLimit int,
+	opts *scrapeOptions,
 ) {
+	if opts.books != nil {
+		webscrapeBibleBooks(translations, corpusSizes, opts)
+		return
+	}
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	checkpoint := opts.checkpoint
+
+	for _, translation := range translations {
+		language := translation.ISO
+		cleaner := languageCleaner(opts.rules, translation, opts.dropped)
+
+		// continue from the saved frontier when resuming
+		frontier := checkpoint.Start(language, translation.StartURL)
+		if frontier.Done || len(frontier.Pending) == 0 {
+			fmt.Printf("Skipping %s: finished in a previous run (%d chapters)\n", language, frontier.ChapterCount)
+			corpusSizes[language] = frontier.WordCount
+			continue
+		}
 
-	for language, root := range bibleURLs {
-		chapterCount := 1
+		chapterCount := 1 + frontier.ChapterCount
 
 		wg.Add(1)
 		filepath := fmt.Sprintf("%s/%s", config.CORPUS_VERSES_FOLDER, language)
-		classification := types.LanguageClass{Language: language, OutputDir: filepath}
+		classification := types.LanguageClass{
+			Language:      language,
+			OutputDir:     filepath,
+			AnnotationDir: fmt.Sprintf("%s/%s", config.ANNOTATIONS_FOLDER, language),
+		}
 
 		go func(lang *types.LanguageClass, bibleURL string, chapterCount *int) {
 			defer wg.Done()
 
-			res := scraper.WebscrapeAndParse(bibleURL, lang, &cleaningConfig, make(map[string]bool), chapterCount, chapterLimit)
-			//res := scraper.ConcurrentWebscrapeAndParse(bibleURL, lang, &cleaningConfig, chapterLimit, 5)
+			res := scraper.WebscrapeAndParse(bibleURL, lang, cleaner, frontier.Visited, chapterCount, chapterLimit, opts.crawl, checkpoint)
+			//res := scraper.ConcurrentWebscrapeAndParse(bibleURL, lang, cleaner, chapterLimit, 5, opts.crawl, checkpoint)
+
+			if err := checkpoint.Finish(lang.Language); err != nil {
+				fmt.Println("Error saving checkpoint:", err)
+			}
 
 			// Critical Section: Update shared map
 			mu.Lock()
-			corpusSizes[lang.Language] = res
+			corpusSizes[lang.Language] = frontier.WordCount + res
 			mu.Unlock()
 
-		}(&classification, root, &chapterCount)
+		}(&classification, frontier.Pending[0], &chapterCount)
 	}
 
 	wg.Wait()
 }
 
+// webscrapeBibleBooks scrapes only the selected books of every bible, straight from the canon table
+func webscrapeBibleBooks(
+	translations []registry.Translation,
+	corpusSizes map[string]int,
+	opts *scrapeOptions,
+) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	coverages := make(map[string]canon.Coverage)
+
+	for _, translation := range translations {
+		language := translation.ISO
+		cleaner := languageCleaner(opts.rules, translation, opts.dropped)
+
+		version, err := scraper.ParseBibleURL(translation.StartURL)
+		if err != nil {
+			panic(err)
+		}
+
+		wg.Add(1)
+		filepath := fmt.Sprintf("%s/%s", config.CORPUS_VERSES_FOLDER, language)
+		classification := types.LanguageClass{
+			Language:      language,
+			OutputDir:     filepath,
+			AnnotationDir: fmt.Sprintf("%s/%s", config.ANNOTATIONS_FOLDER, language),
+		}
+
+		go func(lang *types.LanguageClass) {
+			defer wg.Done()
+
+			res, coverage := scraper.ScrapeBooks(version, opts.books, lang, cleaner, opts.workers, opts.crawl, opts.checkpoint)
+
+			mu.Lock()
+			corpusSizes[lang.Language] = res
+			coverages[lang.Language] = coverage
+			mu.Unlock()
+		}(&classification)
+	}
+
+	wg.Wait()
+
+	if err := summarizeCoverage(coverages, config.COVERAGE_FILE); err != nil {
+		panic(err)
+	}
+}
+
+// summarizeCoverage prints which books and chapters each translation lacks and saves them as a TSV
+func summarizeCoverage(coverages map[string]canon.Coverage, outPath string) error {
+	if err := os.MkdirAll(path.Dir(outPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create coverage report: %w", err)
+	}
+	defer file.Close()
+
+	fmt.Fprintln(file, "language\tbook\tstatus\tchapters")
+
+	languages := make([]string, 0, len(coverages))
+	for language := range coverages {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	for _, language := range languages {
+		coverage := coverages[language]
+		fmt.Printf("%s : %d/%d chapters", language, coverage.Chapters, coverage.Expected)
+		if len(coverage.MissingBooks) > 0 {
+			fmt.Printf(", missing books %s", strings.Join(coverage.MissingBooks, " "))
+		}
+		fmt.Println()
+
+		for _, book := range coverage.MissingBooks {
+			fmt.Fprintf(file, "%s\t%s\tmissing_book\t\n", language, book)
+		}
+		for _, b := range canon.Books {
+			if chapters, ok := coverage.MissingChapters[b.Code]; ok {
+				fmt.Printf("  %s missing chapters %s\n", b.Code, canon.FormatChapters(chapters))
+				fmt.Fprintf(file, "%s\t%s\tmissing_chapters\t%s\n", language, b.Code, canon.FormatChapters(chapters))
+			}
+		}
+		extraBooks := make([]string, 0, len(coverage.ExtraChapters))
+		for book := range coverage.ExtraChapters {
+			extraBooks = append(extraBooks, book)
+		}
+		sort.Strings(extraBooks)
+
+		for _, book := range extraBooks {
+			chapters := coverage.ExtraChapters[book]
+			if len(chapters) > 0 {
+				fmt.Printf("  %s extra chapters %s\n", book, canon.FormatChapters(chapters))
+				fmt.Fprintf(file, "%s\t%s\textra_chapters\t%s\n", language, book, canon.FormatChapters(chapters))
+			}
+		}
+	}
+
+	fmt.Printf("Saved coverage report to %s\n", outPath)
+	return nil
+}
+
+// reportCoverage compares the scraped corpus against the canon without touching the network
+func reportCoverage(reg *registry.Registry, args []string) {
+	fs := flag.NewFlagSet("coverage", flag.ExitOnError)
+	bookSpec := fs.String("books", "protestant", "books to compare against: codes or ot, nt, dc, protestant, all")
+	fs.Parse(args)
+
+	books, err := canon.Select(*bookSpec)
+	if err != nil {
+		panic(err)
+	}
+
+	corpus, err := biblecorpus.Open(config.CORPUS_VERSES_FOLDER)
+	if err != nil {
+		panic(err)
+	}
+
+	coverages := make(map[string]canon.Coverage)
+	for _, language := range corpus.Languages() {
+		if !reg.Has(language) {
+			continue
+		}
+
+		found := make(map[string][]int)
+		for _, chapter := range corpus.Chapters(language) {
+			found[chapter.Book] = append(found[chapter.Book], chapter.Chapter)
+		}
+		coverages[language] = canon.CheckCoverage(books, found)
+	}
+
+	if err := summarizeCoverage(coverages, config.COVERAGE_FILE); err != nil {
+		panic(err)
+	}
+}
+
 // summarizeCorpus prints the corpus sizes per language and the total sum
 func summarizeCorpus(corpusSizes map[string]int) {
 	sum := 0
@@ -106,110 +288,648 @@ func summarizeCorpus(corpusSizes map[string]int) {
 	fmt.Println("Sig", " : ", sum)
 }
 
-func parallelizeCorpusByVerses() {
-	err := parallelcorpus.GenerateParallelCorpusByVerses()
+// parallelizeCorpusByVerses joins the verses of the selected language pairs
+func parallelizeCorpusByVerses(reg *registry.Registry, args []string) {
+	var selection parallelcorpus.PairSelection
+
+	fs := flag.NewFlagSet("parallel verses", flag.ExitOnError)
+	addPairFlags(fs, &selection)
+	fs.Parse(args)
+
+	err := parallelcorpus.GenerateParallelCorpusByVerses(reg.Languages(), selection)
 
 	if err != nil {
 		panic(err)
 	}
 }
 
-func parallelizeCorpusBySentences() {
-	err := parallelcorpus.GenerateParallelCorpusBySentences()
+// parallelizeCorpusBySentences aligns the sentences of every language pair
+func parallelizeCorpusBySentences(reg *registry.Registry, opts parallelcorpus.SentenceOptions) {
+	err := parallelcorpus.GenerateParallelCorpusBySentences(reg.Languages(), opts)
 
 	if err != nil {
 		panic(err)
 	}
 }
 
-func splitSentencesInCorpus() {
-	err := sentencecleaning.SplitCorpusBySentence(config.CORPUS_VERSES_FOLDER, config.CORPUS_SENTENCES_FOLDER)
+// addPairFlags adds the flags choosing the language pairs to build to fs, set into selection.
+// --pairs takes src-tgt, src-* (one to many) and *-tgt (many to one), the source named first;
+// pairs whose output is newer than their inputs are skipped unless --force.
+func addPairFlags(fs *flag.FlagSet, selection *parallelcorpus.PairSelection) {
+	fs.Func("pairs", "comma-separated language pairs: src-tgt, src-* or *-tgt (default every pair, alphabetically)", func(s string) error {
+		selection.Specs = append(selection.Specs, strings.Split(s, ",")...)
+		return nil
+	})
+	fs.BoolVar(&selection.Force, "force", false, "rebuild pairs whose output is newer than their inputs")
+}
+
+// addAlignerFlags adds the flags of the sentence aligner to fs, set into cfg.
+// --aligner-config loads a JSON configuration, such as tune writes; flags after it override it.
+// --scoring similarity rates beads by n-gram Dice, length ratio and proper nouns, weighed by
+// --dice-weight, --length-weight and --proper-noun-weight; gale-church by the Gale-Church
+// length model. --null-penalty < 0 forbids 1-0 and 0-1 beads.
+func addAlignerFlags(fs *flag.FlagSet, cfg *sentencealignment.AlignerConfig) {
+	fs.Func("aligner-config", "JSON file of aligner settings, overridden by the flags after it", func(path string) error {
+		loaded, err := sentencealignment.LoadAlignerConfig(path)
+		if err != nil {
+			return err
+		}
+		*cfg = loaded
+		return nil
+	})
+	fs.Func("scoring", "bead scoring: similarity or gale-church (default "+string(cfg.Scoring)+")", func(s string) error {
+		cfg.Scoring = sentencealignment.Scoring(s)
+		return nil
+	})
+	fs.IntVar(&cfg.MaxMerge, "max-merge", cfg.MaxMerge, "most sentences merged per side by the similarity scoring")
+	fs.Float64Var(&cfg.NullPenalty, "null-penalty", cfg.NullPenalty, "similarity given up by leaving a sentence unaligned, negative to forbid it")
+	fs.IntVar(&cfg.NGram, "ngram", cfg.NGram, "order of the character n-grams of the similarity")
+	fs.Float64Var(&cfg.DiceWeight, "dice-weight", cfg.DiceWeight, "weight of the character n-gram Dice coefficient in the similarity")
+	fs.Float64Var(&cfg.LengthWeight, "length-weight", cfg.LengthWeight, "weight of the length ratio in the similarity")
+	fs.Float64Var(&cfg.ProperNounWeight, "proper-noun-weight", cfg.ProperNounWeight, "weight of the proper noun overlap in the similarity")
+	fs.Float64Var(&cfg.Mean, "gc-mean", cfg.Mean, "Gale-Church: expected target characters per source character")
+	fs.Float64Var(&cfg.Variance, "gc-variance", cfg.Variance, "Gale-Church: variance of that ratio per character")
+	fs.IntVar(&cfg.Band, "band", cfg.Band, "sentences an alignment may stray from the diagonal of a verse, 0 for no limit")
+}
+
+// parseSentenceFlags reads the aligner and filtering flags of "parallel sentences".
+func parseSentenceFlags(args []string) parallelcorpus.SentenceOptions {
+	opts := parallelcorpus.SentenceOptions{Aligner: sentencealignment.DefaultAlignerConfig()}
+
+	fs := flag.NewFlagSet("parallel sentences", flag.ExitOnError)
+	fs.Float64Var(&opts.MinScore, "min-score", 0, "leave out aligned pairs scoring under this, from 0 to 1")
+	addPairFlags(fs, &opts.Pairs)
+	addAlignerFlags(fs, &opts.Aligner)
+	fs.Parse(args)
+
+	return opts
+}
+
+// alignWordsInCorpus trains the word aligner on the verse or sentence parallel corpora.
+// --model ibm2 is fast_align's diagonal-favouring IBM Model 2; --symmetrize none keeps source to target only.
+func alignWordsInCorpus(args []string) {
+	opts := parallelcorpus.WordOptions{Aligner: wordalignment.DefaultConfig(), Workers: 4}
+
+	fs := flag.NewFlagSet("parallel words", flag.ExitOnError)
+	from := fs.String("from", "sentences", "parallel corpora to align: sentences or verses")
+	fs.Func("model", "word alignment model: ibm1 or ibm2 (default "+string(opts.Aligner.Model)+")", func(s string) error {
+		opts.Aligner.Model = wordalignment.Model(s)
+		return nil
+	})
+	fs.IntVar(&opts.Aligner.Iterations, "iterations", opts.Aligner.Iterations, "EM iterations")
+	fs.Float64Var(&opts.Aligner.Tension, "tension", opts.Aligner.Tension, "ibm2: how sharply the prior favours the diagonal")
+	fs.Float64Var(&opts.Aligner.NullProb, "null-prob", opts.Aligner.NullProb, "ibm2: prior probability of aligning a word to NULL")
+	fs.Func("symmetrize", "grow-diag-final or none (default "+string(opts.Aligner.Symmetrization)+")", func(s string) error {
+		opts.Aligner.Symmetrization = wordalignment.Symmetrization(s)
+		return nil
+	})
+	fs.Float64Var(&opts.MinProb, "min-prob", config.LEXICAL_TABLE_MIN_PROB, "leave lexical table entries under this probability out")
+	fs.IntVar(&opts.Workers, "workers", opts.Workers, "language pairs aligned at once")
+	fs.Parse(args)
+
+	opts.From = parallelCorporaFolder(*from)
+	if err := parallelcorpus.GenerateWordAlignments(opts); err != nil {
+		panic(err)
+	}
+}
+
+// parallelCorporaFolder is the folder of the verse or sentence parallel corpora named by --from
+func parallelCorporaFolder(from string) string {
+	switch from {
+	case "sentences":
+		return config.PARALLEL_SENTENCES_FOLDER
+	case "verses":
+		return config.PARALLEL_VERSES_FOLDER
+	}
+	panic(fmt.Sprintf("unknown parallel corpora %q: sentences or verses", from))
+}
+
+// induceLexicons mines ranked translation and cognate candidates from the verse or sentence parallel corpora.
+// Candidates are scored by --association (dice or pmi) over the pairs both words occur in,
+// mixed with their Jaro-Winkler similarity by --orthographic-weight.
+func induceLexicons(args []string) {
+	opts := parallelcorpus.LexiconOptions{Lexicon: lexicon.DefaultConfig(), Workers: 4}
+
+	fs := flag.NewFlagSet("lexicon", flag.ExitOnError)
+	from := fs.String("from", "verses", "parallel corpora to mine: verses or sentences")
+	fs.Func("association", "co-occurrence statistic: dice or pmi (default "+string(opts.Lexicon.Association)+")", func(s string) error {
+		opts.Lexicon.Association = lexicon.Association(s)
+		return nil
+	})
+	fs.Float64Var(&opts.Lexicon.OrthographicWeight, "orthographic-weight", opts.Lexicon.OrthographicWeight, "share of the score given to orthographic similarity, from 0 to 1")
+	fs.IntVar(&opts.Lexicon.MinCount, "min-count", opts.Lexicon.MinCount, "pairs a word must occur in to get candidates")
+	fs.IntVar(&opts.Lexicon.MinCooccurrence, "min-cooccurrence", opts.Lexicon.MinCooccurrence, "pairs a word and its candidate must share")
+	fs.Float64Var(&opts.Lexicon.CognateSimilarity, "cognate-similarity", opts.Lexicon.CognateSimilarity, "orthographic similarity from which a candidate is marked a cognate")
+	fs.IntVar(&opts.Lexicon.TopN, "top", opts.Lexicon.TopN, "candidates kept per source word")
+	fs.IntVar(&opts.Lexicon.Examples, "examples", opts.Lexicon.Examples, "example pair IDs listed per candidate")
+	fs.IntVar(&opts.Workers, "workers", opts.Workers, "language pairs mined at once")
+	fs.Parse(args)
+
+	opts.From = parallelCorporaFolder(*from)
+	if err := parallelcorpus.GenerateLexicons(opts); err != nil {
+		panic(err)
+	}
+}
+
+// exportMultiwayTable writes the verses of every language as one table with a row per verse ID
+// and a text per language: a TSV column per language, or a JSONL record per verse
+func exportMultiwayTable(reg *registry.Registry, args []string) {
+	fs := flag.NewFlagSet("parallel multiway", flag.ExitOnError)
+	format := fs.String("format", string(parallelcorpus.MultiwayTSV), "output format: tsv or jsonl")
+	outPath := fs.String("out", "", "output file (default parallel_corpus/multiway.tsv or parallel_corpus/multiway.jsonl)")
+	minLanguages := fs.Int("min-languages", 2, "languages a verse must be in to get a row")
+	fs.Parse(args)
+
+	opts := parallelcorpus.MultiwayOptions{
+		Format:       parallelcorpus.MultiwayFormat(*format),
+		Out:          *outPath,
+		MinLanguages: *minLanguages,
+	}
+	if opts.Out == "" {
+		opts.Out = config.MULTIWAY_FILE + opts.Format.Extension()
+	}
 
+	count, err := parallelcorpus.ExportMultiwayTable(reg.Languages(), opts)
 	if err != nil {
 		panic(err)
 	}
+	fmt.Printf("Exported %d verses to %s\n", count, opts.Out)
+}
+
+// triangulatePivotCorpora joins the parallel corpora of every language pair through a --pivot language
+func triangulatePivotCorpora(args []string) {
+	opts := parallelcorpus.PivotOptions{Workers: 4}
+
+	fs := flag.NewFlagSet("parallel pivot", flag.ExitOnError)
+	fs.StringVar(&opts.Pivot, "pivot", "", "language the corpora are joined through, e.g. eng")
+	from := fs.String("from", "sentences", "parallel corpora to triangulate: sentences or verses")
+	fs.IntVar(&opts.Workers, "workers", opts.Workers, "language pairs triangulated at once")
+	addPairFlags(fs, &opts.Pairs)
+	fs.Parse(args)
+
+	if opts.Pivot == "" {
+		panic("No pivot language given: --pivot eng")
+	}
+
+	opts.From = parallelCorporaFolder(*from)
+	if err := parallelcorpus.GeneratePivotCorpora(opts); err != nil {
+		panic(err)
+	}
 }
 
-func getWebscrape() {
-	chapterLimit, bibles, corpusSizes := initialize()
+// bookCodes parses a --books style selection into book codes, none for an empty one.
+func bookCodes(spec string) []string {
+	if spec == "" {
+		return nil
+	}
+	books, err := canon.Select(spec)
+	if err != nil {
+		panic(err)
+	}
+	codes := make([]string, len(books))
+	for i, b := range books {
+		codes[i] = b.Code
+	}
+	return codes
+}
 
-	cleaningTuples := types.TurnToRegexpsTuple([]types.FindReplaceTuple[string]{
-		{
-			Find:    `[^a-zA-Z0-9\s\.\,\;\:\!\?\'\"-]+`,
-			Replace: "",
-		}, {
-			Find:    `[:\s]+$`,
-			Replace: "",
-		}, {
-			Find:    `^[\d#:\s]+`,
-			Replace: "",
-		}, {
-			Find:		 `[\ ]*L[\s]ord[\ ]*`,
-			Replace: "Lord",
-		}, {
-			Find: `[\ ]*H[\s]e[\ ]*`,
-			Replace: `He`,
-		}, {
-			Find: `[\ ]*h[\s]e[\ ]*`,
-			Replace: `he`,
-		}, {
-			Find: `[\ ]*T[\s]he[\ ]*`,
-			Replace: `The`,
-		}, {
-			Find: `[\ ]*t[\s]he[\ ]*`,
-			Replace: `the`,
-		}, {
-			Find: `[\ ]*W[\s]e[\ ]*`,
-			Replace: `We`,
-		}, {
-			Find: `[\ ]*w[\s]e[\ ]*`,
-			Replace: `we`,
-		}, {
-			Find: `[\ ]*B[\s]ehold[\ ]*`,
-			Replace: `Behold`,
-		}, {
-			Find: `[\ ]*A[\s]nd[\ ]*`,
-			Replace: `And`,
-		}, {
-			Find: `[\ ]*I[\s]f[\ ]*`,
-			Replace: `If`,
-		},
+// splitTrainingData writes seeded train, valid and test sets of the verse or sentence parallel corpora.
+// --by verse draws verses at random, chapter and book hold out whole chapters or books; --valid-books
+// and --test-books name the held-out books instead. A verse is in the same split in every language pair.
+func splitTrainingData(args []string) {
+	opts := parallelcorpus.DataSplitOptions{Format: parallelcorpus.DataSplitTSV, Workers: 4, Split: datasplit.DefaultConfig()}
+
+	fs := flag.NewFlagSet("split data", flag.ExitOnError)
+	from := fs.String("from", "verses", "parallel corpora to split: verses or sentences")
+	fs.Func("by", "what is held out as a whole: verse, chapter or book (default "+string(opts.Split.Unit)+")", func(s string) error {
+		opts.Split.Unit = datasplit.Unit(s)
+		return nil
 	})
+	fs.Int64Var(&opts.Split.Seed, "seed", opts.Split.Seed, "seed of the assignment; the same seed gives the same splits")
+	fs.Float64Var(&opts.Split.Valid, "valid", opts.Split.Valid, "share of the verses, chapters or books held out for validation")
+	fs.Float64Var(&opts.Split.Test, "test", opts.Split.Test, "share of the verses, chapters or books held out for testing")
+	validBooks := fs.String("valid-books", "", "with --by book, the validation books instead of a share: codes or ot, nt, dc")
+	testBooks := fs.String("test-books", "", "with --by book, the test books instead of a share: codes or ot, nt, dc")
+	fs.Func("format", "output: tsv, a corpus per split, or text, fairseq's train.src-tgt.src files (default tsv)", func(s string) error {
+		opts.Format = parallelcorpus.DataSplitFormat(s)
+		return nil
+	})
+	fs.IntVar(&opts.Workers, "workers", opts.Workers, "language pairs split at once")
+	fs.Parse(args)
+
+	opts.From = parallelCorporaFolder(*from)
+	opts.Split.ValidBooks = bookCodes(*validBooks)
+	opts.Split.TestBooks = bookCodes(*testBooks)
+
+	reports, err := parallelcorpus.GenerateDataSplits(opts)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%-9s  %8s  %8s  %8s  %s\n", "corpus", "train", "valid", "test", "left out")
+	for _, r := range reports {
+		fmt.Printf("%-9s  %8d  %8d  %8d  %d missing, %d straddling splits, %d unlabelled\n", r.Corpus,
+			r.Pairs[datasplit.Train], r.Pairs[datasplit.Valid], r.Pairs[datasplit.Test], r.Missing, r.Straddling, r.Unlabelled)
+	}
+}
+
+// benchSentenceAlignment times the sentence aligner against the reference aligner over the sentence corpus
+func benchSentenceAlignment(reg *registry.Registry, args []string) {
+	cfg := sentencealignment.DefaultAlignerConfig()
+
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	pairs := fs.Int("pairs", 0, "benchmark only the first N language pairs, 0 for all")
+	addAlignerFlags(fs, &cfg)
+	fs.Parse(args)
 
-	webscrapeBibles(bibles, corpusSizes, cleaningTuples, chapterLimit)
+	bench, err := parallelcorpus.BenchmarkSentenceAlignment(reg.Languages(), cfg, *pairs)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%d language pairs, %d verses, %d sentences\n", bench.LanguagePairs, bench.Verses, bench.Sentences)
+	fmt.Printf("Reference: %s\n", bench.Reference)
+	fmt.Printf("Aligner:   %s (%.1fx faster)\n", bench.Aligner, bench.Speedup())
+	fmt.Printf("Aligned the same: %d of %d verses\n", bench.Same, bench.Verses)
+}
+
+// tuneSentenceAlignment grid-searches the similarity weights and n-gram order against hand-aligned gold TSVs
+func tuneSentenceAlignment(args []string) {
+	cfg := sentencealignment.DefaultAlignerConfig()
+
+	fs := flag.NewFlagSet("tune", flag.ExitOnError)
+	gold := fs.String("gold", "", "comma-separated gold sentence TSVs, named src_tgt.tsv")
+	ngrams := fs.String("ngrams", "2,3,4", "comma-separated n-gram orders to try")
+	step := fs.Float64("step", 0.1, "step of the weights, which sum to 1")
+	top := fs.Int("top", 10, "configurations to list")
+	out := fs.String("out", config.ALIGNER_CONFIG_FILE, "where to save the best configuration, empty for nowhere")
+	addAlignerFlags(fs, &cfg)
+	fs.Parse(args)
+
+	if *gold == "" {
+		panic("No gold alignment given: --gold src_tgt.tsv")
+	}
+
+	var orders []int
+	for _, field := range strings.Split(*ngrams, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			panic(fmt.Sprintf("invalid n-gram order %q", field))
+		}
+		orders = append(orders, n)
+	}
 
+	results, err := parallelcorpus.TuneAligner(strings.Split(*gold, ","), parallelcorpus.WeightGrid(cfg, orders, *step))
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("%-5s  %-5s  %-6s  %-11s  %-9s  %-6s  %-6s\n", "ngram", "dice", "length", "proper_noun", "precision", "recall", "f1")
+	for _, r := range results[:min(*top, len(results))] {
+		c := r.Config
+		fmt.Printf("%-5d  %-5.2f  %-6.2f  %-11.2f  %-9.4f  %-6.4f  %-6.4f\n", c.NGram, c.DiceWeight, c.LengthWeight, c.ProperNounWeight, r.Scores.Precision(), r.Scores.Recall(), r.Scores.F1())
+	}
+
+	if *out == "" {
+		return
+	}
+	if err := results[0].Config.Save(*out); err != nil {
+		panic(err)
+	}
+	fmt.Printf("Saved the best configuration to %s (use it with --aligner-config %s)\n", *out, *out)
+}
+
+// verseTexts yields the verse texts of a language, skipping chapters that cannot be read
+func verseTexts(corpus *biblecorpus.Index, language string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for verse, err := range corpus.Verses(language) {
+			if err != nil {
+				fmt.Println("Skipping:", err)
+				continue
+			}
+			if !yield(verse.Text) {
+				return
+			}
+		}
+	}
+}
+
+// splitSentencesInCorpus splits every language with the segmenter the registry picks for it, or with segmenterName if set.
+// Languages whose verses and segmenter are unchanged since the manifest recorded them are skipped unless force.
+func splitSentencesInCorpus(reg *registry.Registry, segmenterName string, force bool) {
+	corpus, err := biblecorpus.Open(config.CORPUS_VERSES_FOLDER)
+	if err != nil {
+		panic(err)
+	}
+
+	m, err := manifest.Load(config.MANIFEST_FILE)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, t := range reg.Translations {
+		language := t.ISO
+		if len(corpus.Chapters(language)) == 0 {
+			fmt.Printf("Skipping %s: not scraped yet\n", language)
+			continue
+		}
+
+		name := t.Segmenter
+		if segmenterName != "" {
+			name = segmenterName
+		}
+
+		versesDir, sentencesDir := path.Join(config.CORPUS_VERSES_FOLDER, language), path.Join(config.CORPUS_SENTENCES_FOLDER, language)
+		build, err := m.Plan("split", sentencesDir, []string{versesDir}, struct {
+			Segmenter     string   `json:"segmenter"`
+			Abbreviations []string `json:"abbreviations"`
+		}{name, t.Abbreviations})
+		if err != nil {
+			panic(err)
+		}
+		reason := m.Stale(build)
+		if reason == "" && !force {
+			fmt.Printf("Skipping %s: up to date\n", language)
+			continue
+		}
+		if reason != "" {
+			fmt.Printf("Splitting %s: %s\n", language, reason)
+		}
+
+		segmenter, err := sentencecleaning.NewSegmenter(name, t.Abbreviations, verseTexts(corpus, language))
+		if err != nil {
+			panic(err)
+		}
+
+		err = sentencecleaning.SplitCorpusBySentence(versesDir, sentencesDir, segmenter)
+
+		if err != nil {
+			panic(err)
+		}
+
+		if err := m.Record(build); err != nil {
+			panic(err)
+		}
+		if err := m.Save(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// reportStatus checks every artifact recorded in the manifest against its inputs and lists the stale ones
+func reportStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	manifestPath := fs.String("manifest", config.MANIFEST_FILE, "manifest to check, e.g. ../language_similarity/manifest.json")
+	all := fs.Bool("all", false, "list the up-to-date artifacts too")
+	fs.Parse(args)
+
+	m, err := manifest.Load(*manifestPath)
+	if err != nil {
+		panic(err)
+	}
+	statuses := m.Status()
+	if len(statuses) == 0 {
+		fmt.Printf("No artifacts recorded in %s yet\n", *manifestPath)
+		return
+	}
+
+	stale := 0
+	for _, s := range statuses {
+		state := "up to date"
+		if s.Reason != "" {
+			stale++
+			state = "stale: " + s.Reason
+		} else if !*all {
+			continue
+		}
+		fmt.Printf("%-23s  %-48s  %s  %s\n", s.Stage, s.Path, s.BuiltAt.Local().Format("2006-01-02 15:04"), state)
+	}
+	fmt.Printf("%d of %d artifacts stale\n", stale, len(statuses))
+}
+
+// parseScrapeFlags reads the cache, crawl policy and checkpoint flags shared by the scraping subcommands.
+// --cache stores every fetched page under --cache-dir; --replay rebuilds the
+// corpus from that cache alone and fails on pages that were never fetched.
+// --resume continues every language from the frontier saved by an interrupted run.
+// --books scrapes only the given books from generated chapter URLs.
+func parseScrapeFlags(name string, args []string) *scrapeOptions {
+	policy := scraper.DefaultCrawlPolicy()
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	useCache := fs.Bool("cache", false, "store fetched chapter HTML in the cache")
+	replay := fs.Bool("replay", false, "scrape from the HTML cache only, without the network")
+	cacheDir := fs.String("cache-dir", config.HTML_CACHE_FOLDER, "directory of the HTML cache")
+	fs.DurationVar(&policy.RequestDelay, "delay", policy.RequestDelay, "minimum delay between requests to the same host")
+	fs.IntVar(&policy.Parallelism, "parallelism", policy.Parallelism, "maximum requests in flight across all languages")
+	fs.IntVar(&policy.MaxRetries, "retries", policy.MaxRetries, "retries for a chapter after a 429, 5xx or network error")
+	fs.DurationVar(&policy.BackoffBase, "backoff", policy.BackoffBase, "first retry delay, doubled on every retry")
+	fs.DurationVar(&policy.BackoffMax, "max-backoff", policy.BackoffMax, "longest single retry delay")
+	fs.StringVar(&policy.UserAgent, "user-agent", policy.UserAgent, "User-Agent header sent with every request")
+	ignoreRobots := fs.Bool("ignore-robots", false, "do not check robots.txt before fetching")
+	resume := fs.Bool("resume", false, "continue from the checkpoint of an interrupted run")
+	rulesPath := fs.String("rules", config.CLEANING_RULES_FILE, "cleaning rule file")
+	bookSpec := fs.String("books", "", "scrape only these books: codes or ot, nt, dc, protestant, all")
+	workers := fs.Int("workers", 4, "chapters scraped at once per language with --books")
+	fs.Parse(args)
+
+	rules, err := textcleaning.LoadRules(*rulesPath)
+	if err != nil {
+		panic(err)
+	}
+
+	opts := &scrapeOptions{workers: *workers, rules: rules, dropped: textcleaning.NewDropReport()}
+	if *bookSpec != "" {
+		books, err := canon.Select(*bookSpec)
+		if err != nil {
+			panic(err)
+		}
+		opts.books = books
+	}
+
+	checkpoint := scraper.NewCheckpoint(config.CHECKPOINT_FILE, config.CHECKPOINT_INTERVAL)
+	if *resume {
+		var err error
+		checkpoint, err = scraper.LoadCheckpoint(config.CHECKPOINT_FILE, config.CHECKPOINT_INTERVAL)
+		if err != nil {
+			panic(err)
+		}
+		resumed := len(checkpoint.Languages)
+		if opts.books != nil {
+			resumed = len(checkpoint.Books)
+		}
+		fmt.Printf("Resuming %d languages from %s\n", resumed, config.CHECKPOINT_FILE)
+	}
+
+	policy.RespectRobots = !*ignoreRobots
+	crawl := &scraper.CrawlConfig{
+		Policy:   &policy,
+		Failures: &scraper.FailureReport{},
+	}
+
+	opts.crawl = crawl
+	opts.checkpoint = checkpoint
+
+	if !*useCache && !*replay {
+		return opts
+	}
+
+	cache, err := scraper.NewHTMLCache(*cacheDir)
+	if err != nil {
+		panic(err)
+	}
+
+	if *replay {
+		fmt.Printf("Replaying %d cached pages from %s\n", cache.Size(), *cacheDir)
+	}
+
+	crawl.Cache = cache
+	crawl.Replay = *replay
+	return opts
+}
+
+// reportFailedChapters lists the chapters still missing after every retry.
+func reportFailedChapters(crawl *scraper.CrawlConfig) {
+	if err := crawl.Failures.Summarize(config.FAILED_CHAPTERS_FILE); err != nil {
+		panic(err)
+	}
+}
+
+// reportDroppedCharacters lists the characters cleaning removed from each language.
+func reportDroppedCharacters(dropped *textcleaning.DropReport) {
+	if err := dropped.Summarize(config.DROPPED_CHARACTERS_FILE); err != nil {
+		panic(err)
+	}
+}
+
+// exportVerseRecords converts the by_verses tree into one record per verse in JSONL or Arrow
+func exportVerseRecords(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", string(versestore.FormatJSONL), "output format: jsonl or arrow")
+	outPath := fs.String("out", "", "output file (default corpus/verses.jsonl or corpus/verses.arrows)")
+	fs.Parse(args)
+
+	if *format != string(versestore.FormatJSONL) && *format != string(versestore.FormatArrow) {
+		panic(fmt.Sprintf("unknown export format %q", *format))
+	}
+
+	if *outPath == "" {
+		*outPath = config.VERSE_RECORDS_FILE + versestore.Format(*format).Extension()
+	}
+
+	out, err := versestore.Create(*outPath)
+	if err != nil {
+		panic(err)
+	}
+
+	count, err := versestore.ConvertTree(config.CORPUS_VERSES_FOLDER, config.ANNOTATIONS_FOLDER, out)
+	if err != nil {
+		out.Close()
+		panic(err)
+	}
+	if err := out.Close(); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Exported %d verses to %s\n", count, *outPath)
+}
+
+// testCleaningRules runs sample lines through the cleaning pipeline of a language and shows
+// every change as a before/after diff, then how often each rule fired
+func testCleaningRules(reg *registry.Registry, args []string) {
+	fs := flag.NewFlagSet("clean test", flag.ExitOnError)
+	rulesPath := fs.String("rules", config.CLEANING_RULES_FILE, "cleaning rule file")
+	language := fs.String("lang", reg.Translations[0].ISO, "language whose rules and allowed characters are used")
+	fs.Parse(args)
+
+	translation, ok := reg.Lookup(*language)
+	if !ok {
+		panic(fmt.Sprintf("language %s is not in the registry", *language))
+	}
+
+	rules, err := textcleaning.LoadRules(*rulesPath)
+	if err != nil {
+		panic(err)
+	}
+	cleaner := languageCleaner(rules, translation, nil)
+
+	// sample lines come from the given files, or stdin without any
+	var samples []string
+	if fs.NArg() == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			samples = append(samples, scanner.Text())
+		}
+	}
+	for _, file := range fs.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		samples = append(samples, strings.Split(strings.TrimRight(string(data), "\n"), "\n")...)
+	}
+
+	hits := make(map[string]int)
+	changed := 0
+
+	for i, sample := range samples {
+		cleaned, steps := cleaner.Trace(sample)
+		if len(steps) == 0 {
+			continue
+		}
+		changed++
+
+		fmt.Printf("line %d:\n", i+1)
+		for _, step := range steps {
+			hits[step.Rule] += step.Hits
+			fmt.Printf("  %s (%d)\n", step.Rule, step.Hits)
+			fmt.Printf("    - %s\n", step.Before)
+			fmt.Printf("    + %s\n", step.After)
+		}
+		fmt.Printf("  = %s\n\n", cleaned)
+	}
+
+	fmt.Printf("%d of %d lines changed for %s\n", changed, len(samples), translation.ISO)
+	for _, name := range append([]string{"nfc", "charset"}, ruleNames(cleaner.Rules)...) {
+		fmt.Printf("  %-30s %d\n", name, hits[name])
+	}
+}
+
+func ruleNames(rules []*textcleaning.Rule) []string {
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+func getWebscrape(reg *registry.Registry, opts *scrapeOptions) {
+	chapterLimit, corpusSizes := initialize(reg)
+
+	webscrapeBibles(reg.Translations, corpusSizes, chapterLimit, opts)
+
+	reportFailedChapters(opts.crawl)
+
+	reportDroppedCharacters(opts.dropped)
 }
 
 // getCorpus orchestrates the entire process of webscraping and corpus generation
-func getCorpus() {
+func getCorpus(reg *registry.Registry, opts *scrapeOptions) {
 	// 1189 is the chapterLimit number of chapters in the English Bible
-	chapterLimit, bibles, corpusSizes := initialize()
-
-	cleaningTuples := types.TurnToRegexpsTuple([]types.FindReplaceTuple[string]{
-		{
-			Find:    `[^a-zA-Z0-9\s\.\,\;\:\!\?\'\"-]+`,
-			Replace: "",
-		}, {
-			Find:    `[:\s]+$`,
-			Replace: "",
-		}, {
-			Find:    `^[\d#:\s]+`,
-			Replace: "",
-		}, {
-			Find:		`[\s]L[\s]ord[\s]`,
-			Replace: " Lord ",
-		},
-	})
+	chapterLimit, corpusSizes := initialize(reg)
 
-	webscrapeBibles(bibles, corpusSizes, cleaningTuples, chapterLimit)
+	webscrapeBibles(reg.Translations, corpusSizes, chapterLimit, opts)
 
 	summarizeCorpus(corpusSizes)
 
-	parallelizeCorpusByVerses()
+	reportFailedChapters(opts.crawl)
+
+	reportDroppedCharacters(opts.dropped)
 
-	splitSentencesInCorpus()
+	parallelizeCorpusByVerses(reg, nil)
 
-	parallelizeCorpusBySentences()
+	splitSentencesInCorpus(reg, "", false)
+
+	parallelizeCorpusBySentences(reg, parallelcorpus.SentenceOptions{Aligner: sentencealignment.DefaultAlignerConfig()})
 }
 
 func main() {
@@ -218,22 +938,65 @@ func main() {
 		panic("No argument provided")
 	}
 
+	reg := loadRegistry()
+
 	switch os.Args[1] {
 	case "corpus":
-		getCorpus()
+		getCorpus(reg, parseScrapeFlags("corpus", os.Args[2:]))
 	case "webscrape":
-		getWebscrape()
+		getWebscrape(reg, parseScrapeFlags("webscrape", os.Args[2:]))
+	case "coverage":
+		reportCoverage(reg, os.Args[2:])
+	case "clean":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "usage: clean test [--rules file] [--lang iso] [sample files...]")
+			os.Exit(2)
+		}
+		switch os.Args[2] {
+		default:
+			panic("No argument provided")
+		case "test":
+			testCleaningRules(reg, os.Args[3:])
+		}
+	case "export":
+		exportVerseRecords(os.Args[2:])
 	case "split":
-		splitSentencesInCorpus()
+		if len(os.Args) > 2 && os.Args[2] == "data" {
+			splitTrainingData(os.Args[3:])
+			break
+		}
+		fs := flag.NewFlagSet("split", flag.ExitOnError)
+		segmenterName := fs.String("segmenter", "", "sentence segmenter for every language: rules or punkt (default: as in the registry)")
+		force := fs.Bool("force", false, "split languages the manifest has up to date")
+		fs.Parse(os.Args[2:])
+		splitSentencesInCorpus(reg, *segmenterName, *force)
 	case "parallel":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "usage: parallel verses|sentences|words|multiway|pivot [flags]")
+			os.Exit(2)
+		}
 		switch os.Args[2] {
 		default:
 			panic("No argument provided")
 		case "verses", "verse", "v":
-			parallelizeCorpusByVerses()
+			parallelizeCorpusByVerses(reg, os.Args[3:])
 		case "sentences", "sentence", "s":
-			parallelizeCorpusBySentences()
+			parallelizeCorpusBySentences(reg, parseSentenceFlags(os.Args[3:]))
+		case "words", "word", "w":
+			alignWordsInCorpus(os.Args[3:])
+		case "multiway", "m":
+			exportMultiwayTable(reg, os.Args[3:])
+		case "pivot", "p":
+			triangulatePivotCorpora(os.Args[3:])
 		}
+	case "bench":
+		benchSentenceAlignment(reg, os.Args[2:])
+	case "tune":
+		tuneSentenceAlignment(os.Args[2:])
+	case "lexicon":
+		induceLexicons(os.Args[2:])
+	case "status":
+		reportStatus(os.Args[2:])
 
 	default:
 		panic("Non-exaustive switch-case or argument not found.")
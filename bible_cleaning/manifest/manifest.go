@@ -0,0 +1,392 @@
+/*
+Package manifest records how every artifact of the corpus pipeline was built:
+the content hashes of its inputs, a hash of the settings and the pipeline
+version. A stage asks it whether an artifact is stale before building it and
+records the artifact once written, so work whose inputs are unchanged is
+skipped on the next run.
+*/
+package manifest
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+)
+
+// Artifact is how a file or folder of the pipeline was built.
+type Artifact struct {
+	Stage    string            `json:"stage"`
+	Inputs   map[string]string `json:"inputs"`             // content hash by path, relative to the manifest
+	Settings string            `json:"settings"`           // hash of the settings
+	Version  int               `json:"version"`            // config.PIPELINE_VERSION of the code that built it
+	Revision string            `json:"revision,omitempty"` // VCS revision of the binary, checked by Status only
+	Output   string            `json:"output"`             // content hash of the artifact as written
+	BuiltAt  time.Time         `json:"built_at"`
+}
+
+// fileHash is a content hash remembered for a file's size and modification time.
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+/*
+Manifest is the record of the artifacts under the folder of its file, by path
+relative to that folder. It is safe for use by concurrent workers.
+*/
+type Manifest struct {
+	Artifacts map[string]*Artifact `json:"artifacts"`
+
+	path   string
+	dir    string
+	mu     sync.Mutex
+	hashes map[string]fileHash // by absolute path, so a file shared by many artifacts is read once
+}
+
+// Load reads the manifest at path, or starts an empty one if there is none yet.
+func Load(path string) (*Manifest, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{
+		Artifacts: make(map[string]*Artifact),
+		path:      abs,
+		dir:       filepath.Dir(abs),
+		hashes:    make(map[string]fileHash),
+	}
+
+	data, err := os.ReadFile(abs)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+	}
+	if m.Artifacts == nil {
+		m.Artifacts = make(map[string]*Artifact)
+	}
+	return m, nil
+}
+
+// Save writes the manifest back to its file, replacing it only once fully written.
+func (m *Manifest) Save() error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// key is a path as the manifest records it: relative to the manifest, with forward slashes.
+func (m *Manifest) key(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(m.dir, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// resolve is the path on disk of a recorded path.
+func (m *Manifest) resolve(key string) string {
+	return filepath.Join(m.dir, filepath.FromSlash(key))
+}
+
+// hashFile is the SHA-256 of a file, read again only if its size or modification time changed.
+func (m *Manifest) hashFile(path string, info fs.FileInfo) (string, error) {
+	m.mu.Lock()
+	cached, ok := m.hashes[path]
+	m.mu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.hash, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	m.mu.Lock()
+	m.hashes[path] = fileHash{size: info.Size(), modTime: info.ModTime(), hash: hash}
+	m.mu.Unlock()
+	return hash, nil
+}
+
+/*
+Hash is the content hash of a file, or of a folder: the names and hashes of
+every file under it, so adding, removing or renaming a file changes it too.
+*/
+func (m *Manifest) Hash(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return m.hashFile(abs, info)
+	}
+
+	h := sha256.New()
+	err = filepath.WalkDir(abs, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hash, err := m.hashFile(p, info)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(abs, p)
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), hash)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashSettings is the hash of the JSON encoding of a stage's settings.
+func HashSettings(settings any) (string, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// revision is the VCS revision the binary was built from, "" if it was not stamped.
+var revision = buildRevision()
+
+// buildRevision reads the revision, marked "+modified" for a tree with uncommitted changes, from the build info.
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	rev, modified := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if rev != "" && modified {
+		rev += "+modified"
+	}
+	return rev
+}
+
+// Build is an artifact about to be built: what from, and how.
+type Build struct {
+	Stage  string
+	Output string // path of the artifact
+
+	key      string
+	inputs   map[string]string
+	settings string
+}
+
+/*
+Plan hashes the inputs, files or folders, and settings of an artifact a stage
+is about to build at output.
+*/
+func (m *Manifest) Plan(stage, output string, inputs []string, settings any) (*Build, error) {
+	key, err := m.key(output)
+	if err != nil {
+		return nil, err
+	}
+	b := &Build{Stage: stage, Output: output, key: key, inputs: make(map[string]string, len(inputs))}
+
+	for _, input := range inputs {
+		inputKey, err := m.key(input)
+		if err != nil {
+			return nil, err
+		}
+		if b.inputs[inputKey], err = m.Hash(input); err != nil {
+			return nil, fmt.Errorf("failed to hash input %s of %s: %w", input, output, err)
+		}
+	}
+
+	if b.settings, err = HashSettings(settings); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+/*
+Stale says why the artifact of b must be built, or "" if it is up to date.
+Of the code that built it only the pipeline version counts: an artifact built
+by another revision of the same version is up to date, so that rebuilding the
+binary does not redo every stage.
+*/
+func (m *Manifest) Stale(b *Build) string {
+	m.mu.Lock()
+	a, ok := m.Artifacts[b.key]
+	m.mu.Unlock()
+
+	if !ok {
+		if _, err := os.Stat(b.Output); err != nil {
+			return "missing"
+		}
+		return "not in the manifest"
+	}
+	if a.Stage != b.Stage {
+		return fmt.Sprintf("built by %s", a.Stage)
+	}
+	if a.Settings != b.settings {
+		return "settings changed"
+	}
+	if reason := m.staleArtifact(b.key, a); reason != "" {
+		return reason
+	}
+	if !maps.Equal(a.Inputs, b.inputs) {
+		return changedInputs(a.Inputs, b.inputs)
+	}
+	return ""
+}
+
+// staleArtifact checks what can be checked of a recorded artifact without its stage: the version and the output.
+func (m *Manifest) staleArtifact(key string, a *Artifact) string {
+	if a.Version != config.PIPELINE_VERSION {
+		return fmt.Sprintf("built by pipeline version %d", a.Version)
+	}
+	hash, err := m.Hash(m.resolve(key))
+	if err != nil {
+		return "missing"
+	}
+	if hash != a.Output {
+		return "modified since built"
+	}
+	return ""
+}
+
+// changedInputs names the first input, in path order, that was added, removed or changed.
+func changedInputs(recorded, current map[string]string) string {
+	for _, path := range slices.Sorted(maps.Keys(current)) {
+		hash, ok := recorded[path]
+		if !ok {
+			return "new input " + path
+		}
+		if hash != current[path] {
+			return "input changed: " + path
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(recorded)) {
+		if _, ok := current[path]; !ok {
+			return "input removed: " + path
+		}
+	}
+	return ""
+}
+
+// Record notes the artifact of b as built, hashing it as written.
+func (m *Manifest) Record(b *Build) error {
+	output, err := m.Hash(b.Output)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", b.Output, err)
+	}
+
+	a := &Artifact{
+		Stage:    b.Stage,
+		Inputs:   b.inputs,
+		Settings: b.settings,
+		Version:  config.PIPELINE_VERSION,
+		Revision: revision,
+		Output:   output,
+		BuiltAt:  time.Now().UTC().Truncate(time.Second),
+	}
+
+	m.mu.Lock()
+	m.Artifacts[b.key] = a
+	m.mu.Unlock()
+	return nil
+}
+
+// Status is whether a recorded artifact is stale and why.
+type Status struct {
+	Path    string // relative to the manifest
+	Stage   string
+	Reason  string // "" if up to date
+	BuiltAt time.Time
+}
+
+/*
+Status checks every recorded artifact against its inputs as they are now, by
+stage and path. Settings are only known to the stage, so a change of settings
+shows when the stage runs, not here. Unlike Stale, it also reports an artifact
+built by another revision of the code, which a stage rebuilds only when forced
+or when the pipeline version was bumped.
+*/
+func (m *Manifest) Status() []Status {
+	var statuses []Status
+	for _, key := range slices.Sorted(maps.Keys(m.Artifacts)) {
+		a := m.Artifacts[key]
+		s := Status{Path: key, Stage: a.Stage, BuiltAt: a.BuiltAt, Reason: m.staleArtifact(key, a)}
+
+		if s.Reason == "" {
+			current := make(map[string]string, len(a.Inputs))
+			for input := range a.Inputs {
+				if hash, err := m.Hash(m.resolve(input)); err == nil {
+					current[input] = hash
+				}
+			}
+			s.Reason = changedInputs(a.Inputs, current)
+		}
+		if s.Reason == "" && a.Revision != "" && revision != "" && a.Revision != revision {
+			s.Reason = "built by revision " + a.Revision
+		}
+		statuses = append(statuses, s)
+	}
+
+	slices.SortStableFunc(statuses, func(a, b Status) int {
+		return cmp.Compare(a.Stage, b.Stage)
+	})
+	return statuses
+}
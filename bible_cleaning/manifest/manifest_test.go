@@ -0,0 +1,286 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+)
+
+// settings stands in for the settings of a stage.
+type settings struct {
+	MinScore float64
+}
+
+// writeFiles writes the files, by path relative to dir, and returns dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// built plans and records out, built from the inputs by a stage with the settings.
+func built(t *testing.T, m *Manifest, out string, inputs []string, s settings) {
+	t.Helper()
+
+	if err := os.WriteFile(out, []byte("built"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.Plan("align", out, inputs, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Record(b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	dir := writeFiles(t, t.TempDir(), map[string]string{
+		"corpus/tgl/tgl_GEN_Genesis_001.txt": "Nang pasimula.",
+		"corpus/ceb/ceb_GEN_Genesis_001.txt": "Sa sinugdan.",
+	})
+	m, err := Load(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "parallel", "ceb_tgl.tsv")
+	inputs := []string{filepath.Join(dir, "corpus", "ceb"), filepath.Join(dir, "corpus", "tgl")}
+	b, err := m.Plan("align", out, inputs, settings{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.key != "parallel/ceb_tgl.tsv" {
+		t.Errorf("key %q, want the output relative to the manifest", b.key)
+	}
+	for _, input := range []string{"corpus/ceb", "corpus/tgl"} {
+		if hash, err := m.Hash(m.resolve(input)); err != nil || b.inputs[input] != hash {
+			t.Errorf("input %s hashed %q, want %q (%v)", input, b.inputs[input], hash, err)
+		}
+	}
+	if len(b.inputs) != 2 {
+		t.Errorf("inputs %v, want the two folders", b.inputs)
+	}
+	if want, _ := HashSettings(settings{0.5}); b.settings != want {
+		t.Errorf("settings hashed %q, want %q", b.settings, want)
+	}
+	if other, _ := HashSettings(settings{0.6}); b.settings == other {
+		t.Error("different settings hash the same")
+	}
+
+	if _, err := m.Plan("align", out, []string{filepath.Join(dir, "corpus", "ilo")}, settings{}); err == nil {
+		t.Error("Plan with a missing input made a build, want an error")
+	}
+}
+
+func TestStale(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *Manifest, dir string)
+		stage  string
+		inputs []string
+		s      settings
+		want   string
+	}{
+		{"unchanged", nil, "align", []string{"ceb", "tgl", "lexicon.tsv"}, settings{0.5}, ""},
+		{"changed settings", nil, "align", []string{"ceb", "tgl", "lexicon.tsv"}, settings{0.6}, "settings changed"},
+		{"other stage", nil, "triangulate", []string{"ceb", "tgl", "lexicon.tsv"}, settings{0.5}, "built by align"},
+		{"new input", nil, "align", []string{"ceb", "tgl", "lexicon.tsv", "ilo"}, settings{0.5}, "new input ilo"},
+		{"removed input", nil, "align", []string{"ceb", "tgl"}, settings{0.5}, "input removed: lexicon.tsv"},
+		{"changed input", func(m *Manifest, dir string) {
+			writeFiles(t, dir, map[string]string{"tgl/tgl_GEN_Genesis_002.txt": "At nayari ang langit."})
+		}, "align", []string{"ceb", "tgl", "lexicon.tsv"}, settings{0.5}, "input changed: tgl"},
+		{"edited output", func(m *Manifest, dir string) {
+			writeFiles(t, dir, map[string]string{"ceb_tgl.tsv": "edited by hand"})
+		}, "align", []string{"ceb", "tgl", "lexicon.tsv"}, settings{0.5}, "modified since built"},
+		{"deleted output", func(m *Manifest, dir string) {
+			os.Remove(filepath.Join(dir, "ceb_tgl.tsv"))
+		}, "align", []string{"ceb", "tgl", "lexicon.tsv"}, settings{0.5}, "missing"},
+		{"older version", func(m *Manifest, dir string) {
+			m.Artifacts["ceb_tgl.tsv"].Version--
+		}, "align", []string{"ceb", "tgl", "lexicon.tsv"}, settings{0.5}, fmt.Sprintf("built by pipeline version %d", config.PIPELINE_VERSION-1)},
+		{"not recorded", func(m *Manifest, dir string) {
+			delete(m.Artifacts, "ceb_tgl.tsv")
+		}, "align", []string{"ceb", "tgl", "lexicon.tsv"}, settings{0.5}, "not in the manifest"},
+		{"never built", func(m *Manifest, dir string) {
+			delete(m.Artifacts, "ceb_tgl.tsv")
+			os.Remove(filepath.Join(dir, "ceb_tgl.tsv"))
+		}, "align", []string{"ceb", "tgl", "lexicon.tsv"}, settings{0.5}, "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, t.TempDir(), map[string]string{
+				"tgl/tgl_GEN_Genesis_001.txt": "Nang pasimula.",
+				"ceb/ceb_GEN_Genesis_001.txt": "Sa sinugdan.",
+				"ilo/ilo_GEN_Genesis_001.txt": "Idi punganay.",
+				"lexicon.tsv":                 "dios\tdios",
+			})
+			paths := func(names []string) []string {
+				var out []string
+				for _, name := range names {
+					out = append(out, filepath.Join(dir, name))
+				}
+				return out
+			}
+			m, err := Load(filepath.Join(dir, "manifest.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := filepath.Join(dir, "ceb_tgl.tsv")
+			built(t, m, out, paths([]string{"ceb", "tgl", "lexicon.tsv"}), settings{0.5})
+			if tt.change != nil {
+				tt.change(m, dir)
+			}
+
+			b, err := m.Plan(tt.stage, out, paths(tt.inputs), tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Stale(b); got != tt.want {
+				t.Errorf("Stale = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashFolder(t *testing.T) {
+	dir := writeFiles(t, t.TempDir(), map[string]string{
+		"a/tgl/tgl_GEN_Genesis_001.txt":     "Nang pasimula.",
+		"a/tgl/sub/tgl_GEN_Genesis_002.txt": "At nayari.",
+		"b/tgl/tgl_GEN_Genesis_001.txt":     "Nang pasimula.",
+		"b/tgl/sub/tgl_GEN_Genesis_002.txt": "At nayari.",
+	})
+	m, err := Load(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder := filepath.Join(dir, "a", "tgl")
+	hash := func(path string) string {
+		t.Helper()
+		h, err := m.Hash(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	original := hash(folder)
+	if hash(filepath.Join(dir, "b", "tgl")) != original {
+		t.Error("folders with the same files hash differently")
+	}
+	if hash(filepath.Join(folder, "tgl_GEN_Genesis_001.txt")) == original {
+		t.Error("a folder hashes as one of its files")
+	}
+
+	changes := []struct {
+		name   string
+		change func() error
+	}{
+		{"edited file", func() error {
+			return os.WriteFile(filepath.Join(folder, "sub", "tgl_GEN_Genesis_002.txt"), []byte("At nayari ang langit."), 0o644)
+		}},
+		{"added file", func() error {
+			return os.WriteFile(filepath.Join(folder, "tgl_GEN_Genesis_003.txt"), nil, 0o644)
+		}},
+		{"renamed file", func() error {
+			return os.Rename(filepath.Join(folder, "tgl_GEN_Genesis_003.txt"), filepath.Join(folder, "tgl_GEN_Genesis_004.txt"))
+		}},
+		{"moved file", func() error {
+			return os.Rename(filepath.Join(folder, "tgl_GEN_Genesis_004.txt"), filepath.Join(folder, "sub", "tgl_GEN_Genesis_004.txt"))
+		}},
+	}
+	seen := []string{original}
+	for _, c := range changes {
+		if err := c.change(); err != nil {
+			t.Fatal(err)
+		}
+		h := hash(folder)
+		if slices.Contains(seen, h) {
+			t.Errorf("%s: folder hash unchanged", c.name)
+		}
+		seen = append(seen, h)
+	}
+
+	if err := os.Remove(filepath.Join(folder, "sub", "tgl_GEN_Genesis_004.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if hash(folder) != seen[1] {
+		t.Error("removing the added file did not bring back the hash from before it was added")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	dir := writeFiles(t, t.TempDir(), map[string]string{
+		"corpus/tgl.txt": "Nang pasimula.",
+		"corpus/ceb.txt": "Sa sinugdan.",
+	})
+	path := filepath.Join(dir, "manifest.json")
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(old string) { revision = old }(revision)
+	revision = "abc123"
+	built(t, m, filepath.Join(dir, "tgl.out"), []string{filepath.Join(dir, "corpus", "tgl.txt")}, settings{})
+	built(t, m, filepath.Join(dir, "ceb.out"), []string{filepath.Join(dir, "corpus", "ceb.txt")}, settings{})
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if a := m.Artifacts["tgl.out"]; a == nil || a.Revision != "abc123" || a.Stage != "align" {
+		t.Fatalf("loaded artifact %+v, want the one recorded", a)
+	}
+
+	reasons := func() []string {
+		var got []string
+		for _, s := range m.Status() {
+			got = append(got, s.Path+": "+s.Reason)
+		}
+		return got
+	}
+	if got := reasons(); !slices.Equal(got, []string{"ceb.out: ", "tgl.out: "}) {
+		t.Errorf("statuses %q, want both up to date", got)
+	}
+
+	writeFiles(t, dir, map[string]string{"corpus/tgl.txt": "Nang pasimula ay nilikha."})
+	if got := reasons(); !slices.Equal(got, []string{"ceb.out: ", "tgl.out: input changed: corpus/tgl.txt"}) {
+		t.Errorf("statuses %q, want tgl.out stale", got)
+	}
+
+	revision = "def456"
+	want := []string{"ceb.out: built by revision abc123", "tgl.out: input changed: corpus/tgl.txt"}
+	if got := reasons(); !slices.Equal(got, want) {
+		t.Errorf("statuses %q, want %q", got, want)
+	}
+	b, err := m.Plan("align", filepath.Join(dir, "ceb.out"), []string{filepath.Join(dir, "corpus", "ceb.txt")}, settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason := m.Stale(b); reason != "" {
+		t.Errorf("Stale = %q for an artifact of another revision, want it up to date", reason)
+	}
+
+	revision = ""
+	if got := reasons(); got[0] != "ceb.out: " {
+		t.Errorf("status %q from an unstamped binary, want up to date", got[0])
+	}
+}
@@ -0,0 +1,92 @@
+package parallelcorpus
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/sentencealignment"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// AlignmentBenchmark compares the sentence aligner with sentencealignment.AlignSentencesReference.
+type AlignmentBenchmark struct {
+	LanguagePairs int
+	Verses        int
+	Sentences     int // source and target sentences aligned
+	Reference     time.Duration
+	Aligner       time.Duration
+	Same          int // verses both align into the same pairs
+}
+
+// Speedup is how many times faster the aligner is than the reference.
+func (b *AlignmentBenchmark) Speedup() float64 {
+	if b.Aligner == 0 {
+		return 0
+	}
+	return float64(b.Reference) / float64(b.Aligner)
+}
+
+/*
+Aligns the sentences of every pair of the given languages with both the aligner
+and the reference, one pair at a time on a single thread, and times the two.
+Reading the corpus is not timed. maxPairs > 0 stops after that many pairs.
+*/
+func BenchmarkSentenceAlignment(languages []string, cfg sentencealignment.AlignerConfig, maxPairs int) (*AlignmentBenchmark, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	corpus, err := indexLanguages(config.CORPUS_SENTENCES_FOLDER, languages)
+	if err != nil {
+		return nil, err
+	}
+	index, langs := corpus.FileMap(), corpus.Languages()
+	caches := buildLanguageNounCaches(corpus)
+
+	bench := &AlignmentBenchmark{}
+	for pair := range buildLanguagePairJobs(allLanguagePairs(langs)) {
+		if maxPairs > 0 && bench.LanguagePairs == maxPairs {
+			break
+		}
+		src, tgt := pair[0], pair[1]
+		cache := types.JoinProperNouns(caches[src], caches[tgt])
+
+		var verses []verseSentences
+		for verse := range verseSentenceGroups(src, tgt, index) {
+			verses = append(verses, verse)
+			bench.Sentences += len(verse.Src) + len(verse.Tgt)
+		}
+
+		reference := make([][]types.TextPair, len(verses))
+		start := time.Now()
+		for i, verse := range verses {
+			reference[i] = sentencealignment.AlignSentencesReference(verse.Src, verse.Tgt, verse.ID, cache, cfg)
+		}
+		referenceTime := time.Since(start)
+
+		aligned := make([][]types.TextPair, len(verses))
+		start = time.Now()
+		for i, verse := range verses {
+			aligned[i] = sentencealignment.AlignSentencesByGaleChurchDP(verse.Src, verse.Tgt, verse.ID, cache, cfg)
+		}
+		alignerTime := time.Since(start)
+
+		same := 0
+		for i := range verses {
+			if reflect.DeepEqual(reference[i], aligned[i]) {
+				same++
+			}
+		}
+
+		fmt.Printf("%s <--> %s: %d verses, reference %s, aligner %s, %d aligned the same\n", src, tgt, len(verses), referenceTime, alignerTime, same)
+
+		bench.LanguagePairs++
+		bench.Verses += len(verses)
+		bench.Reference += referenceTime
+		bench.Aligner += alignerTime
+		bench.Same += same
+	}
+	return bench, nil
+}
@@ -0,0 +1,156 @@
+package parallelcorpus
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/datasplit"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+/*
+
+	# Train, validation and test splits
+
+*/
+
+// DataSplitFormat is how the splits are written.
+type DataSplitFormat string
+
+const (
+	DataSplitTSV  DataSplitFormat = "tsv"  // "<split>/src_tgt.tsv", as the parallel corpora
+	DataSplitText DataSplitFormat = "text" // "<split>.src-tgt.src" and ".tgt", a line per pair, for fairseq-preprocess
+)
+
+// DataSplitOptions configures the splitting of the parallel corpora.
+type DataSplitOptions struct {
+	From    string // folder of the parallel corpora, by verses or by sentences
+	Format  DataSplitFormat
+	Workers int // language pairs split at once
+	Split   datasplit.Config
+}
+
+// saveParallelTSV writes a corpus with the columns of the sentence corpora if sentences, else of the verse corpora.
+func saveParallelTSV(entry *types.ParallelCorpusEntry, sentences bool, path, outdir string) error {
+	if sentences {
+		return entry.SaveAsTSVSentences(path, outdir)
+	}
+	return entry.SaveAsTSV(path, outdir)
+}
+
+// writeLines writes a text per line, its own line breaks folded into spaces.
+func writeLines(path string, texts []string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for _, text := range texts {
+		if _, err := w.WriteString(strings.Join(strings.Fields(text), " ") + "\n"); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// saveSplitText writes a split of a corpus as the two line-aligned text files fairseq reads.
+func saveSplitText(entry *types.ParallelCorpusEntry, split datasplit.Split, outdir string) error {
+	if err := os.MkdirAll(outdir, os.ModePerm); err != nil {
+		return err
+	}
+
+	sources := make([]string, len(entry.Pairs))
+	targets := make([]string, len(entry.Pairs))
+	for i, pair := range entry.Pairs {
+		sources[i], targets[i] = pair.SourceText, pair.TargetText
+	}
+
+	prefix := filepath.Join(outdir, fmt.Sprintf("%s.%s-%s.", split, entry.SourceLang, entry.TargetLang))
+	if err := writeLines(prefix+entry.SourceLang, sources); err != nil {
+		return err
+	}
+	return writeLines(prefix+entry.TargetLang, targets)
+}
+
+// savePartition writes every split of a corpus to outdir in the format.
+func savePartition(entry *types.ParallelCorpusEntry, p *datasplit.Partition, format DataSplitFormat, outdir string) error {
+	sentences := len(entry.Pairs) > 0 && entry.Pairs[0].Sentence != ""
+	name := fmt.Sprintf("%s_%s.tsv", entry.SourceLang, entry.TargetLang)
+
+	for _, split := range datasplit.Splits {
+		var err error
+		if format == DataSplitText {
+			err = saveSplitText(p.Splits[split], split, outdir)
+		} else {
+			err = saveParallelTSV(p.Splits[split], sentences, name, filepath.Join(outdir, string(split)))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DataSplitReport is how the pairs of a parallel corpus were split.
+type DataSplitReport struct {
+	Corpus     string // "src_tgt"
+	Pairs      map[datasplit.Split]int
+	Missing    int
+	Straddling int
+	Unlabelled int
+}
+
+/*
+Splits every parallel corpus in opts.From into train, validation and test sets
+and writes them to the splits folder, under the name of the corpora's folder.
+Pairs are assigned by their verse, chapter or book alone, so the same verse is
+in the same split in every language pair. Returns a report per corpus, sorted by name.
+*/
+func GenerateDataSplits(opts DataSplitOptions) ([]DataSplitReport, error) {
+	if opts.Format != DataSplitTSV && opts.Format != DataSplitText {
+		return nil, fmt.Errorf("unknown split format %q", opts.Format)
+	}
+	if err := opts.Split.Validate(); err != nil {
+		return nil, err
+	}
+
+	outdir := filepath.Join(config.DATA_SPLITS_FOLDER, filepath.Base(opts.From))
+
+	var mu sync.Mutex
+	var reports []DataSplitReport
+	err := processParallelCorpora(opts.From, opts.Workers, "splitting", func(entry *types.ParallelCorpusEntry) error {
+		p := datasplit.Divide(entry, opts.Split)
+		if err := savePartition(entry, p, opts.Format, outdir); err != nil {
+			return err
+		}
+
+		report := DataSplitReport{
+			Corpus:     fmt.Sprintf("%s_%s", entry.SourceLang, entry.TargetLang),
+			Pairs:      make(map[datasplit.Split]int, len(p.Splits)),
+			Missing:    p.Missing,
+			Straddling: p.Straddling,
+			Unlabelled: p.Unlabelled,
+		}
+		for split, e := range p.Splits {
+			report.Pairs[split] = len(e.Pairs)
+		}
+
+		mu.Lock()
+		reports = append(reports, report)
+		mu.Unlock()
+		return nil
+	})
+
+	slices.SortFunc(reports, func(a, b DataSplitReport) int {
+		return strings.Compare(a.Corpus, b.Corpus)
+	})
+	return reports, err
+}
@@ -0,0 +1,34 @@
+package parallelcorpus
+
+import (
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/lexicon"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+/*
+
+	# Bilingual lexicon induction
+
+*/
+
+// LexiconOptions configures the lexicon induction over the parallel corpora.
+type LexiconOptions struct {
+	From    string // folder of the parallel corpora to mine, by verses or by sentences
+	Workers int    // language pairs mined at once
+	Lexicon lexicon.Config
+}
+
+/*
+Induces a bilingual lexicon from every parallel corpus in opts.From and writes
+the ranked candidates of each language pair to the lexicons folder.
+*/
+func GenerateLexicons(opts LexiconOptions) error {
+	if err := opts.Lexicon.Validate(); err != nil {
+		return err
+	}
+
+	return processParallelCorpora(opts.From, opts.Workers, "lexicon induction", func(entry *types.ParallelCorpusEntry) error {
+		return lexicon.Induce(entry, opts.Lexicon).Save(config.LEXICONS_FOLDER)
+	})
+}
@@ -0,0 +1,209 @@
+package parallelcorpus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/zrygan.nlp/bible_cleaning/biblecorpus"
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+/*
+
+	# Multi-way aligned verse table
+
+*/
+
+// MultiwayFormat is the file format of the multi-way table.
+type MultiwayFormat string
+
+const (
+	MultiwayTSV   MultiwayFormat = "tsv"   // a column per language, missing verses labelled
+	MultiwayJSONL MultiwayFormat = "jsonl" // a record per verse, missing verses left out
+)
+
+// Extension is the file extension of the format, with its dot.
+func (f MultiwayFormat) Extension() string {
+	return "." + string(f)
+}
+
+// MultiwayOptions configures the multi-way table export.
+type MultiwayOptions struct {
+	Format       MultiwayFormat
+	Out          string // output file
+	MinLanguages int    // languages a verse must be in to get a row
+}
+
+// MultiwayRow is a verse, or span of verses, in every language that has it.
+type MultiwayRow struct {
+	ID      string            `json:"id"`
+	Book    string            `json:"book"`
+	Chapter int               `json:"chapter"`
+	Verse   string            `json:"verse"`
+	Texts   map[string]string `json:"texts"` // by language
+}
+
+// chapterIDs lists the "BOOK_CCC" chapters any of the languages has, in canonical order.
+func chapterIDs(corpus *biblecorpus.Index) []types.PairID {
+	seen := make(map[types.PairID]bool)
+	var ids []types.PairID
+	for _, lang := range corpus.Languages() {
+		for _, chapter := range corpus.Chapters(lang) {
+			id := types.PairID{Book: chapter.Book, Chapter: chapter.Chapter}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	slices.SortFunc(ids, types.PairID.Compare)
+	return ids
+}
+
+/*
+Yields a row per verse of the corpus, chapter by chapter in canonical order,
+joined across the languages on verse number as the verse pairs are: a verse
+merged in one translation ("3-4") absorbs the matching verses of all the
+others. Rows in fewer than minLanguages languages are left out, and chapters
+that cannot be read are reported and left out of their language.
+*/
+func multiwayRows(corpus *biblecorpus.Index, minLanguages int) iter.Seq[MultiwayRow] {
+	langs := corpus.Languages()
+	files := corpus.FileMap()
+
+	return func(yield func(MultiwayRow) bool) {
+		for _, chapterID := range chapterIDs(corpus) {
+			label := fmt.Sprintf("%s_%s", chapterID.Book, chapterID.ChapterLabel())
+
+			verses := make([][]types.Verse, len(langs))
+			numbers := make([][]types.VerseNumber, len(langs))
+			for i, lang := range langs {
+				path, ok := files[lang][label]
+				if !ok {
+					continue
+				}
+				read, err := types.ReadChapterFile(path)
+				if err != nil {
+					fmt.Printf("Skipping chapter %s (%s): %v\n", label, path, err)
+					continue
+				}
+				verses[i], numbers[i] = read, verseNumbers(read)
+			}
+
+			for _, group := range types.GroupVerseNumbersMultiway(numbers) {
+				id := chapterID
+				id.Verse = group.Number
+
+				row := MultiwayRow{
+					ID:      id.String(),
+					Book:    id.Book,
+					Chapter: id.Chapter,
+					Verse:   id.Verse.String(),
+					Texts:   make(map[string]string),
+				}
+				for i, members := range group.Members {
+					if len(members) > 0 {
+						row.Texts[langs[i]] = joinVerseTexts(verses[i], members)
+					}
+				}
+				if len(row.Texts) < minLanguages {
+					continue
+				}
+
+				if !yield(row) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeMultiwayTSV writes the rows with a column per language, a verse a language lacks labelled as missing.
+func writeMultiwayTSV(w *bufio.Writer, langs []string, rows iter.Seq[MultiwayRow]) (int, error) {
+	header := append([]string{"id", "book", "chapter", "verse"}, langs...)
+	if _, err := w.WriteString(strings.Join(header, "\t") + "\n"); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	fields := make([]string, len(header))
+	for row := range rows {
+		fields[0], fields[1], fields[2], fields[3] = row.ID, row.Book, fmt.Sprintf("%03d", row.Chapter), row.Verse
+		for i, lang := range langs {
+			text, ok := row.Texts[lang]
+			if !ok {
+				text = config.TOKEN_MISSING_TRANSLATION
+			}
+			fields[4+i] = types.TransfromEscapeCharTSV(text)
+		}
+		if _, err := w.WriteString(strings.Join(fields, "\t") + "\n"); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// writeMultiwayJSONL writes a record per row.
+func writeMultiwayJSONL(w *bufio.Writer, rows iter.Seq[MultiwayRow]) (int, error) {
+	encoder := json.NewEncoder(w)
+	count := 0
+	for row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+/*
+Exports the verses of the given languages as one table with a row per verse
+ID and a text per language, for multilingual training. Returns the number of
+rows written.
+*/
+func ExportMultiwayTable(languages []string, opts MultiwayOptions) (int, error) {
+	if opts.Format != MultiwayTSV && opts.Format != MultiwayJSONL {
+		return 0, fmt.Errorf("unknown multi-way format %q", opts.Format)
+	}
+
+	corpus, err := indexLanguages(config.CORPUS_VERSES_FOLDER, languages)
+	if err != nil {
+		return 0, err
+	}
+	langs := corpus.Languages()
+	if len(langs) == 0 {
+		return 0, fmt.Errorf("no languages in %s", config.CORPUS_VERSES_FOLDER)
+	}
+	fmt.Printf("Found %d languages, exporting the multi-way table...\n", len(langs))
+
+	if err := os.MkdirAll(filepath.Dir(opts.Out), os.ModePerm); err != nil {
+		return 0, err
+	}
+	file, err := os.Create(opts.Out)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create multi-way table: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	rows := multiwayRows(corpus, opts.MinLanguages)
+
+	var count int
+	if opts.Format == MultiwayTSV {
+		count, err = writeMultiwayTSV(w, langs, rows)
+	} else {
+		count, err = writeMultiwayJSONL(w, rows)
+	}
+	if err != nil {
+		return count, fmt.Errorf("failed to write multi-way table: %w", err)
+	}
+	return count, w.Flush()
+}
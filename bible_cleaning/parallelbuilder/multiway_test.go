@@ -0,0 +1,60 @@
+package parallelcorpus
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/biblecorpus"
+)
+
+func TestMultiwayRows(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"tgl/tgl_GEN_Genesis_001.txt": "verse\tcontent\n001\tNang pasimula.\n002\tAt ang lupa.\n003\tAt sinabi ng Dios.\n004\tAt nakita ng Dios.\n",
+		"ceb/ceb_GEN_Genesis_001.txt": "verse\tcontent\n001\tSa sinugdan.\n003-004\tUg miingon ang Dios.\n",
+		"ilo/ilo_GEN_Genesis_001.txt": "verse\tcontent\n001\tIdi punganay.\n002\tIti daga.\n",
+		"ilo/ilo_EXO_Exodo_001.txt":   "verse\tcontent\n001\tTa dagitoy dagiti nagan.\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	corpus, err := biblecorpus.Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	describe := func(minLanguages int) []string {
+		var rows []string
+		for row := range multiwayRows(corpus, minLanguages) {
+			var texts []string
+			for _, lang := range corpus.Languages() {
+				if text, ok := row.Texts[lang]; ok {
+					texts = append(texts, lang+": "+text)
+				}
+			}
+			rows = append(rows, row.ID+" "+row.Verse+" | "+strings.Join(texts, " | "))
+		}
+		return rows
+	}
+
+	want := []string{
+		"GEN_001_001 001 | ceb: Sa sinugdan. | ilo: Idi punganay. | tgl: Nang pasimula.",
+		"GEN_001_002 002 | ilo: Iti daga. | tgl: At ang lupa.",
+		"GEN_001_003-004 003-004 | ceb: Ug miingon ang Dios. | tgl: At sinabi ng Dios. At nakita ng Dios.",
+		"EXO_001_001 001 | ilo: Ta dagitoy dagiti nagan.",
+	}
+	if got := describe(1); !slices.Equal(got, want) {
+		t.Errorf("rows\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := describe(3); !slices.Equal(got, want[:1]) {
+		t.Errorf("rows in all three languages\n%s\nwant\n%s", strings.Join(got, "\n"), want[0])
+	}
+}
@@ -0,0 +1,162 @@
+package parallelcorpus
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/zrygan.nlp/bible_cleaning/biblecorpus"
+	"github.com/zrygan.nlp/bible_cleaning/manifest"
+)
+
+/*
+
+	# Selection of the language pairs to build
+
+*/
+
+// PairSelection chooses the language pairs a parallel builder builds.
+type PairSelection struct {
+	Specs []string // "src-tgt", "src-*" or "*-tgt"; every pair in alphabetical order when empty
+	Force bool     // rebuild pairs the manifest has up to date
+}
+
+// allLanguagePairs is every unordered pair of the languages, the alphabetically first as the source.
+func allLanguagePairs(langs []string) [][2]string {
+	sorted := slices.Sorted(slices.Values(langs))
+
+	var pairs [][2]string
+	for i := 0; i < len(sorted); i++ {
+		for j := i + 1; j < len(sorted); j++ {
+			pairs = append(pairs, [2]string{sorted[i], sorted[j]})
+		}
+	}
+	return pairs
+}
+
+/*
+Expands pair specs into the ordered pairs they name, in the order given and
+without repeats: "tgl-ceb" is tgl to ceb, "tgl-*" tgl to every other language
+and "*-tgl" every other language to tgl. "*-*" is every ordered pair. A
+language that is not in langs is an error.
+*/
+func selectLanguagePairs(specs []string, langs []string) ([][2]string, error) {
+	if len(specs) == 0 {
+		return allLanguagePairs(langs), nil
+	}
+
+	expand := func(side, spec string) ([]string, error) {
+		if side == "*" {
+			return langs, nil
+		}
+		if !slices.Contains(langs, side) {
+			return nil, fmt.Errorf("language %s of pair %q is not in the corpus", side, spec)
+		}
+		return []string{side}, nil
+	}
+
+	seen := make(map[[2]string]bool)
+	var pairs [][2]string
+	for _, spec := range specs {
+		src, tgt, ok := strings.Cut(strings.TrimSpace(spec), "-")
+		if !ok || src == "" || tgt == "" {
+			return nil, fmt.Errorf("invalid language pair %q: expected src-tgt, src-* or *-tgt", spec)
+		}
+
+		sources, err := expand(src, spec)
+		if err != nil {
+			return nil, err
+		}
+		targets, err := expand(tgt, spec)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range sources {
+			for _, t := range targets {
+				pair := [2]string{s, t}
+				if s == t || seen[pair] {
+					continue
+				}
+				seen[pair] = true
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no language pairs selected by %s", strings.Join(specs, ","))
+	}
+	return pairs, nil
+}
+
+// pairLanguages lists the languages of the pairs, sorted.
+func pairLanguages(pairs [][2]string) []string {
+	var langs []string
+	for _, pair := range pairs {
+		langs = append(langs, pair[0], pair[1])
+	}
+	slices.Sort(langs)
+	return slices.Compact(langs)
+}
+
+// languageFolders lists the folders of the languages in a chapter corpus.
+func languageFolders(corpus *biblecorpus.Index, langs ...string) []string {
+	folders := make([]string, len(langs))
+	for i, lang := range langs {
+		folders[i] = filepath.Join(corpus.Root, lang)
+	}
+	return folders
+}
+
+/*
+Selects the pairs of langs to build and plans their "src_tgt.tsv" in outdir
+from the inputs of each pair and the settings of the stage. Unless forced,
+pairs the manifest has up to date are dropped.
+*/
+func (s PairSelection) jobs(m *manifest.Manifest, stage string, langs []string, outdir string, settings any, inputs func(src, tgt string) []string) ([][2]string, map[[2]string]*manifest.Build, error) {
+	pairs, err := selectLanguagePairs(s.Specs, langs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stale [][2]string
+	builds := make(map[[2]string]*manifest.Build)
+	for _, pair := range pairs {
+		path := filepath.Join(outdir, fmt.Sprintf("%s_%s.tsv", pair[0], pair[1]))
+		build, err := m.Plan(stage, path, inputs(pair[0], pair[1]), settings)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		reason := m.Stale(build)
+		if reason == "" && !s.Force {
+			fmt.Printf("Skipping %s: up to date\n", path)
+			continue
+		}
+		if reason != "" {
+			fmt.Printf("Rebuilding %s: %s\n", path, reason)
+		}
+		stale = append(stale, pair)
+		builds[pair] = build
+	}
+	return stale, builds, nil
+}
+
+// chapterJobs selects the pairs of the languages of a chapter corpus, the folders of both languages being the inputs.
+func (s PairSelection) chapterJobs(m *manifest.Manifest, stage string, corpus *biblecorpus.Index, outdir string, settings any) ([][2]string, map[[2]string]*manifest.Build, error) {
+	return s.jobs(m, stage, corpus.Languages(), outdir, settings, func(src, tgt string) []string {
+		return languageFolders(corpus, src, tgt)
+	})
+}
+
+// recordBuild notes a built artifact in the manifest, or reports why it was not built.
+func recordBuild(m *manifest.Manifest, b *manifest.Build, err error) {
+	if err == nil {
+		err = m.Record(b)
+	}
+	if err != nil {
+		fmt.Printf("Failed %s of %s: %v\n", b.Stage, b.Output, err)
+	}
+}
@@ -0,0 +1,73 @@
+package parallelcorpus
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSelectLanguagePairs(t *testing.T) {
+	langs := []string{"ceb", "ilo", "tgl"}
+
+	tests := []struct {
+		name  string
+		specs []string
+		want  string
+	}{
+		{"none", nil, "ceb-ilo ceb-tgl ilo-tgl"},
+		{"one pair", []string{"tgl-ceb"}, "tgl-ceb"},
+		{"from a language", []string{"tgl-*"}, "tgl-ceb tgl-ilo"},
+		{"into a language", []string{"*-tgl"}, "ceb-tgl ilo-tgl"},
+		{"every ordered pair", []string{"*-*"}, "ceb-ilo ceb-tgl ilo-ceb ilo-tgl tgl-ceb tgl-ilo"},
+		{"in the order given", []string{"tgl-ilo", "ceb-tgl"}, "tgl-ilo ceb-tgl"},
+		{"without repeats", []string{"ceb-tgl", "*-tgl", " ceb-tgl "}, "ceb-tgl ilo-tgl"},
+		{"both directions", []string{"ceb-tgl", "tgl-ceb"}, "ceb-tgl tgl-ceb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, err := selectLanguagePairs(tt.specs, langs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, pair := range pairs {
+				got = append(got, pair[0]+"-"+pair[1])
+			}
+			if !slices.Equal(got, strings.Fields(tt.want)) {
+				t.Errorf("pairs of %q: %v, want %s", tt.specs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectLanguagePairsErrors(t *testing.T) {
+	langs := []string{"ceb", "ilo", "tgl"}
+
+	tests := []struct {
+		name  string
+		specs []string
+		want  string
+	}{
+		{"unknown source", []string{"war-tgl"}, "language war"},
+		{"unknown target", []string{"ceb-tgl", "tgl-war"}, "language war"},
+		{"no separator", []string{"tgl"}, "invalid language pair"},
+		{"missing target", []string{"tgl-"}, "invalid language pair"},
+		{"missing source", []string{"-tgl"}, "invalid language pair"},
+		{"self pair", []string{"tgl-tgl"}, "no language pairs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, err := selectLanguagePairs(tt.specs, langs)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("pairs of %q: %v, %v; want an error containing %q", tt.specs, pairs, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairLanguages(t *testing.T) {
+	pairs := [][2]string{{"tgl", "ceb"}, {"ilo", "tgl"}, {"ceb", "ilo"}}
+	if got := pairLanguages(pairs); !slices.Equal(got, []string{"ceb", "ilo", "tgl"}) {
+		t.Errorf("languages %v, want each once, sorted", got)
+	}
+}
@@ -2,23 +2,22 @@ package parallelcorpus
 
 import (
 	"fmt"
+	"iter"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/zrygan.nlp/bible_cleaning/biblecorpus"
 	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/manifest"
 	"github.com/zrygan.nlp/bible_cleaning/sentencealignment"
 	"github.com/zrygan.nlp/bible_cleaning/types"
 	"github.com/zrygan.nlp/bible_cleaning/workerprogress"
 )
 
-func nChoose2(n int) int {
-	return n * (n - 1) / 2
-}
-
 func getParallelQueenConfig() *workerprogress.QueenConfig {
 	return &workerprogress.QueenConfig{
 		IsDetailed:     config.IS_DETAILED,
@@ -36,62 +35,44 @@ func GetKeys(bibles map[string]map[string]string) []string {
 	return result
 }
 
-// readLines reads a file into a slice of strings (one per line).
-func readLines(path string) ([]string, error) {
-	data, err := os.ReadFile(path)
+/*
+Indexes the chapter files under root for the given languages.
+Corpus folders of translations no longer registered are left out.
+*/
+func indexLanguages(root string, languages []string) (*biblecorpus.Index, error) {
+	corpus, err := biblecorpus.Open(root)
 	if err != nil {
 		return nil, err
 	}
-	lines := strings.Split(string(data), "\n")
-	out := make([]string, 0, len(lines))
-	for _, line := range lines {
-		if trimmed := strings.TrimSpace(line); trimmed != "" {
-			out = append(out, trimmed)
-		}
-	}
-	return out, nil
-}
 
-func indexLanguageFileMap(root string) (map[string]map[string]string, error) {
-	files, err := filepath.Glob(root + "/*/*.txt")
-	if err != nil {
-		return nil, err
+	for _, path := range corpus.Skipped {
+		fmt.Println("Skipping:", path)
 	}
 
-	re := regexp.MustCompile(`^([a-z]+)_([A-Z]+)_[^_]+_(\d+)\.txt$`)
-	index := make(map[string]map[string]string)
-
-	for _, file := range files {
-		base := filepath.Base(file)
-		lang := filepath.Base(filepath.Dir(file))
-
-		matches := re.FindStringSubmatch(base)
-		if matches == nil {
-			continue
-		}
-		book := matches[2]
-		verse := matches[3]
-		verseID := book + "_" + verse
+	return corpus.WithLanguages(languages...), nil
+}
 
-		if _, ok := index[lang]; !ok {
-			index[lang] = make(map[string]string)
-		}
-		index[lang][verseID] = file
+// parseChapterID splits a "BOOK_CCC" chapter ID of the index into book and chapter.
+func parseChapterID(chapterID string) (string, int, error) {
+	book, chapter, ok := strings.Cut(chapterID, "_")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid chapter ID %q", chapterID)
 	}
-
-	return index, nil
+	n, err := strconv.Atoi(chapter)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid chapter ID %q", chapterID)
+	}
+	return book, n, nil
 }
 
 /*
-Builds a channel of jobs for each unique language pair (n choose 2).
+Builds a channel of jobs for the language pairs, source first.
 */
-func buildLanguagePairJobs(languageKeys []string) chan [2]string {
-	jobCh := make(chan [2]string, 200)
-	for i := 0; i < len(languageKeys); i++ {
-		for j := i + 1; j < len(languageKeys); j++ {
-			fmt.Printf("Queued job for language pair %s-%s...\n", languageKeys[i], languageKeys[j])
-			jobCh <- [2]string{languageKeys[i], languageKeys[j]}
-		}
+func buildLanguagePairJobs(pairs [][2]string) chan [2]string {
+	jobCh := make(chan [2]string, len(pairs))
+	for _, pair := range pairs {
+		fmt.Printf("Queued job for language pair %s-%s...\n", pair[0], pair[1])
+		jobCh <- pair
 	}
 	close(jobCh)
 	return jobCh
@@ -126,6 +107,60 @@ func createLanguagePairThreadPool(numOfThreads int, jobCh chan [2]string, queenC
 	}
 }
 
+// parallelCorpusJobs queues a job for every "src_tgt.tsv" parallel corpus in dir.
+func parallelCorpusJobs(dir string) (chan [2]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*_*.tsv"))
+	if err != nil {
+		return nil, err
+	}
+
+	jobCh := make(chan [2]string, len(paths))
+	for _, path := range paths {
+		src, tgt, _ := strings.Cut(strings.TrimSuffix(filepath.Base(path), ".tsv"), "_")
+		jobCh <- [2]string{src, tgt}
+	}
+	close(jobCh)
+	return jobCh, nil
+}
+
+/*
+Reads every parallel corpus in dir and hands it to process, numOfThreads at a
+time. task names the work in the progress reports.
+*/
+func processParallelCorpora(dir string, numOfThreads int, task string, process func(*types.ParallelCorpusEntry) error) error {
+	jobCh, err := parallelCorpusJobs(dir)
+	if err != nil {
+		return err
+	}
+	if len(jobCh) == 0 {
+		return fmt.Errorf("no parallel corpora in %s", dir)
+	}
+	fmt.Printf("Created %d jobs from %s.\n", len(jobCh), dir)
+
+	queenCtx := workerprogress.NewQueenContext(len(jobCh), getParallelQueenConfig())
+	go queenCtx.RunReporter()
+
+	createLanguagePairThreadPool(numOfThreads, jobCh, *queenCtx, func(src, tgt string, prg workerprogress.WorkerProgressContext) {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%s.tsv", src, tgt))
+		entry, err := types.ReadParallelTSV(path)
+		if err == nil {
+			err = process(entry)
+		}
+		if err != nil {
+			fmt.Printf("Failed %s of %s: %v\n", task, path, err)
+			return
+		}
+
+		prg.Progress <- workerprogress.WorkerProgressMsg{
+			WorkerID: prg.WorkerID,
+			Percent:  1.0,
+			Status:   fmt.Sprintf("Finished %s for %s <--> %s (%d pairs)", task, src, tgt, len(entry.Pairs)),
+		}
+	})
+	closeoutThreadPool(queenCtx)
+	return nil
+}
+
 /*
 	Waits for all workers to finish, then closes the quit channel to signal the reporter to stop.
 */
@@ -139,30 +174,6 @@ func closeoutThreadPool(queenCtx *workerprogress.QueenContext) {
 	fmt.Printf("All done in %s!\n", &elapsed)
 }
 
-/*
-Reads the source and target lines for a given verseID from the index.
-Errors if the required file is not found.
-*/
-func readSrcTgtLines(srcFile string, index *map[string]map[string]string, tgt string, verseID string) ([]string, []string, error) {
-	tgtFile, ok := (*index)[tgt][verseID]
-
-	if !ok {
-		return nil, nil, fmt.Errorf("target file not found for %s in %s", verseID, tgt)
-	}
-
-	srcLines, err := readLines(srcFile)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	tgtLines, err := readLines(tgtFile)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return srcLines, tgtLines, nil
-}
-
 func findVerseAlignments(srcIndex, tgtIndex map[string]string) (shared, missingSrc, missingTgt []string) {
 	// 1. Check verses from src perspective
 	for verseID := range srcIndex {
@@ -183,47 +194,80 @@ func findVerseAlignments(srcIndex, tgtIndex map[string]string) (shared, missingS
 	return shared, missingSrc, missingTgt
 }
 
-func sharedKeys(a, b map[string][]string) []string {
-	var shared []string
-	for k := range a {
-		if _, ok := b[k]; ok {
-			shared = append(shared, k)
+/*
+Returns the verse IDs of a verse map in verse order together with their parsed numbers.
+IDs that are not verse numbers are dropped.
+*/
+func sortedVerseNumbers(verses map[string][]string) ([]string, []types.VerseNumber) {
+	ids := make([]string, 0, len(verses))
+	numbers := make(map[string]types.VerseNumber, len(verses))
+	for id := range verses {
+		number, err := types.ParseVerseNumber(id)
+		if err != nil {
+			continue
 		}
+		ids = append(ids, id)
+		numbers[id] = number
 	}
-	sort.Strings(shared)
-	return shared
-}
 
-func readVerseMap(path string) (map[string][]string, error) {
-	lines, err := readLines(path)
-	if err != nil {
-		return nil, err
+	sort.Slice(ids, func(i, j int) bool {
+		return numbers[ids[i]].Start < numbers[ids[j]].Start
+	})
+
+	ordered := make([]types.VerseNumber, len(ids))
+	for i, id := range ids {
+		ordered[i] = numbers[id]
 	}
+	return ids, ordered
+}
 
-	if len(lines) > 0 && strings.HasPrefix(lines[0], "verse") {
-		lines = lines[1:] // remove header
+// collectSentences concatenates the sentences of the verses at the given indices.
+func collectSentences(verses map[string][]string, ids []string, indices []int) []string {
+	var sentences []string
+	for _, idx := range indices {
+		sentences = append(sentences, verses[ids[idx]]...)
 	}
+	return sentences
+}
 
+// readVerseMap groups the rows of a chapter file by verse number, streaming the file.
+func readVerseMap(path string) (map[string][]string, error) {
 	verses := make(map[string][]string)
 
-	for _, line := range lines {
-		parts := strings.SplitN(line, "\t", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		verseID := strings.TrimSpace(parts[0])
-		content := strings.TrimSpace(types.RemoveEscapeCharTSV(parts[1]))
-
-		if content == "" {
-			continue
+	for verse, err := range types.ScanChapterFile(path) {
+		if err != nil {
+			return nil, err
 		}
-
-		verses[verseID] = append(verses[verseID], content)
+		verseID := verse.Number.String()
+		verses[verseID] = append(verses[verseID], verse.Text)
 	}
 
 	return verses, nil
 }
 
+func verseNumbers(verses []types.Verse) []types.VerseNumber {
+	numbers := make([]types.VerseNumber, len(verses))
+	for i, v := range verses {
+		numbers[i] = v.Number
+	}
+	return numbers
+}
+
+/*
+Joins the verses at the given indices into one text.
+A verse the translation lacks is labelled explicitly instead of left empty.
+*/
+func joinVerseTexts(verses []types.Verse, indices []int) string {
+	if len(indices) == 0 {
+		return config.TOKEN_MISSING_TRANSLATION
+	}
+
+	texts := make([]string, len(indices))
+	for i, idx := range indices {
+		texts[i] = verses[idx].Text
+	}
+	return strings.Join(texts, " ")
+}
 
 /*
 
@@ -233,8 +277,10 @@ func readVerseMap(path string) (map[string][]string, error) {
 
 /*
 Given a source and target language, builds a parallel corpus by aligning verses by verseID.
+Verses are joined on their verse number: merged verses ("3-4") absorb the matching
+verses of the other translation and verses missing on one side are labelled.
 */
-func buildCorpusVerses(src, tgt string, index map[string]map[string]string, outdir string, prg workerprogress.WorkerProgressContext) {
+func buildCorpusVerses(src, tgt string, index map[string]map[string]string, outdir string, prg workerprogress.WorkerProgressContext) error {
 	entry := &types.ParallelCorpusEntry{
 		SourceLang: src,
 		TargetLang: tgt,
@@ -245,25 +291,26 @@ func buildCorpusVerses(src, tgt string, index map[string]map[string]string, outd
 	for verseID, srcFile := range index[src] {
 		if tgtFile, ok := index[tgt][verseID]; ok {
 			fmt.Printf("Processing (src %s, dst %s) with files (%s, %s)\n", src, tgt, srcFile, index[tgt][verseID])
-			book := strings.SplitN(verseID, "_", 2)[0]
-			chapter := strings.SplitN(verseID, "_", 2)[1]
-
-			srcContent, _ := os.ReadFile(srcFile)
-			tgtContent, _ := os.ReadFile(tgtFile)
-
-			srcLines := strings.Split(strings.TrimSpace(string(srcContent)), "\n")
-			tgtLines := strings.Split(strings.TrimSpace(string(tgtContent)), "\n")
-
-			for v := 0; v < len(srcLines) && v < len(tgtLines); v++ {
-				verseNum := fmt.Sprintf("%03d", v+1)
-				entry.Pairs = append(entry.Pairs, types.TextPair{
-					SourceText: strings.TrimSpace(srcLines[v]),
-					TargetText: strings.TrimSpace(tgtLines[v]),
-					ID:         fmt.Sprintf("%s_%s_%s", book, chapter, verseNum),
-					Book:       book,
-					Chapter:    chapter,
-					Verse:      verseNum,
-				})
+			book, chapter, err := parseChapterID(verseID)
+			if err != nil {
+				fmt.Printf("Skipping chapter %s: %v\n", verseID, err)
+				continue
+			}
+
+			srcVerses, err := types.ReadChapterFile(srcFile)
+			if err != nil {
+				fmt.Printf("Skipping chapter %s (%s): failed to read src: %v\n", verseID, srcFile, err)
+				continue
+			}
+			tgtVerses, err := types.ReadChapterFile(tgtFile)
+			if err != nil {
+				fmt.Printf("Skipping chapter %s (%s): failed to read tgt: %v\n", verseID, tgtFile, err)
+				continue
+			}
+
+			for _, group := range types.GroupVerseNumbers(verseNumbers(srcVerses), verseNumbers(tgtVerses)) {
+				id := types.PairID{Book: book, Chapter: chapter, Verse: group.Number}
+				entry.Pairs = append(entry.Pairs, types.NewTextPair(id, joinVerseTexts(srcVerses, group.Src), joinVerseTexts(tgtVerses, group.Tgt)))
 			}
 		}
 		n = n + 1
@@ -286,117 +333,180 @@ func buildCorpusVerses(src, tgt string, index map[string]map[string]string, outd
 	// 	Status:   fmt.Sprintf("Built sentence-level corpus for %s <--> %s (%03d pairs); Saving TSV file. ", src, tgt, len(entry.Pairs)),
 	// }
 	fmt.Printf("Done Sort and Send (%s, %s)... Saving...\n", src, tgt);
-	entry.SaveAsTSV(fmt.Sprintf("%s_%s.tsv", src, tgt), outdir)
+	if err := entry.SaveAsTSV(fmt.Sprintf("%s_%s.tsv", src, tgt), outdir); err != nil {
+		return err
+	}
 	fmt.Printf("Done Saving (%s, %s)... End.\n", src, tgt);
+	return nil
 }
 
 /*
 Wrapper to pass additional parameters to the worker function.
-Mainly used for createLanguagePairThreadPool.
+Mainly used for createLanguagePairThreadPool. Built pairs are recorded in the manifest.
 */
-func buildCorpusVersesWrapper(index map[string]map[string]string, outdir string) func(string, string, workerprogress.WorkerProgressContext) {
+func buildCorpusVersesWrapper(index map[string]map[string]string, outdir string, m *manifest.Manifest, builds map[[2]string]*manifest.Build) func(string, string, workerprogress.WorkerProgressContext) {
 	return func(src, tgt string, prg workerprogress.WorkerProgressContext) {
-		buildCorpusVerses(src, tgt, index, outdir, prg)
+		err := buildCorpusVerses(src, tgt, index, outdir, prg)
+		recordBuild(m, builds[[2]string{src, tgt}], err)
 	}
 }
 
 /*
 Initializes the verse-level parallel corpus generation by indexing the files and creating the output directory.
 */
-func initializeParallelCorpusByVerses() (map[string]map[string]string, []string, error) {
+func initializeParallelCorpusByVerses(languages []string) (*biblecorpus.Index, error) {
 	root := config.CORPUS_VERSES_FOLDER
 
 	// Make sure destination directory exists
 	if err := os.MkdirAll(config.PARALLEL_VERSES_FOLDER, os.ModePerm); err != nil {
-		return nil, nil, err
-	}
-
-	index, err := indexLanguageFileMap(root)
-
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Get a list of unique languages
-	langs := make([]string, 0, len(index))
-	for k := range index {
-		langs = append(langs, k)
+		return nil, err
 	}
 
-	sort.Strings(langs)
-	return index, langs, nil
+	return indexLanguages(root, languages)
 }
 
 /*
-Generates the parallel corpus by verses for all language pairs found in the corpus/verses folder.
+Generates the parallel corpus by verses for the selected pairs of the given languages found in the corpus/verses folder.
+Pairs whose chapters are unchanged since the manifest recorded them are skipped unless forced.
 It creates a thread pool to process multiple language pairs in parallel.
 */
-func GenerateParallelCorpusByVerses() error {
-	index, langs, err := initializeParallelCorpusByVerses()
+func GenerateParallelCorpusByVerses(languages []string, selection PairSelection) error {
+	corpus, err := initializeParallelCorpusByVerses(languages)
 
 	if err != nil {
 		return err
 	}
+	index, langs := corpus.FileMap(), corpus.Languages()
+
+	m, err := manifest.Load(config.MANIFEST_FILE)
+	if err != nil {
+		return err
+	}
+	pairs, builds, err := selection.chapterJobs(m, "parallel verses", corpus, config.PARALLEL_VERSES_FOLDER, nil)
+	if err != nil {
+		return err
+	}
+	if len(pairs) == 0 {
+		fmt.Println("Every selected language pair is up to date.")
+		return nil
+	}
 
 	println(fmt.Sprintf("Found %d languages, generating parallel corpora...", len(langs)))
-	// launch workers for each unique pair of languages
-	total := nChoose2(len(langs))
-	queenCtx := workerprogress.NewQueenContext(total, getParallelQueenConfig())
-	jobCh := buildLanguagePairJobs(langs)
+	// launch workers for each selected pair of languages
+	queenCtx := workerprogress.NewQueenContext(len(pairs), getParallelQueenConfig())
+	jobCh := buildLanguagePairJobs(pairs)
 	fmt.Printf("Created %d jobs for %d languages.\n", len(jobCh), len(langs))
 
 	go queenCtx.RunReporter()
-	createLanguagePairThreadPool(config.THREAD_POOL_SIZE, jobCh, *queenCtx, buildCorpusVersesWrapper(index, config.PARALLEL_VERSES_FOLDER))
+	createLanguagePairThreadPool(config.THREAD_POOL_SIZE, jobCh, *queenCtx, buildCorpusVersesWrapper(index, config.PARALLEL_VERSES_FOLDER, m, builds))
 	closeoutThreadPool(queenCtx)
-	return nil
+	return m.Save()
 }
 
 /**
 # Sentence-level parallel corpus generation.
 */
 
-func initializeParallelCorpusBySentences(root string) (map[string]map[string]string, []string, error) {
+func initializeParallelCorpusBySentences(root string, languages []string) (*biblecorpus.Index, error) {
 
 	if err := os.MkdirAll(config.PARALLEL_SENTENCES_FOLDER, os.ModePerm); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	index, err := indexLanguageFileMap(root)
+	corpus, err := indexLanguages(root, languages)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	langs := GetKeys(index)
-
-	fmt.Printf("Found %d languages for sentence-level corpus generation.\n", len(langs))
-	return index, langs, nil
+	fmt.Printf("Found %d languages for sentence-level corpus generation.\n", len(corpus.Languages()))
+	return corpus, nil
 }
 
-func buildLanguageNounCache (src, tgt string, index map[string]map[string]string) *types.ProperNounCache {
-	var allSrcSents, allTgtSents []string
-	for _, srcFile := range index[src] {
-		srcVerses, err := readVerseMap(srcFile)
-		if err == nil {
-			for _, sents := range srcVerses {
-				allSrcSents = append(allSrcSents, sents...)
+/*
+Builds the proper noun cache of every language once, streaming its sentences.
+The pairs share these caches instead of re-reading both languages per pair.
+*/
+func buildLanguageNounCaches(corpus *biblecorpus.Index) map[string]*types.ProperNounCache {
+	caches := make(map[string]*types.ProperNounCache)
+	for _, lang := range corpus.Languages() {
+		cache := types.NewProperNounCache()
+		for sentence, err := range corpus.Sentences(lang) {
+			if err != nil {
+				fmt.Println("Skipping:", err)
+				continue
 			}
+			cache.Add(sentence.Text)
 		}
+
+		fmt.Printf("Found %d proper nouns in %s\n", len(cache.Words), lang)
+		caches[lang] = cache
 	}
-	for _, tgtFile := range index[tgt] {
-		tgtVerses, err := readVerseMap(tgtFile)
-		if err == nil {
-			for _, sents := range tgtVerses {
-				allTgtSents = append(allTgtSents, sents...)
+	return caches
+}
+
+// verseSentences are the sentences of a verse, or span of merged verses, in both languages.
+type verseSentences struct {
+	ID       types.PairID
+	Src, Tgt []string
+}
+
+/*
+Yields the sentences of every verse both languages have, chapter by chapter,
+grouping the verses either translation merges. Chapters that one language lacks
+or that cannot be read are reported and skipped.
+*/
+func verseSentenceGroups(src, tgt string, index map[string]map[string]string) iter.Seq[verseSentences] {
+	return func(yield func(verseSentences) bool) {
+		for chapterName, srcFile := range index[src] {
+
+			// Find corresponding target file (same chapter)
+			tgtFile, ok := index[tgt][chapterName]
+			if !ok {
+				fmt.Printf("Missing chapter %s in %s\n", chapterName, tgt)
+				continue
 			}
-		}
-	}
 
-	// Step 2: Build proper noun cache (from both source and target)
-	properNounCache := types.ExtractProperNouns(
-		append(allSrcSents, allTgtSents...),
-	)
+			book, chapter, err := parseChapterID(chapterName)
+			if err != nil {
+				fmt.Printf("Invalid chapter name format: %s\n", chapterName)
+				continue
+			}
+
+			srcVerses, err := readVerseMap(srcFile)
+			if err != nil {
+				fmt.Printf("Skipping chapter %s (%s): failed to read src: %v\n", chapterName, srcFile, err)
+				continue
+			}
+			tgtVerses, err := readVerseMap(tgtFile)
+			if err != nil {
+				fmt.Printf("Skipping chapter %s (%s): failed to read tgt: %v\n", chapterName, tgtFile, err)
+				continue
+			}
+
+			srcIDs, srcNumbers := sortedVerseNumbers(srcVerses)
+			tgtIDs, tgtNumbers := sortedVerseNumbers(tgtVerses)
+
+			for _, group := range types.GroupVerseNumbers(srcNumbers, tgtNumbers) {
+				// sentences of a verse the other translation lacks have nothing to align to
+				if len(group.Src) == 0 || len(group.Tgt) == 0 {
+					continue
+				}
 
-	return properNounCache
+				verse := verseSentences{
+					ID:  types.PairID{Book: book, Chapter: chapter, Verse: group.Number},
+					Src: collectSentences(srcVerses, srcIDs, group.Src),
+					Tgt: collectSentences(tgtVerses, tgtIDs, group.Tgt),
+				}
+				if len(verse.Src) == 0 || len(verse.Tgt) == 0 {
+					continue
+				}
+
+				if !yield(verse) {
+					return
+				}
+			}
+		}
+	}
 }
 
 // buildCorpusSentences aligns verse-level TSVs (verse\tcontent) between src and tgt languages.
@@ -404,120 +514,135 @@ func buildLanguageNounCache (src, tgt string, index map[string]map[string]string
 func buildCorpusSentences(
 	src, tgt string,
 	index map[string]map[string]string, // chapterName -> filepath per language
+	caches map[string]*types.ProperNounCache,
+	opts SentenceOptions,
 	outdir string,
 	prg workerprogress.WorkerProgressContext,
-) {
+) error {
 	entry := &types.ParallelCorpusEntry{
 		SourceLang: src,
 		TargetLang: tgt,
 	}
 
-	cache := buildLanguageNounCache(src, tgt, index)
-
-	for chapterName, srcFile := range index[src] {
+	cache := types.JoinProperNouns(caches[src], caches[tgt])
 
-		// Find corresponding target file (same chapter)
-		tgtFile, ok := index[tgt][chapterName]
-		if !ok {
-			fmt.Printf("Missing chapter %s in %s\n", chapterName, tgt)
-			continue
-		}
-
-		srcVerses, err := readVerseMap(srcFile)
-		if err != nil {
-			fmt.Printf("Skipping chapter %s (%s): failed to read src: %v\n", chapterName, srcFile, err)
-			continue
-		}
-		tgtVerses, err := readVerseMap(tgtFile)
-		if err != nil {
-			fmt.Printf("Skipping chapter %s (%s): failed to read tgt: %v\n", chapterName, tgtFile, err)
-			continue
+	wholeVerses := 0
+	for verse := range verseSentenceGroups(src, tgt, index) {
+		aligner := sentencealignment.NewAligner(verse.Src, verse.Tgt, cache)
+		pairs := aligner.Align(verse.ID, opts.Aligner)
+		if aligner.WholeVerse() {
+			wholeVerses++
 		}
 
-		shared := sharedKeys(srcVerses, tgtVerses)
-
-		for _, verseID := range shared {
-			srcSentences := srcVerses[verseID]
-			tgtSentences := tgtVerses[verseID]
+		entry.Pairs = append(entry.Pairs, pairs...)
 
-			if len(srcSentences) == 0 || len(tgtSentences) == 0 {
-				continue
-			}
+		prg.Progress <- workerprogress.WorkerProgressMsg{
+			WorkerID: prg.WorkerID,
+			Status:   fmt.Sprintf("Aligned %s (%d pairs)", verse.ID.Verse, len(pairs)),
+		}
+	}
 
-			pairs := sentencealignment.AlignSentencesByGaleChurchDP(srcSentences, tgtSentences, verseID, cache)
-			
-			chapterParts := strings.SplitN(chapterName, "_", 2)
-			if len(chapterParts) != 2 {
-				fmt.Printf("Invalid chapter name format: %s\n", chapterName)
-				continue
-			}
-			book := chapterParts[0]
-			chapter := chapterParts[1]
-
-			for i := range pairs {
-				sentenceNum := fmt.Sprintf("%d", i+1)
-				pairs[i].ID = fmt.Sprintf("%s_%s_%s", book, chapter, sentenceNum)
-				pairs[i].Book = book
-				pairs[i].Chapter = chapter
-				pairs[i].Sentence = sentenceNum
-			}
-			
-			entry.Pairs = append(entry.Pairs, pairs...)
+	if wholeVerses > 0 {
+		fmt.Printf("Kept %d verses of %s <--> %s as one pair each: no alignment without null beads fits them\n", wholeVerses, src, tgt)
+	}
 
-			prg.Progress <- workerprogress.WorkerProgressMsg{
-				WorkerID: prg.WorkerID,
-				Status:   fmt.Sprintf("Aligned %s (%d pairs)", verseID, len(pairs)),
-			}
-		}
+	aligned := len(entry.Pairs)
+	entry = entry.Filter(func(pair types.TextPair) bool {
+		return pair.Score == nil || *pair.Score >= opts.MinScore
+	})
+	if dropped := aligned - len(entry.Pairs); dropped > 0 {
+		fmt.Printf("Dropped %d of %d pairs of %s <--> %s scoring under %.2f\n", dropped, aligned, src, tgt, opts.MinScore)
 	}
 
 	entry.Sort()
 
 	outPath := fmt.Sprintf("%s_%s.tsv", src, tgt)
 	fmt.Printf("Saving aligned corpus: %s/%s\n", outdir, outPath)
-	entry.SaveAsTSVSentences(outPath, outdir)
+	if err := entry.SaveAsTSVSentences(outPath, outdir); err != nil {
+		return err
+	}
 
 	prg.Progress <- workerprogress.WorkerProgressMsg{
 		WorkerID: prg.WorkerID,
 		Percent:  1.0,
 		Status:   fmt.Sprintf("Finished alignment for %s <--> %s (%03d pairs)", src, tgt, len(entry.Pairs)),
 	}
+	return nil
 }
 
 
 /*
 Wrapper to pass additional parameters to the worker function.
-Mainly used for createLanguagePairThreadPool.
+Mainly used for createLanguagePairThreadPool. Built pairs are recorded in the manifest.
 */
-func buildCorpusSentencesWrapper(index map[string]map[string]string, outdir string) func(string, string, workerprogress.WorkerProgressContext) {
+func buildCorpusSentencesWrapper(index map[string]map[string]string, caches map[string]*types.ProperNounCache, opts SentenceOptions, outdir string, m *manifest.Manifest, builds map[[2]string]*manifest.Build) func(string, string, workerprogress.WorkerProgressContext) {
 	return func(src, tgt string, prg workerprogress.WorkerProgressContext) {
-		buildCorpusSentences(src, tgt, index, outdir, prg)
+		err := buildCorpusSentences(src, tgt, index, caches, opts, outdir, prg)
+		recordBuild(m, builds[[2]string{src, tgt}], err)
 	}
 }
 
+// SentenceOptions configures the sentence-level parallel corpus generation.
+type SentenceOptions struct {
+	MinScore float64 // aligned pairs scoring lower are left out; 0 keeps every pair
+	Pairs    PairSelection
+	Aligner  sentencealignment.AlignerConfig
+}
+
+// settings are the options that change what is written, as the manifest records them.
+func (opts SentenceOptions) settings() any {
+	return struct {
+		MinScore float64                         `json:"min_score"`
+		Aligner  sentencealignment.AlignerConfig `json:"aligner"`
+	}{opts.MinScore, opts.Aligner}
+}
+
 /*
-Generates the parallel corpus by sentences for all language pairs found in the corpus/verses folder.
+Generates the parallel corpus by sentences for the selected pairs of the given languages found in the corpus/verses folder.
+Pairs whose chapters and settings are unchanged since the manifest recorded them are skipped unless forced.
 It creates a thread pool to process multiple language pairs in parallel.
 */
-func GenerateParallelCorpusBySentences() error {
+func GenerateParallelCorpusBySentences(languages []string, opts SentenceOptions) error {
 	root := config.CORPUS_SENTENCES_FOLDER
 
-	index, langs, err := initializeParallelCorpusBySentences(root)
+	if err := opts.Aligner.Validate(); err != nil {
+		return err
+	}
+
+	corpus, err := initializeParallelCorpusBySentences(root, languages)
+
+	if err != nil {
+		return err
+	}
 
+	m, err := manifest.Load(config.MANIFEST_FILE)
 	if err != nil {
 		return err
 	}
+	pairs, builds, err := opts.Pairs.chapterJobs(m, "parallel sentences", corpus, config.PARALLEL_SENTENCES_FOLDER, opts.settings())
+	if err != nil {
+		return err
+	}
+	if len(pairs) == 0 {
+		fmt.Println("Every selected language pair is up to date.")
+		return nil
+	}
+
+	// only the languages of the pairs need their proper nouns
+	corpus = corpus.WithLanguages(pairLanguages(pairs)...)
+	index, langs := corpus.FileMap(), corpus.Languages()
+	caches := buildLanguageNounCaches(corpus)
 
-	queenCtx := workerprogress.NewQueenContext(nChoose2(len(langs)), getParallelQueenConfig())
+	queenCtx := workerprogress.NewQueenContext(len(pairs), getParallelQueenConfig())
 
 	fmt.Printf("Found %d languages, generating parallel corpora...\n", len(langs))
-	jobCh := buildLanguagePairJobs(langs)
+	jobCh := buildLanguagePairJobs(pairs)
 	fmt.Printf("Created %d jobs for %d languages.\n", len(jobCh), len(langs))
 
 	go queenCtx.RunReporter()
 
-	createLanguagePairThreadPool(config.THREAD_POOL_SIZE, jobCh, *queenCtx, buildCorpusSentencesWrapper(index, config.PARALLEL_SENTENCES_FOLDER))
+	createLanguagePairThreadPool(config.THREAD_POOL_SIZE, jobCh, *queenCtx, buildCorpusSentencesWrapper(index, caches, opts, config.PARALLEL_SENTENCES_FOLDER, m, builds))
 
 	closeoutThreadPool(queenCtx)
-	return nil
+	return m.Save()
 }
@@ -0,0 +1,221 @@
+package parallelcorpus
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/manifest"
+	"github.com/zrygan.nlp/bible_cleaning/sentencealignment"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+	"github.com/zrygan.nlp/bible_cleaning/workerprogress"
+)
+
+/*
+
+	# Pivot triangulation of the parallel corpora
+
+*/
+
+// PivotOptions configures the triangulation of the parallel corpora through a pivot language.
+type PivotOptions struct {
+	Pivot   string // language the corpora are joined through
+	From    string // folder of the parallel corpora, by verses or by sentences
+	Pairs   PairSelection
+	Workers int // language pairs triangulated at once
+}
+
+/*
+pivotCorpora finds the parallel corpora in dir with the pivot on one side, by
+the language on the other. Where a language has a corpus in each direction,
+the one from the language to the pivot ("ceb_tgl.tsv" for pivot tgl) is used.
+*/
+func pivotCorpora(dir, pivot string) (map[string]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*_*.tsv"))
+	if err != nil {
+		return nil, err
+	}
+
+	corpora := make(map[string]string)
+	for _, path := range paths {
+		src, tgt, _ := strings.Cut(strings.TrimSuffix(filepath.Base(path), ".tsv"), "_")
+		switch pivot {
+		case src:
+			if _, ok := corpora[tgt]; !ok {
+				corpora[tgt] = path
+			}
+		case tgt:
+			corpora[src] = path
+		}
+	}
+	return corpora, nil
+}
+
+// readPivotCorpus reads the corpus of a language and the pivot with the language as the source.
+func readPivotCorpus(path, lang string) (*types.ParallelCorpusEntry, error) {
+	entry, err := types.ReadParallelTSV(path)
+	if err != nil {
+		return nil, err
+	}
+	if entry.SourceLang != lang {
+		entry = entry.Reversed()
+	}
+	return entry, nil
+}
+
+// pivotKey is the pivot side of a pair, whitespace-normalized, in its chapter.
+type pivotKey struct {
+	book, chapter, text string
+}
+
+func isMissing(pair types.TextPair) bool {
+	return pair.SourceText == config.TOKEN_MISSING_TRANSLATION || pair.TargetText == config.TOKEN_MISSING_TRANSLATION
+}
+
+// composeMerge joins the merges "a-p" and "p-b" of the two legs into "a-b".
+func composeMerge(srcLeg, tgtLeg string) string {
+	a, _, ok := strings.Cut(srcLeg, "-")
+	_, b, ok2 := strings.Cut(tgtLeg, "-")
+	if !ok || !ok2 {
+		return ""
+	}
+	return a + "-" + b
+}
+
+/*
+Triangulates a corpus from srcLeg (A to pivot) and tgtLeg (pivot to B): pairs
+whose pivot sides are the same text, in overlapping verses of the same chapter,
+become an A to B pair. Its verse is the span of both, its score the product of
+theirs. Pairs with a missing side have no pivot to join on and are left out.
+*/
+func triangulate(srcLeg, tgtLeg *types.ParallelCorpusEntry) *types.ParallelCorpusEntry {
+	byPivot := make(map[pivotKey][]types.TextPair)
+	for _, pair := range tgtLeg.Pairs {
+		if isMissing(pair) {
+			continue
+		}
+		key := pivotKey{pair.Book, pair.Chapter, strings.Join(strings.Fields(pair.SourceText), " ")}
+		byPivot[key] = append(byPivot[key], pair)
+	}
+
+	entry := &types.ParallelCorpusEntry{SourceLang: srcLeg.SourceLang, TargetLang: tgtLeg.TargetLang}
+	sentences := make(map[types.PairID]int) // sentence pairs numbered so far per verse
+	for _, src := range srcLeg.Pairs {
+		if isMissing(src) {
+			continue
+		}
+		srcID, err := types.ParsePairID(src.ID)
+		if err != nil {
+			continue
+		}
+
+		key := pivotKey{src.Book, src.Chapter, strings.Join(strings.Fields(src.TargetText), " ")}
+		for _, tgt := range byPivot[key] {
+			tgtID, err := types.ParsePairID(tgt.ID)
+			if err != nil || !srcID.Verse.Overlaps(tgtID.Verse) {
+				continue
+			}
+
+			id := types.PairID{
+				Book:    srcID.Book,
+				Chapter: srcID.Chapter,
+				Verse: types.VerseNumber{
+					Start: min(srcID.Verse.Start, tgtID.Verse.Start),
+					End:   max(srcID.Verse.End, tgtID.Verse.End),
+				},
+			}
+			if srcID.Sentence > 0 {
+				sentences[id]++
+				id.Sentence = sentences[id]
+			}
+
+			pair := types.NewTextPair(id, src.SourceText, tgt.TargetText)
+			pair.Merge = composeMerge(src.Merge, tgt.Merge)
+			if src.Score != nil && tgt.Score != nil {
+				pair.SetScore(*src.Score * *tgt.Score)
+			}
+			pair.LengthRatio = sentencealignment.LengthRatioSimilarity(pair.SourceText, pair.TargetText)
+			entry.Pairs = append(entry.Pairs, pair)
+		}
+	}
+
+	entry.Sort()
+	return entry
+}
+
+// buildPivotCorpus triangulates the corpus of src and tgt through the pivot and saves it to outdir.
+func buildPivotCorpus(src, tgt string, corpora map[string]string, outdir string, prg workerprogress.WorkerProgressContext) error {
+	srcLeg, err := readPivotCorpus(corpora[src], src)
+	if err != nil {
+		return err
+	}
+	tgtLeg, err := readPivotCorpus(corpora[tgt], tgt)
+	if err != nil {
+		return err
+	}
+
+	entry := triangulate(srcLeg, tgtLeg.Reversed())
+
+	sentences := len(entry.Pairs) > 0 && entry.Pairs[0].Sentence != ""
+	if err := saveParallelTSV(entry, sentences, fmt.Sprintf("%s_%s.tsv", src, tgt), outdir); err != nil {
+		return err
+	}
+
+	prg.Progress <- workerprogress.WorkerProgressMsg{
+		WorkerID: prg.WorkerID,
+		Percent:  1.0,
+		Status:   fmt.Sprintf("Triangulated %s <--> %s (%d pairs)", src, tgt, len(entry.Pairs)),
+	}
+	return nil
+}
+
+/*
+Triangulates a parallel corpus for every pair of languages that have a corpus
+with the pivot in opts.From, joining their pairs on the pivot side, and writes
+them to the pivot folder under the pivot's name. This only recovers pairs both
+languages share with the pivot: a verse one of them lacks stays missing.
+*/
+func GeneratePivotCorpora(opts PivotOptions) error {
+	corpora, err := pivotCorpora(opts.From, opts.Pivot)
+	if err != nil {
+		return err
+	}
+	langs := make([]string, 0, len(corpora))
+	for lang := range corpora {
+		langs = append(langs, lang)
+	}
+	if len(langs) < 2 {
+		return fmt.Errorf("fewer than two languages have a corpus with %s in %s", opts.Pivot, opts.From)
+	}
+	sort.Strings(langs)
+
+	outdir := filepath.Join(config.PARALLEL_PIVOT_FOLDER, opts.Pivot)
+	m, err := manifest.Load(config.MANIFEST_FILE)
+	if err != nil {
+		return err
+	}
+	pairs, builds, err := opts.Pairs.jobs(m, "parallel pivot", langs, outdir, nil, func(src, tgt string) []string {
+		return []string{corpora[src], corpora[tgt]}
+	})
+	if err != nil {
+		return err
+	}
+	if len(pairs) == 0 {
+		fmt.Println("Every selected language pair is up to date.")
+		return nil
+	}
+	fmt.Printf("Found %d languages with a corpus with %s, triangulating %d pairs...\n", len(langs), opts.Pivot, len(pairs))
+
+	queenCtx := workerprogress.NewQueenContext(len(pairs), getParallelQueenConfig())
+	jobCh := buildLanguagePairJobs(pairs)
+
+	go queenCtx.RunReporter()
+	createLanguagePairThreadPool(opts.Workers, jobCh, *queenCtx, func(src, tgt string, prg workerprogress.WorkerProgressContext) {
+		err := buildPivotCorpus(src, tgt, corpora, outdir, prg)
+		recordBuild(m, builds[[2]string{src, tgt}], err)
+	})
+	closeoutThreadPool(queenCtx)
+	return m.Save()
+}
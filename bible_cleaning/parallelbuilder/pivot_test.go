@@ -0,0 +1,126 @@
+package parallelcorpus
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+func TestPivotCorpora(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"ceb_tgl.tsv", "tgl_ceb.tsv", "tgl_ilo.tsv", "ilo_war.tsv", "tgl.tsv", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	corpora, err := pivotCorpora(dir, "tgl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"ceb": filepath.Join(dir, "ceb_tgl.tsv"), // both directions: the one into the pivot
+		"ilo": filepath.Join(dir, "tgl_ilo.tsv"),
+	}
+	if len(corpora) != len(want) || corpora["ceb"] != want["ceb"] || corpora["ilo"] != want["ilo"] {
+		t.Errorf("corpora %v, want %v", corpora, want)
+	}
+}
+
+func TestComposeMerge(t *testing.T) {
+	tests := []struct {
+		srcLeg, tgtLeg, want string
+	}{
+		{"1-1", "1-1", "1-1"},
+		{"2-1", "1-3", "2-3"},
+		{"1-2", "2-1", "1-1"},
+		{"", "1-1", ""}, // verse pairs have no merge
+		{"1-1", "1", ""},
+	}
+	for _, tt := range tests {
+		if got := composeMerge(tt.srcLeg, tt.tgtLeg); got != tt.want {
+			t.Errorf("composeMerge(%q, %q) = %q, want %q", tt.srcLeg, tt.tgtLeg, got, tt.want)
+		}
+	}
+}
+
+// legPair is a sentence pair of a leg of the triangulation.
+func legPair(id, source, target, merge string, score float64) types.TextPair {
+	pairID, err := types.ParsePairID(id)
+	if err != nil {
+		panic(err)
+	}
+	pair := types.NewTextPair(pairID, source, target)
+	pair.Merge = merge
+	pair.SetScore(score)
+	return pair
+}
+
+func describePairs(entry *types.ParallelCorpusEntry) []string {
+	var out []string
+	for _, pair := range entry.Pairs {
+		out = append(out, strings.Join([]string{pair.ID, pair.SourceText, pair.TargetText, pair.Merge, pair.ScoreLabel()}, " | "))
+	}
+	return out
+}
+
+func TestTriangulateSentences(t *testing.T) {
+	cebTgl := &types.ParallelCorpusEntry{SourceLang: "ceb", TargetLang: "tgl", Pairs: types.TextPairArray{
+		legPair("GEN_001_001_001", "Sa sinugdan.", "Nang  pasimula.", "1-1", 0.8),
+		legPair("GEN_001_001_002", "Gibuhat sa Dios. Ang langit.", "Nilikha ng Dios.", "2-1", 0.5),
+		legPair("GEN_001_002_001", "Ug ang yuta.", config.TOKEN_MISSING_TRANSLATION, "1-0", 0),
+		legPair("GEN_001_003-004_001", "Ug miingon ang Dios.", "At sinabi ng Dios.", "1-1", 0.9),
+	}}
+	tglIlo := &types.ParallelCorpusEntry{SourceLang: "tgl", TargetLang: "ilo", Pairs: types.TextPairArray{
+		legPair("GEN_001_001_001", "Nang pasimula.", "Idi punganay. Idi un-unana.", "1-2", 0.5),
+		legPair("GEN_001_001_002", "Nilikha ng Dios.", "Pinarsua ti Dios.", "1-1", 0.4),
+		legPair("GEN_001_004_001", "At sinabi ng Dios.", "Ket kinuna ti Dios.", "1-1", 0.5),
+		legPair("GEN_001_009_001", "Nilikha ng Dios.", "Pinarsua ti Dios.", "1-1", 0.5),        // verses do not overlap
+		legPair("GEN_002_001_001", "Nilikha ng Dios.", "Pinarsua ti Dios.", "1-1", 0.5),        // another chapter
+		legPair("GEN_001_002_001", config.TOKEN_MISSING_TRANSLATION, "Ket ti daga.", "0-1", 0), // nothing to join on
+	}}
+
+	entry := triangulate(cebTgl, tglIlo)
+	if entry.SourceLang != "ceb" || entry.TargetLang != "ilo" {
+		t.Errorf("languages %s and %s, want ceb and ilo", entry.SourceLang, entry.TargetLang)
+	}
+	want := []string{
+		"GEN_001_001_001 | Sa sinugdan. | Idi punganay. Idi un-unana. | 1-2 | 0.4000",
+		"GEN_001_001_002 | Gibuhat sa Dios. Ang langit. | Pinarsua ti Dios. | 2-1 | 0.2000",
+		"GEN_001_003-004_001 | Ug miingon ang Dios. | Ket kinuna ti Dios. | 1-1 | 0.4500",
+	}
+	if got := describePairs(entry); !slices.Equal(got, want) {
+		t.Errorf("pairs\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTriangulateVerses(t *testing.T) {
+	verse := func(id, source, target string) types.TextPair {
+		pairID, err := types.ParsePairID(id)
+		if err != nil {
+			panic(err)
+		}
+		return types.NewTextPair(pairID, source, target)
+	}
+	cebTgl := &types.ParallelCorpusEntry{SourceLang: "ceb", TargetLang: "tgl", Pairs: types.TextPairArray{
+		verse("JHN_011_035", "Mihilak si Jesus.", "Tumangis si Jesus."),
+		verse("JHN_011_036", "Busa ang mga Judio miingon.", "Sinabi nga ng mga Judio."),
+	}}
+	tglIlo := &types.ParallelCorpusEntry{SourceLang: "tgl", TargetLang: "ilo", Pairs: types.TextPairArray{
+		verse("JHN_011_035", "Tumangis si Jesus.", "Nagsangit ni Jesus."),
+		verse("JHN_011_036", "Sinabi nga ng mga Judio.", "Kinuna ngarud dagiti Judio."),
+	}}
+
+	want := []string{
+		"JHN_011_035 | Mihilak si Jesus. | Nagsangit ni Jesus. |  | ",
+		"JHN_011_036 | Busa ang mga Judio miingon. | Kinuna ngarud dagiti Judio. |  | ",
+	}
+	if got := describePairs(triangulate(cebTgl, tglIlo)); !slices.Equal(got, want) {
+		t.Errorf("pairs\n%s\nwant\n%s, unscored", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,165 @@
+package parallelcorpus
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/sentencealignment"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// TuningResult is how well one aligner configuration reproduces the gold alignments.
+type TuningResult struct {
+	Config sentencealignment.AlignerConfig
+	Scores sentencealignment.AlignmentScores
+}
+
+/*
+WeightGrid varies the similarity weights of base in steps of step, each from 0 to 1
+and summing to 1, for every n-gram order in ngrams. The rest of base is kept.
+*/
+func WeightGrid(base sentencealignment.AlignerConfig, ngrams []int, step float64) []sentencealignment.AlignerConfig {
+	steps := max(int(math.Round(1/step)), 1)
+
+	var grid []sentencealignment.AlignerConfig
+	for _, n := range ngrams {
+		for dice := 0; dice <= steps; dice++ {
+			for length := 0; dice+length <= steps; length++ {
+				cfg := base
+				cfg.Scoring = sentencealignment.ScoringSimilarity
+				cfg.NGram = n
+				cfg.DiceWeight = float64(dice) / float64(steps)
+				cfg.LengthWeight = float64(length) / float64(steps)
+				cfg.ProperNounWeight = float64(steps-dice-length) / float64(steps)
+				grid = append(grid, cfg)
+			}
+		}
+	}
+	return grid
+}
+
+// goldVerse is a verse of a gold alignment with the aligner of its corpus sentences.
+type goldVerse struct {
+	id      types.PairID
+	aligner *sentencealignment.Aligner
+	pairs   []types.TextPair
+}
+
+// verseKey is the ID of the verse a pair was aligned within.
+func verseKey(pair types.TextPair) (types.PairID, error) {
+	id, err := types.ParsePairID(pair.ID)
+	if err != nil {
+		return id, err
+	}
+	id.Sentence = 0
+	return id, nil
+}
+
+/*
+Reads a gold alignment and matches its verses to the sentences of the corpus.
+Only the chapters the gold alignment covers are read.
+*/
+func loadGoldVerses(gold *types.ParallelCorpusEntry, index map[string]map[string]string, cache *types.ProperNounCache) ([]goldVerse, error) {
+	byVerse := make(map[string][]types.TextPair)
+	chapters := make(map[string]map[string]string)
+	for _, lang := range []string{gold.SourceLang, gold.TargetLang} {
+		chapters[lang] = make(map[string]string)
+	}
+
+	for _, pair := range gold.Pairs {
+		id, err := verseKey(pair)
+		if err != nil {
+			return nil, err
+		}
+		byVerse[id.String()] = append(byVerse[id.String()], pair)
+
+		chapter := fmt.Sprintf("%s_%s", id.Book, id.ChapterLabel())
+		for _, lang := range []string{gold.SourceLang, gold.TargetLang} {
+			if path, ok := index[lang][chapter]; ok {
+				chapters[lang][chapter] = path
+			}
+		}
+	}
+
+	var verses []goldVerse
+	for verse := range verseSentenceGroups(gold.SourceLang, gold.TargetLang, chapters) {
+		pairs, ok := byVerse[verse.ID.String()]
+		if !ok {
+			continue
+		}
+		verses = append(verses, goldVerse{
+			id:      verse.ID,
+			aligner: sentencealignment.NewAligner(verse.Src, verse.Tgt, cache),
+			pairs:   pairs,
+		})
+		delete(byVerse, verse.ID.String())
+	}
+
+	if len(byVerse) > 0 {
+		fmt.Printf("Skipping %d gold verses of %s <--> %s missing from the sentence corpus\n", len(byVerse), gold.SourceLang, gold.TargetLang)
+	}
+	return verses, nil
+}
+
+/*
+Aligns the verses of the gold alignments under every configuration of the grid
+and scores each against the gold, best F1 first. Gold files are sentence TSVs
+named "src_tgt.tsv", like those parallel sentences writes, corrected by hand;
+only their id, source_text and target_text columns are read. The verses are
+aligned from the sentence corpus, each profiled once for the whole grid.
+*/
+func TuneAligner(goldPaths []string, grid []sentencealignment.AlignerConfig) ([]TuningResult, error) {
+	for _, cfg := range grid {
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var golds []*types.ParallelCorpusEntry
+	var languages []string
+	for _, path := range goldPaths {
+		gold, err := types.ReadParallelTSV(path)
+		if err != nil {
+			return nil, err
+		}
+		golds = append(golds, gold)
+		languages = append(languages, gold.SourceLang, gold.TargetLang)
+	}
+
+	corpus, err := indexLanguages(config.CORPUS_SENTENCES_FOLDER, languages)
+	if err != nil {
+		return nil, err
+	}
+	index := corpus.FileMap()
+	caches := buildLanguageNounCaches(corpus)
+
+	var verses []goldVerse
+	for _, gold := range golds {
+		cache := types.JoinProperNouns(caches[gold.SourceLang], caches[gold.TargetLang])
+		goldVerses, err := loadGoldVerses(gold, index, cache)
+		if err != nil {
+			return nil, err
+		}
+		verses = append(verses, goldVerses...)
+	}
+	if len(verses) == 0 {
+		return nil, fmt.Errorf("no gold verses found in %s", config.CORPUS_SENTENCES_FOLDER)
+	}
+	fmt.Printf("Tuning %d configurations on %d gold verses\n", len(grid), len(verses))
+
+	results := make([]TuningResult, len(grid))
+	for i, cfg := range grid {
+		results[i].Config = cfg
+		for _, verse := range verses {
+			results[i].Scores.Add(sentencealignment.EvaluateAlignment(verse.pairs, verse.aligner.Align(verse.id, cfg)))
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b TuningResult) int {
+		return cmp.Compare(b.Scores.F1(), a.Scores.F1())
+	})
+	return results, nil
+}
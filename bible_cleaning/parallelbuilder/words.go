@@ -0,0 +1,42 @@
+package parallelcorpus
+
+import (
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+	"github.com/zrygan.nlp/bible_cleaning/wordalignment"
+)
+
+/*
+
+	# Word-level alignment of the parallel corpora
+
+*/
+
+// WordOptions configures the word alignment of the parallel corpora.
+type WordOptions struct {
+	From    string  // folder of the parallel corpora to align, by verses or by sentences
+	MinProb float64 // lexical table entries below this are left out
+	Workers int     // language pairs aligned at once
+	Aligner wordalignment.Config
+}
+
+// alignWords trains the word aligner on a parallel corpus and saves its alignments and lexical tables.
+func alignWords(entry *types.ParallelCorpusEntry, opts WordOptions) error {
+	aligned := wordalignment.AlignEntry(entry, opts.Aligner)
+	return aligned.Save(config.WORD_ALIGNMENTS_FOLDER, opts.MinProb)
+}
+
+/*
+Trains a word aligner on every parallel corpus in opts.From and writes the
+Pharaoh alignments and lexical translation tables of each language pair to
+the word alignments folder.
+*/
+func GenerateWordAlignments(opts WordOptions) error {
+	if err := opts.Aligner.Validate(); err != nil {
+		return err
+	}
+
+	return processParallelCorpora(opts.From, opts.Workers, "word alignment", func(entry *types.ParallelCorpusEntry) error {
+		return alignWords(entry, opts)
+	})
+}
@@ -0,0 +1,126 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zrygan.nlp/bible_cleaning/sentencecleaning"
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
+)
+
+// Translation is one Bible translation the corpus is built from.
+type Translation struct {
+	ISO           string                  `json:"iso"`                // ISO 639-3 code, also the corpus folder name
+	Language      string                  `json:"language"`           // human-readable language name
+	VersionID     string                  `json:"version_id"`         // bible.com version ID, e.g. "2195"
+	Abbreviation  string                  `json:"abbreviation"`       // bible.com version abbreviation, e.g. "ABTAG01"
+	StartURL      string                  `json:"start_url"`          // first chapter the "Next Chapter" crawl starts from
+	License       string                  `json:"license"`            // copyright or license note of the translation
+	Scripts       []string                `json:"scripts"`            // Unicode scripts whose letters are kept, Latin if empty
+	AllowedChars  string                  `json:"allowed_characters"` // punctuation kept on top of the default set
+	Cleaning      []textcleaning.RuleSpec `json:"cleaning"`           // rules for this translation only, run after the rule file's
+	Segmenter     string                  `json:"segmenter"`          // sentence segmenter, "rules" (default) or "punkt"
+	Abbreviations []string                `json:"abbreviations"`      // words ending in a period that do not end a sentence
+}
+
+// CharSet returns the characters the cleaning filter keeps for this translation.
+func (t Translation) CharSet() (*textcleaning.CharSet, error) {
+	return textcleaning.NewCharSet(t.Scripts, t.AllowedChars)
+}
+
+// Registry lists every translation, in the order of the registry file.
+type Registry struct {
+	Path         string        `json:"-"`
+	Translations []Translation `json:"translations"`
+}
+
+var isoPattern = regexp.MustCompile(`^[a-z]{3}$`)
+
+/*
+Load reads and validates the registry file at path. Every translation needs a
+unique ISO 639-3 code, a version ID, an abbreviation and a start URL on that
+version, and its own cleaning rules must compile.
+*/
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry: %w", err)
+	}
+
+	reg := &Registry{Path: path}
+	if err := json.Unmarshal(data, reg); err != nil {
+		return nil, fmt.Errorf("failed to parse registry %s: %w", path, err)
+	}
+
+	if err := reg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid registry %s: %w", path, err)
+	}
+	return reg, nil
+}
+
+func (reg *Registry) validate() error {
+	if len(reg.Translations) == 0 {
+		return fmt.Errorf("no translations")
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range reg.Translations {
+		if !isoPattern.MatchString(t.ISO) {
+			return fmt.Errorf("%q is not an ISO 639-3 code", t.ISO)
+		}
+		if seen[t.ISO] {
+			return fmt.Errorf("%s is listed twice", t.ISO)
+		}
+		seen[t.ISO] = true
+
+		if t.VersionID == "" || t.Abbreviation == "" || t.StartURL == "" {
+			return fmt.Errorf("%s needs a version_id, abbreviation and start_url", t.ISO)
+		}
+		if !strings.Contains(t.StartURL, "/bible/"+t.VersionID+"/") || !strings.HasSuffix(t.StartURL, "."+t.Abbreviation) {
+			return fmt.Errorf("%s: start_url %s is not on version %s.%s", t.ISO, t.StartURL, t.VersionID, t.Abbreviation)
+		}
+
+		if _, err := t.CharSet(); err != nil {
+			return fmt.Errorf("%s: %w", t.ISO, err)
+		}
+
+		if _, err := textcleaning.CompileRules(t.Cleaning); err != nil {
+			return fmt.Errorf("%s: %w", t.ISO, err)
+		}
+
+		if !sentencecleaning.IsSegmenter(t.Segmenter) {
+			return fmt.Errorf("%s: unknown segmenter %q", t.ISO, t.Segmenter)
+		}
+	}
+	return nil
+}
+
+// Lookup finds a translation by its ISO 639-3 code.
+func (reg *Registry) Lookup(iso string) (Translation, bool) {
+	for _, t := range reg.Translations {
+		if t.ISO == iso {
+			return t, true
+		}
+	}
+	return Translation{}, false
+}
+
+// Languages returns the ISO codes of every translation, sorted.
+func (reg *Registry) Languages() []string {
+	langs := make([]string, 0, len(reg.Translations))
+	for _, t := range reg.Translations {
+		langs = append(langs, t.ISO)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Has reports whether the registry lists a language.
+func (reg *Registry) Has(iso string) bool {
+	_, ok := reg.Lookup(iso)
+	return ok
+}
@@ -0,0 +1,113 @@
+package registry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
+)
+
+func tagalog() Translation {
+	return Translation{
+		ISO:          "tgl",
+		Language:     "Tagalog",
+		VersionID:    "2195",
+		Abbreviation: "ABTAG01",
+		StartURL:     "https://www.bible.com/bible/2195/GEN.1.ABTAG01",
+	}
+}
+
+func cebuano() Translation {
+	return Translation{
+		ISO:          "ceb",
+		Language:     "Cebuano",
+		VersionID:    "562",
+		Abbreviation: "RCPV",
+		StartURL:     "https://www.bible.com/bible/562/GEN.1.RCPV",
+	}
+}
+
+// writeRegistry saves the translations as a registry file and loads it back.
+func writeRegistry(t *testing.T, translations ...Translation) (*Registry, error) {
+	t.Helper()
+
+	data, err := json.Marshal(Registry{Translations: translations})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bibles.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoad(t *testing.T) {
+	punkt := cebuano()
+	punkt.Segmenter = "punkt"
+	punkt.Scripts = []string{"Latin", "Tagbanwa"}
+	punkt.Cleaning = []textcleaning.RuleSpec{{Name: "pilcrow", Find: "¶"}}
+
+	reg, err := writeRegistry(t, tagalog(), punkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if langs := reg.Languages(); strings.Join(langs, " ") != "ceb tgl" {
+		t.Errorf("languages %v, want [ceb tgl]", langs)
+	}
+	if ceb, ok := reg.Lookup("ceb"); !ok || ceb.Segmenter != "punkt" || len(ceb.Cleaning) != 1 {
+		t.Errorf("Lookup(ceb) = %+v, %v", ceb, ok)
+	}
+	if reg.Has("ilo") {
+		t.Error("Has(ilo), which is not listed")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Translation)
+		err    string
+	}{
+		{"duplicate ISO code", func(tr *Translation) { *tr = tagalog() }, "tgl is listed twice"},
+		{"not an ISO code", func(tr *Translation) { tr.ISO = "Cebuano" }, "not an ISO 639-3 code"},
+		{"no version", func(tr *Translation) { tr.VersionID = "" }, "needs a version_id"},
+		{"start URL of another version", func(tr *Translation) { tr.StartURL = "https://www.bible.com/bible/2195/GEN.1.ABTAG01" }, "is not on version 562.RCPV"},
+		{"start URL of another abbreviation", func(tr *Translation) { tr.StartURL = "https://www.bible.com/bible/562/GEN.1.CEBMBB" }, "is not on version 562.RCPV"},
+		{"unknown script", func(tr *Translation) { tr.Scripts = []string{"Baybayin"} }, "Baybayin"},
+		{"unknown segmenter", func(tr *Translation) { tr.Segmenter = "nltk" }, `unknown segmenter "nltk"`},
+		{"bad rule", func(tr *Translation) {
+			tr.Cleaning = []textcleaning.RuleSpec{{Name: "brackets", Find: "[("}}
+		}, "rule brackets"},
+		{"unnamed rule", func(tr *Translation) { tr.Cleaning = []textcleaning.RuleSpec{{Find: "¶"}} }, "has no name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			second := cebuano()
+			tt.modify(&second)
+
+			_, err := writeRegistry(t, tagalog(), second)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+
+	if _, err := writeRegistry(t); err == nil || !strings.Contains(err.Error(), "no translations") {
+		t.Errorf("empty registry: error %v, want no translations", err)
+	}
+}
+
+func TestLoadRepositoryRegistry(t *testing.T) {
+	reg, err := Load(filepath.Join("..", "bibles.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reg.Has("tgl") {
+		t.Errorf("bibles.json has no tgl among %v", reg.Languages())
+	}
+}
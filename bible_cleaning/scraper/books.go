@@ -0,0 +1,176 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/zrygan.nlp/bible_cleaning/canon"
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// BibleVersion identifies a translation on bible.com, e.g. /bible/2195/GEN.1.ABTAG01.
+type BibleVersion struct {
+	BaseURL      string // scheme and host, e.g. "https://www.bible.com"
+	ID           string // numeric version ID, e.g. "2195"
+	Abbreviation string // e.g. "ABTAG01"
+}
+
+var bibleURLPattern = regexp.MustCompile(`^/bible/([0-9]+)/[0-9A-Z]{3}\.[0-9]+\.(.+)$`)
+
+// ParseBibleURL reads the version of a translation from any of its chapter URLs.
+func ParseBibleURL(chapterURL string) (BibleVersion, error) {
+	u, err := url.Parse(chapterURL)
+	if err != nil {
+		return BibleVersion{}, fmt.Errorf("invalid bible URL %s: %w", chapterURL, err)
+	}
+
+	m := bibleURLPattern.FindStringSubmatch(u.Path)
+	if m == nil {
+		return BibleVersion{}, fmt.Errorf("not a bible.com chapter URL: %s", chapterURL)
+	}
+
+	return BibleVersion{
+		BaseURL:      u.Scheme + "://" + u.Host,
+		ID:           m[1],
+		Abbreviation: m[2],
+	}, nil
+}
+
+// ChapterURL returns the address of a chapter of this version.
+func (bv BibleVersion) ChapterURL(book string, chapter int) string {
+	return fmt.Sprintf("%s/bible/%s/%s.%d.%s", bv.BaseURL, bv.ID, book, chapter, bv.Abbreviation)
+}
+
+type chapterJob struct {
+	book    canon.Book
+	chapter int
+}
+
+/*
+ScrapeBooks scrapes the chapters of the given books directly from their
+generated URLs, numWorkers at a time, instead of following "Next Chapter"
+links. Chapters the translation does not have (404, or a redirect to another
+chapter) are reported in the returned coverage rather than as failures.
+Chapters past the canon's count (e.g. Joel 4 in Hebrew versification) are
+picked up by following the next link of the last canonical chapter.
+Chapters the checkpoint has as done are skipped, and the words and chapters
+they found are counted in.
+*/
+func ScrapeBooks(
+	version BibleVersion,
+	books []canon.Book,
+	langClass *types.LanguageClass,
+	cleaner *textcleaning.Cleaner,
+	numWorkers int,
+	crawl *CrawlConfig,
+	checkpoint *Checkpoint,
+) (int, canon.Coverage) {
+	progress := checkpoint.StartBooks(langClass.Language)
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		wordCount = progress.WordCount
+		found     = progress.Chapters
+	)
+
+	jobCh := make(chan chapterJob, 100)
+
+	for i := 0; i < max(numWorkers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				chapters, words, err := scrapeBookChapter(version, job, langClass, cleaner, crawl)
+
+				// a failed chapter is left out of the checkpoint to be tried again on resume
+				if err == nil {
+					if err := checkpoint.CompleteBookChapter(langClass.Language, version.ChapterURL(job.book.Code, job.chapter), job.book.Code, chapters, words); err != nil {
+						log.Println("Error saving checkpoint:", err)
+					}
+				}
+
+				mu.Lock()
+				found[job.book.Code] = append(found[job.book.Code], chapters...)
+				wordCount += words
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, b := range books {
+		for ch := 1; ch <= b.Chapters; ch++ {
+			if !progress.Visited[version.ChapterURL(b.Code, ch)] {
+				jobCh <- chapterJob{book: b, chapter: ch}
+			}
+		}
+	}
+	close(jobCh)
+	wg.Wait()
+
+	if err := checkpoint.Save(); err != nil {
+		log.Println("Error saving checkpoint:", err)
+	}
+
+	return wordCount, canon.CheckCoverage(books, found)
+}
+
+/*
+scrapeBookChapter scrapes one chapter and returns the chapter numbers it
+found: none when the translation lacks it, and more than one when the last
+canonical chapter of a book is followed by extra ones. The error is that of a
+chapter that failed, already recorded in the failure report.
+*/
+func scrapeBookChapter(
+	version BibleVersion,
+	job chapterJob,
+	langClass *types.LanguageClass,
+	cleaner *textcleaning.Cleaner,
+	crawl *CrawlConfig,
+) ([]int, int, error) {
+	var chapters []int
+	wordCount := 0
+
+	for chapter := job.chapter; ; chapter++ {
+		chapterURL := version.ChapterURL(job.book.Code, chapter)
+		want := fmt.Sprintf("%s.%d", job.book.Code, chapter)
+
+		page, attempts, err := fetchChapter(chapterURL, cleaner, crawl)
+		if err != nil {
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) || httpErr.Status != http.StatusNotFound {
+				crawl.recordFailure(langClass.Language, chapterURL, attempts, err)
+				log.Println("Error scraping chapter:", err)
+				return chapters, wordCount, err
+			}
+			return chapters, wordCount, nil
+		}
+
+		// bible.com redirects chapters a version lacks to one it has
+		if page.Code != want || len(page.Verses) == 0 {
+			return chapters, wordCount, nil
+		}
+
+		if err := saveChapter(*langClass, page); err != nil {
+			log.Println("Error saving chapter:", err)
+		}
+
+		chapters = append(chapters, chapter)
+		for _, v := range page.Verses {
+			wordCount += len(strings.Fields(v.Text))
+		}
+
+		// only the last canonical chapter looks ahead for extra ones
+		next := chapterCodeFromURL(page.NextURL)
+		if chapter < job.book.Chapters || next != fmt.Sprintf("%s.%d", job.book.Code, chapter+1) {
+			return chapters, wordCount, nil
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/canon"
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+func TestScrapeBooksResumes(t *testing.T) {
+	faults := map[string]*fault{
+		"GEN.2.ABTAG01": {statuses: []int{http.StatusInternalServerError}, always: true},
+	}
+	server, requests := faultyServer(t, faults, "")
+	version, err := ParseBibleURL(server.URL + "/bible/2195/GEN.1.ABTAG01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := []canon.Book{{Code: "GEN", Name: "Genesis", Chapters: 3, Testament: canon.OldTestament}}
+	scrape := func(checkpoint *Checkpoint) (int, canon.Coverage) {
+		lang := &types.LanguageClass{Language: "tgl", OutputDir: t.TempDir()}
+		crawl := &CrawlConfig{Policy: testPolicy(), Failures: &FailureReport{}}
+		return ScrapeBooks(version, genesis, lang, &textcleaning.Cleaner{}, 2, crawl, checkpoint)
+	}
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	_, coverage := scrape(NewCheckpoint(path, 1))
+	if !slices.Equal(coverage.MissingChapters["GEN"], []int{2}) {
+		t.Fatalf("first run missing chapters %v, want GEN.2 that failed", coverage.MissingChapters)
+	}
+
+	delete(faults, "GEN.2.ABTAG01")
+	requests.mu.Lock()
+	before := maps.Clone(requests.paths)
+	requests.mu.Unlock()
+
+	checkpoint, err := LoadCheckpoint(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words, coverage := scrape(checkpoint)
+
+	for _, chapter := range []string{"GEN.1", "GEN.3"} {
+		if p := "/bible/2195/" + chapter + ".ABTAG01"; requests.paths[p] != before[p] {
+			t.Errorf("resume fetched %s again", chapter)
+		}
+	}
+	if p := "/bible/2195/GEN.2.ABTAG01"; requests.paths[p] != before[p]+1 {
+		t.Errorf("resume fetched GEN.2 %d times, want once", requests.paths[p]-before[p])
+	}
+	if coverage.Chapters != 3 || len(coverage.MissingChapters) != 0 {
+		t.Errorf("resumed coverage %+v, want all 3 chapters", coverage)
+	}
+
+	if fresh, _ := scrape(nil); words != fresh {
+		t.Errorf("resume counted %d words, a fresh run %d", words, fresh)
+	}
+}
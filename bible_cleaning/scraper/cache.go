@@ -0,0 +1,210 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const cacheIndexFile = "index.tsv"
+
+// cacheEntry is one line of the cache index: the response a URL produced.
+type cacheEntry struct {
+	Status   int
+	Hash     string // sha256 of the body, names the blob under objects/
+	Location string // redirect target, empty for 200 responses
+}
+
+// HTMLCache is a content-addressed on-disk store of raw chapter HTML.
+// Bodies live under <dir>/objects/<hh>/<sha256>.html, and <dir>/index.tsv
+// maps every fetched URL to the blob it returned.
+type HTMLCache struct {
+	Dir     string
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewHTMLCache opens (or creates) the cache rooted at dir and loads its index.
+func NewHTMLCache(dir string) (*HTMLCache, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	cache := &HTMLCache{Dir: dir, entries: make(map[string]cacheEntry)}
+
+	file, err := os.Open(filepath.Join(dir, cacheIndexFile))
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cache index: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "\t")
+		if len(parts) != 4 {
+			continue
+		}
+		status, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		// later lines win, so a re-fetched URL points to its newest blob
+		cache.entries[parts[0]] = cacheEntry{Status: status, Hash: parts[2], Location: parts[3]}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading cache index: %w", err)
+	}
+	return cache, nil
+}
+
+func (hc *HTMLCache) objectPath(hash string) string {
+	return filepath.Join(hc.Dir, "objects", hash[:2], hash+".html")
+}
+
+// Get returns the cached body for url, if any.
+func (hc *HTMLCache) Get(url string) ([]byte, bool) {
+	_, body, ok := hc.lookup(url)
+	return body, ok
+}
+
+func (hc *HTMLCache) lookup(url string) (cacheEntry, []byte, bool) {
+	hc.mu.Lock()
+	entry, ok := hc.entries[url]
+	hc.mu.Unlock()
+
+	if !ok {
+		return cacheEntry{}, nil, false
+	}
+
+	body, err := os.ReadFile(hc.objectPath(entry.Hash))
+	if err != nil {
+		return cacheEntry{}, nil, false
+	}
+	return entry, body, true
+}
+
+// Put stores body under its content hash and records that url produced it.
+func (hc *HTMLCache) Put(url string, status int, location string, body []byte) error {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	objectPath := hc.objectPath(hash)
+
+	if _, err := os.Stat(objectPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(objectPath), os.ModePerm); err != nil {
+			return err
+		}
+		// write then rename so an interrupted run never leaves a torn blob
+		if err := os.WriteFile(objectPath+"~", body, 0644); err != nil {
+			return err
+		}
+		if err := os.Rename(objectPath+"~", objectPath); err != nil {
+			return err
+		}
+	}
+
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	index, err := os.OpenFile(filepath.Join(hc.Dir, cacheIndexFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	if _, err := fmt.Fprintf(index, "%s\t%d\t%s\t%s\n", url, status, hash, location); err != nil {
+		return err
+	}
+
+	hc.entries[url] = cacheEntry{Status: status, Hash: hash, Location: location}
+	return nil
+}
+
+// Size returns the number of URLs in the cache index.
+func (hc *HTMLCache) Size() int {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	return len(hc.entries)
+}
+
+// cacheTransport serves requests from an HTMLCache and fills it from next.
+// In replay mode a cache miss is an error and the network is never touched.
+type cacheTransport struct {
+	cache  *HTMLCache
+	next   http.RoundTripper
+	replay bool
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+
+	url := req.URL.String()
+	if entry, body, ok := t.cache.lookup(url); ok {
+		return cachedResponse(req, entry, body), nil
+	}
+
+	if t.replay {
+		return nil, fmt.Errorf("replay: %s is not in the HTML cache", url)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// only successful pages and redirects are worth replaying, and only
+	// once the transport has already decoded them
+	cacheable := resp.StatusCode == http.StatusOK || (resp.StatusCode >= 300 && resp.StatusCode < 400)
+	if !cacheable || resp.Header.Get("Content-Encoding") != "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.cache.Put(url, resp.StatusCode, resp.Header.Get("Location"), body); err != nil {
+		return nil, fmt.Errorf("failed to cache %s: %w", url, err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+func cachedResponse(req *http.Request, entry cacheEntry, body []byte) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	if entry.Location != "" {
+		header.Set("Location", entry.Location)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+		StatusCode:    entry.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+		Uncompressed:  true,
+	}
+}
@@ -0,0 +1,365 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// ChapterPage is everything the scraper needs from one YouVersion chapter page.
+type ChapterPage struct {
+	URL     string        // address the page was fetched from
+	Title   string        // text of the h1, e.g. "Genesis 1"
+	Code    string        // USFM book and chapter, e.g. "GEN.1"
+	Verses  []types.Verse // cleaned, numbered verses in page order
+	NextURL string        // absolute "Next Chapter" link, empty on the last chapter
+
+	FetchedAt time.Time // when the page was downloaded (or replayed from the cache)
+
+	// headings, notes and words of Jesus of the verses that have any, in page order
+	Annotations []types.VerseAnnotations
+}
+
+// Book returns the USFM book code of the chapter, e.g. "GEN".
+func (cp *ChapterPage) Book() string {
+	book, _, _ := strings.Cut(cp.Code, ".")
+	return book
+}
+
+// Chapter returns the chapter number of the page as written in its code.
+func (cp *ChapterPage) Chapter() string {
+	_, chapter, _ := strings.Cut(cp.Code, ".")
+	return chapter
+}
+
+var chapterCodePattern = regexp.MustCompile(`^([0-9A-Z]{3})\.([0-9]+)`)
+
+// chapterCodeFromURL extracts "GEN.1" from ".../bible/2195/GEN.1.ABTAG01".
+func chapterCodeFromURL(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+
+	m := chapterCodePattern.FindStringSubmatch(path.Base(u.Path))
+	if m == nil {
+		return ""
+	}
+	return m[1] + "." + m[2]
+}
+
+var verseLabelPattern = regexp.MustCompile(`[^0-9\-–—]+`)
+
+/*
+verseNumberOf reads the number of a verse span. YouVersion marks merged verses
+in data-usfm ("GEN.1.3+GEN.1.4") and prints them in the label ("3-4").
+ok is false for spans without either, which continue the previous verse.
+*/
+func verseNumberOf(verseSel *goquery.Selection) (types.VerseNumber, bool) {
+	if usfm, exists := verseSel.Attr("data-usfm"); exists && usfm != "" {
+		number := types.VerseNumber{}
+		for i, ref := range strings.Split(usfm, "+") {
+			parts := strings.Split(ref, ".")
+			n, err := strconv.Atoi(parts[len(parts)-1])
+			if err != nil {
+				break
+			}
+			if i == 0 {
+				number = types.VerseNumber{Start: n, End: n}
+			}
+			number.Start = min(number.Start, n)
+			number.End = max(number.End, n)
+		}
+		if number.Start > 0 {
+			return number, true
+		}
+	}
+
+	label := verseSel.Find("span[class^='ChapterContent_label__']").First().Text()
+	label = verseLabelPattern.ReplaceAllString(label, "")
+	number, err := types.ParseVerseNumber(label)
+	if err != nil {
+		return types.VerseNumber{}, false
+	}
+	return number, true
+}
+
+const (
+	verseSelector   = "span[class^='ChapterContent_verse__']"
+	headingSelector = "span[class^='ChapterContent_heading__']"
+	contentSelector = "span[class^='ChapterContent_content__']"
+	noteSelector    = "span[class*='ChapterContent_note__']"
+	wordsOfJesus    = "span[class*='ChapterContent_wj__']"
+)
+
+var referencePattern = regexp.MustCompile(`[0-9A-Z]{3}\.[0-9]+(?:\.[0-9]+(?:-[0-9]+)?)?`)
+
+// collapseSpace trims text and joins its words with single spaces.
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+/*
+parseNotes reads the footnotes and cross references of a verse span. The note
+label ("#") and the verse the note belongs to ("1:1") are left out of the text.
+*/
+func parseNotes(verseSel *goquery.Selection) ([]string, []types.CrossReference) {
+	var footnotes []string
+	var crossRefs []types.CrossReference
+
+	verseSel.Find(noteSelector).Each(func(_ int, note *goquery.Selection) {
+		body := note.Find("span[class^='ChapterContent_body__']").Clone()
+		body.Find("span[class*='ChapterContent_fr__'], span[class*='ChapterContent_xo__']").Remove()
+		text := collapseSpace(body.Text())
+		if text == "" {
+			return
+		}
+
+		class, _ := note.Attr("class")
+		if !strings.Contains(class, "ChapterContent_x__") {
+			footnotes = append(footnotes, text)
+			return
+		}
+
+		ref := types.CrossReference{Text: text}
+		note.Find("[data-usfm], a[href]").Each(func(_ int, s *goquery.Selection) {
+			target := s.AttrOr("data-usfm", "")
+			if target == "" {
+				target = path.Base(s.AttrOr("href", ""))
+			}
+			ref.Targets = append(ref.Targets, referencePattern.FindAllString(target, -1)...)
+		})
+		crossRefs = append(crossRefs, ref)
+	})
+
+	return footnotes, crossRefs
+}
+
+/*
+ParseChapter reads a YouVersion chapter page and extracts its verses, title,
+book/chapter code and "Next Chapter" link in a single pass.
+pageURL is the address the page came from; it resolves the relative next link
+and gives the chapter code.
+Section headings, footnotes, cross references and words of Jesus are kept out
+of the verse text and returned as annotations of the verse they belong to.
+*/
+func ParseChapter(r io.Reader, pageURL string, cleaner *textcleaning.Cleaner) (*ChapterPage, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse chapter page %s: %w", pageURL, err)
+	}
+
+	page := &ChapterPage{
+		URL:  pageURL,
+		Code: chapterCodeFromURL(pageURL),
+	}
+
+	annotations := make(map[int]*types.VerseAnnotations) // by index into page.Verses
+	var headings []string                                // waiting for the verse they precede
+
+	doc.Find(verseSelector + ", " + headingSelector).Each(func(_ int, sel *goquery.Selection) {
+		if sel.Is(headingSelector) {
+			if heading := collapseSpace(sel.Text()); heading != "" {
+				headings = append(headings, heading)
+			}
+			return
+		}
+
+		var verseTexts, redLetters []string
+		var lastWJ *goquery.Selection
+		sel.Find(contentSelector).Each(func(_ int, s *goquery.Selection) {
+			if s.Closest(noteSelector).Length() > 0 {
+				return
+			}
+
+			text := strings.TrimSpace(s.Text())
+			if text == "" {
+				return
+			}
+
+			clean := cleaner.CleanSpan(text)
+			if clean == "" {
+				return
+			}
+			verseTexts = append(verseTexts, clean)
+
+			// consecutive spans of one words-of-Jesus element form one quote
+			if wj := s.Closest(wordsOfJesus); wj.Length() > 0 {
+				if lastWJ != nil && lastWJ.IsSelection(wj) {
+					redLetters[len(redLetters)-1] += " " + clean
+				} else {
+					redLetters = append(redLetters, clean)
+				}
+				lastWJ = wj
+			}
+		})
+
+		text := ""
+		if len(verseTexts) > 0 {
+			text = cleaner.CleanVerse(strings.Join(verseTexts, " "))
+		}
+
+		number, ok := verseNumberOf(sel)
+		last := len(page.Verses) - 1
+
+		index := -1
+		switch {
+		// a verse broken across paragraphs repeats its number (or has none)
+		// on the later parts; fold them into the verse they continue
+		case last >= 0 && (!ok || number == page.Verses[last].Number):
+			index = last
+			if text != "" {
+				page.Verses[last].Text = strings.TrimSpace(page.Verses[last].Text + " " + text)
+			}
+		case ok && text != "":
+			page.Verses = append(page.Verses, types.Verse{Number: number, Text: text})
+			index = len(page.Verses) - 1
+		default:
+			return
+		}
+
+		footnotes, crossRefs := parseNotes(sel)
+
+		annotation, exists := annotations[index]
+		if !exists {
+			annotation = &types.VerseAnnotations{}
+			annotations[index] = annotation
+		}
+		annotation.Headings = append(annotation.Headings, headings...)
+		annotation.Footnotes = append(annotation.Footnotes, footnotes...)
+		annotation.CrossReferences = append(annotation.CrossReferences, crossRefs...)
+		annotation.WordsOfJesus = append(annotation.WordsOfJesus, redLetters...)
+		headings = nil
+	})
+
+	for i, verse := range page.Verses {
+		if annotation, ok := annotations[i]; ok && !annotation.Empty() {
+			annotation.Verse = verse.Number
+			page.Annotations = append(page.Annotations, *annotation)
+		}
+	}
+
+	// the last h1 wins, like colly's OnHTML callback did
+	doc.Find("h1").Each(func(_ int, s *goquery.Selection) {
+		page.Title = strings.TrimSpace(s.Text())
+	})
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chapter URL %s: %w", pageURL, err)
+	}
+
+	doc.Find("a[href^='/bible/']").Each(func(_ int, s *goquery.Selection) {
+		if s.Find("svg title").Text() != "Next Chapter" {
+			return
+		}
+
+		href, _ := s.Attr("href")
+		next, err := base.Parse(href)
+		if err == nil {
+			page.NextURL = next.String()
+		}
+	})
+
+	return page, nil
+}
+
+// HTTPError is a fetch that ended with an unsuccessful HTTP status.
+type HTTPError struct {
+	URL    string
+	Status int
+	Err    error
+}
+
+func (e *HTTPError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+/*
+fetchChapter downloads a chapter page once and parses it with ParseChapter.
+Rate limited and server errors are retried with backoff as the crawl policy
+allows; the number of attempts made is returned with the result.
+*/
+func fetchChapter(chapterURL string, cleaner *textcleaning.Cleaner, crawl *CrawlConfig) (*ChapterPage, int, error) {
+	ok, err := crawl.allowed(chapterURL)
+	if err != nil {
+		return nil, 1, err
+	}
+	if !ok {
+		return nil, 1, fmt.Errorf("%s: %w", chapterURL, ErrRobotsDisallowed)
+	}
+
+	for attempt := 1; ; attempt++ {
+		page, status, retryAfter, err := fetchChapterOnce(chapterURL, cleaner, crawl)
+		if err == nil {
+			return page, attempt, nil
+		}
+
+		if attempt > crawl.maxRetries() || !retryable(status, err) {
+			if status != 0 {
+				err = &HTTPError{URL: chapterURL, Status: status, Err: err}
+			}
+			return nil, attempt, err
+		}
+
+		wait := crawl.backoff(attempt, retryAfter)
+		log.Printf("Retrying %s in %s (attempt %d/%d): %v\n", chapterURL, wait, attempt+1, crawl.maxRetries()+1, err)
+		time.Sleep(wait)
+	}
+}
+
+// fetchChapterOnce makes a single request, returning the HTTP status and
+// Retry-After header of a failed response so the caller can decide to retry.
+func fetchChapterOnce(chapterURL string, cleaner *textcleaning.Cleaner, crawl *CrawlConfig) (*ChapterPage, int, string, error) {
+	var page *ChapterPage
+	var parseErr error
+	var status int
+	var retryAfter string
+
+	c := crawl.newCollector()
+
+	c.OnResponse(func(r *colly.Response) {
+		// r.Request.URL is the final address after any redirect
+		page, parseErr = ParseChapter(bytes.NewReader(r.Body), r.Request.URL.String(), cleaner)
+		if page != nil {
+			page.FetchedAt = time.Now().UTC()
+		}
+	})
+
+	c.OnError(func(r *colly.Response, _ error) {
+		status = r.StatusCode
+		if r.Headers != nil {
+			retryAfter = r.Headers.Get("Retry-After")
+		}
+	})
+
+	if err := c.Visit(chapterURL); err != nil {
+		return nil, status, retryAfter, err
+	}
+
+	if parseErr != nil {
+		return nil, 0, "", parseErr
+	}
+
+	if page == nil {
+		return nil, 0, "", fmt.Errorf("no response for %s", chapterURL)
+	}
+
+	return page, 0, "", nil
+}
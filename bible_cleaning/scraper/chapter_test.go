@@ -0,0 +1,150 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+const fixtureBase = "https://www.bible.com/bible/2195/"
+
+// parseFixture parses a saved page of testdata as if fetched from fixtureBase.
+func parseFixture(t *testing.T, name string) *ChapterPage {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("testdata", name+".html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	page, err := ParseChapter(file, fixtureBase+name, &textcleaning.Cleaner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestParseChapter(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		code    string
+		verses  []types.Verse
+		nextURL string
+	}{
+		{
+			name:  "GEN.1.ABTAG01",
+			title: "Genesis 1",
+			code:  "GEN.1",
+			verses: []types.Verse{
+				{Number: types.VerseNumber{Start: 1, End: 1}, Text: "Nang pasimula ay nilikha ng Dios ang langit at ang lupa."},
+				{Number: types.VerseNumber{Start: 2, End: 2}, Text: "At ang lupa ay walang anyo at walang laman; at ang kadiliman ay sumasa ibabaw ng kalaliman."},
+				{Number: types.VerseNumber{Start: 3, End: 3}, Text: "At sinabi ng Dios, Magkaroon ng liwanag: at nagkaroon ng liwanag."},
+			},
+			nextURL: fixtureBase + "GEN.2.ABTAG01",
+		},
+		{
+			name:  "GEN.2.ABTAG01",
+			title: "Genesis 2",
+			code:  "GEN.2",
+			verses: []types.Verse{
+				{Number: types.VerseNumber{Start: 1, End: 1}, Text: "At nayari ang langit at ang lupa, at ang buong natatanaw sa mga yaon."},
+				{Number: types.VerseNumber{Start: 2, End: 2}, Text: "At nang ikapitong araw ay nayari ng Dios ang gawang kaniyang ginawa."},
+				{Number: types.VerseNumber{Start: 3, End: 4}, Text: "At binasbasan ng Dios ang ikapitong araw. Ito ang pinagmulan ng langit at ng lupa."},
+			},
+			nextURL: fixtureBase + "GEN.3.ABTAG01",
+		},
+		{
+			name:  "GEN.3.ABTAG01",
+			title: "Genesis 3",
+			code:  "GEN.3",
+			verses: []types.Verse{
+				{Number: types.VerseNumber{Start: 1, End: 1}, Text: "Ang ahas nga ay tuso kay sa alin mang hayop sa parang. At sinabi niya sa babae, Tunay bang sinabi ng Dios?"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := parseFixture(t, tt.name)
+
+			if page.Title != tt.title {
+				t.Errorf("title %q, want %q", page.Title, tt.title)
+			}
+			if page.Code != tt.code {
+				t.Errorf("code %q, want %q", page.Code, tt.code)
+			}
+			if page.NextURL != tt.nextURL {
+				t.Errorf("next URL %q, want %q", page.NextURL, tt.nextURL)
+			}
+			if !slices.Equal(page.Verses, tt.verses) {
+				t.Errorf("verses\n%v\nwant\n%v", page.Verses, tt.verses)
+			}
+		})
+	}
+}
+
+func TestParseChapterAnnotations(t *testing.T) {
+	genesis1 := parseFixture(t, "GEN.1.ABTAG01")
+	if len(genesis1.Annotations) != 2 {
+		t.Fatalf("%d annotated verses in GEN.1, want 2", len(genesis1.Annotations))
+	}
+	if got := genesis1.Annotations[0]; got.Verse.Start != 1 || !slices.Equal(got.Headings, []string{"Ang Paglalang"}) {
+		t.Errorf("GEN.1.1 annotations %+v, want the heading Ang Paglalang", got)
+	}
+	if got := genesis1.Annotations[1]; got.Verse.Start != 2 || !slices.Equal(got.Footnotes, []string{"O, hungkag."}) {
+		t.Errorf("GEN.1.2 annotations %+v, want the footnote without its label", got)
+	}
+
+	genesis2 := parseFixture(t, "GEN.2.ABTAG01")
+	if len(genesis2.Annotations) != 1 || len(genesis2.Annotations[0].CrossReferences) != 1 {
+		t.Fatalf("GEN.2 annotations %+v, want one cross reference", genesis2.Annotations)
+	}
+	if ref := genesis2.Annotations[0].CrossReferences[0]; ref.Text != "Exo. 20:11" || !slices.Equal(ref.Targets, []string{"EXO.20.11"}) {
+		t.Errorf("GEN.2.2 cross reference %+v, want Exo. 20:11 to EXO.20.11", ref)
+	}
+
+	if genesis3 := parseFixture(t, "GEN.3.ABTAG01"); len(genesis3.Annotations) != 0 {
+		t.Errorf("GEN.3 annotations %+v, want none", genesis3.Annotations)
+	}
+
+	// the spans of one words-of-Jesus element are one quote, and the verse keeps its whole text
+	john11 := parseFixture(t, "JHN.11.ABTAG01")
+	want := [][]string{{"Lazaro, lumabas ka."}, {"Kalagan ninyo siya, at bayaang yumaon."}}
+	if len(john11.Annotations) != len(want) {
+		t.Fatalf("JHN.11 annotations %+v, want the words of Jesus of verses 43 and 44", john11.Annotations)
+	}
+	for i, annotation := range john11.Annotations {
+		if annotation.Verse.Start != 43+i || !slices.Equal(annotation.WordsOfJesus, want[i]) {
+			t.Errorf("JHN.11.%d words of Jesus %q, want %q", 43+i, annotation.WordsOfJesus, want[i])
+		}
+	}
+	if text := john11.Verses[0].Text; !strings.HasSuffix(text, "tinig, Lazaro, lumabas ka.") {
+		t.Errorf("JHN.11.43 text %q, want the words of Jesus in it", text)
+	}
+}
+
+func TestConcurrentWebscrapeFetchesEachChapterOnce(t *testing.T) {
+	server, requests := fixtureServer(t)
+
+	lang := &types.LanguageClass{Language: "tgl", OutputDir: t.TempDir()}
+	words := ConcurrentWebscrapeAndParse(server.URL+"/bible/2195/GEN.1.ABTAG01", lang, &textcleaning.Cleaner{}, 10, 2, nil, nil)
+	if words == 0 {
+		t.Fatal("no words scraped")
+	}
+
+	if len(requests.paths) != 3 {
+		t.Errorf("fetched %d chapters, want 3", len(requests.paths))
+	}
+	for path, n := range requests.paths {
+		if n != 1 {
+			t.Errorf("%s fetched %d times, want once", strings.TrimPrefix(path, "/bible/2195/"), n)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// LanguageFrontier is the persisted crawl state of one translation.
+type LanguageFrontier struct {
+	Pending      []string        `json:"pending"` // chapter URLs queued but not yet scraped
+	Visited      map[string]bool `json:"visited"`
+	ChapterCount int             `json:"chapter_count"`
+	WordCount    int             `json:"word_count"`
+	Done         bool            `json:"done"`
+}
+
+// BookProgress is the persisted state of one translation scraped book by book.
+type BookProgress struct {
+	Visited   map[string]bool  `json:"visited"`  // chapter URLs done, whether or not the translation has them
+	Chapters  map[string][]int `json:"chapters"` // chapters found, by book
+	WordCount int              `json:"word_count"`
+}
+
+/*
+Checkpoint persists the crawl frontier of every language to a JSON file so an
+interrupted webscrape can continue where each language stopped. Languages
+scraped with --books keep their chapters done under Books instead.
+All methods are safe on a nil *Checkpoint, which disables checkpointing.
+*/
+type Checkpoint struct {
+	Path      string                       `json:"-"`
+	Languages map[string]*LanguageFrontier `json:"languages"`
+	Books     map[string]*BookProgress     `json:"books,omitempty"`
+
+	interval int // save after this many completed chapters
+	unsaved  int
+	mu       sync.Mutex
+}
+
+// NewCheckpoint starts an empty checkpoint that is saved to path every interval chapters.
+func NewCheckpoint(path string, interval int) *Checkpoint {
+	return &Checkpoint{
+		Path:      path,
+		Languages: make(map[string]*LanguageFrontier),
+		Books:     make(map[string]*BookProgress),
+		interval:  max(interval, 1),
+	}
+}
+
+// LoadCheckpoint reads the checkpoint at path, or starts an empty one if there is none.
+func LoadCheckpoint(path string, interval int) (*Checkpoint, error) {
+	cp := NewCheckpoint(path, interval)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+
+	for _, frontier := range cp.Languages {
+		if frontier.Visited == nil {
+			frontier.Visited = make(map[string]bool)
+		}
+	}
+	if cp.Books == nil {
+		cp.Books = make(map[string]*BookProgress)
+	}
+	for _, progress := range cp.Books {
+		if progress.Visited == nil {
+			progress.Visited = make(map[string]bool)
+		}
+		if progress.Chapters == nil {
+			progress.Chapters = make(map[string][]int)
+		}
+	}
+	return cp, nil
+}
+
+/*
+Start returns a copy of the frontier of a language, creating one that begins at
+startURL if the language has never been crawled. The copy is owned by the
+caller; progress is reported back through Complete and Finish.
+*/
+func (cp *Checkpoint) Start(language, startURL string) LanguageFrontier {
+	if cp == nil {
+		return LanguageFrontier{Pending: []string{startURL}, Visited: make(map[string]bool)}
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	frontier, ok := cp.Languages[language]
+	if !ok {
+		frontier = &LanguageFrontier{Pending: []string{startURL}, Visited: make(map[string]bool)}
+		cp.Languages[language] = frontier
+	}
+
+	return LanguageFrontier{
+		Pending:      slices.Clone(frontier.Pending),
+		Visited:      maps.Clone(frontier.Visited),
+		ChapterCount: frontier.ChapterCount,
+		WordCount:    frontier.WordCount,
+		Done:         frontier.Done,
+	}
+}
+
+// Complete records a scraped chapter and queues the chapter it links to.
+func (cp *Checkpoint) Complete(language, chapterURL string, wordCount int, nextURL string) error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	frontier, ok := cp.Languages[language]
+	if !ok {
+		frontier = &LanguageFrontier{Visited: make(map[string]bool)}
+		cp.Languages[language] = frontier
+	}
+
+	frontier.Visited[chapterURL] = true
+	frontier.ChapterCount++
+	frontier.WordCount += wordCount
+	frontier.Pending = slices.DeleteFunc(frontier.Pending, func(u string) bool { return u == chapterURL })
+
+	if nextURL != "" && !frontier.Visited[nextURL] && !slices.Contains(frontier.Pending, nextURL) {
+		frontier.Pending = append(frontier.Pending, nextURL)
+	}
+
+	cp.unsaved++
+	if cp.unsaved < cp.interval {
+		return nil
+	}
+	return cp.saveLocked()
+}
+
+/*
+StartBooks returns a copy of the book-by-book progress of a language, empty
+if it has never been scraped that way. Progress is reported back through
+CompleteBookChapter.
+*/
+func (cp *Checkpoint) StartBooks(language string) BookProgress {
+	if cp == nil {
+		return BookProgress{Visited: make(map[string]bool), Chapters: make(map[string][]int)}
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	progress, ok := cp.Books[language]
+	if !ok {
+		progress = &BookProgress{Visited: make(map[string]bool), Chapters: make(map[string][]int)}
+		cp.Books[language] = progress
+	}
+
+	chapters := make(map[string][]int, len(progress.Chapters))
+	for book, found := range progress.Chapters {
+		chapters[book] = slices.Clone(found)
+	}
+	return BookProgress{
+		Visited:   maps.Clone(progress.Visited),
+		Chapters:  chapters,
+		WordCount: progress.WordCount,
+	}
+}
+
+/*
+CompleteBookChapter records a chapter URL scraped book by book and the chapters
+of book found from it: none if the translation lacks it, more than one if
+extra chapters follow the last canonical one.
+*/
+func (cp *Checkpoint) CompleteBookChapter(language, chapterURL, book string, chapters []int, wordCount int) error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	progress, ok := cp.Books[language]
+	if !ok {
+		progress = &BookProgress{Visited: make(map[string]bool), Chapters: make(map[string][]int)}
+		cp.Books[language] = progress
+	}
+
+	progress.Visited[chapterURL] = true
+	progress.Chapters[book] = append(progress.Chapters[book], chapters...)
+	progress.WordCount += wordCount
+
+	cp.unsaved++
+	if cp.unsaved < cp.interval {
+		return nil
+	}
+	return cp.saveLocked()
+}
+
+// Finish marks a language as fully crawled and saves the checkpoint.
+func (cp *Checkpoint) Finish(language string) error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if frontier, ok := cp.Languages[language]; ok {
+		frontier.Done = len(frontier.Pending) == 0
+	}
+	return cp.saveLocked()
+}
+
+// Save writes the checkpoint to its path.
+func (cp *Checkpoint) Save() error {
+	if cp == nil {
+		return nil
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.saveLocked()
+}
+
+func (cp *Checkpoint) saveLocked() error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cp.Path), os.ModePerm); err != nil {
+		return err
+	}
+
+	// write then rename so a crash mid-save keeps the previous checkpoint
+	if err := os.WriteFile(cp.Path+"~", data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(cp.Path+"~", cp.Path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	cp.unsaved = 0
+	return nil
+}
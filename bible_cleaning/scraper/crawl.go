@@ -0,0 +1,315 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/temoto/robotstxt"
+)
+
+// CrawlPolicy describes how politely the scraper talks to the Bible host.
+type CrawlPolicy struct {
+	UserAgent     string
+	RequestDelay  time.Duration // minimum gap between two requests to the same host
+	Parallelism   int           // requests in flight at once, across every language
+	MaxRetries    int           // extra attempts after a 429, 5xx or network error
+	BackoffBase   time.Duration // first retry delay, doubled on every attempt
+	BackoffMax    time.Duration // upper bound of a single retry delay
+	Timeout       time.Duration // per request
+	RespectRobots bool          // skip pages the host's robots.txt disallows
+}
+
+// DefaultCrawlPolicy returns the policy used when no flags override it.
+func DefaultCrawlPolicy() CrawlPolicy {
+	return CrawlPolicy{
+		UserAgent:     "bible_cleaning/1.0 (+https://github.com/zrygan/nlp)",
+		RequestDelay:  250 * time.Millisecond,
+		Parallelism:   4,
+		MaxRetries:    5,
+		BackoffBase:   time.Second,
+		BackoffMax:    time.Minute,
+		Timeout:       30 * time.Second,
+		RespectRobots: true,
+	}
+}
+
+// ErrRobotsDisallowed is returned for pages the host's robots.txt forbids.
+var ErrRobotsDisallowed = errors.New("disallowed by robots.txt")
+
+// CrawlConfig holds the settings shared by every collector the scraper creates.
+// A nil *CrawlConfig crawls the network directly, as before.
+type CrawlConfig struct {
+	Cache    *HTMLCache     // raw chapter HTML cache, nil disables caching
+	Replay   bool           // serve every request from Cache and never touch the network
+	Policy   *CrawlPolicy   // rate limit, retries and robots.txt; nil fetches without limits
+	Failures *FailureReport // chapters that still failed after every retry
+
+	initOnce  sync.Once
+	transport http.RoundTripper
+	robotsMu  sync.Mutex
+	robots    map[string]*robotstxt.Group
+}
+
+// newCollector creates a collector wired to the crawl configuration.
+func (cc *CrawlConfig) newCollector() *colly.Collector {
+	c := colly.NewCollector()
+
+	if cc == nil {
+		return c
+	}
+
+	cc.initOnce.Do(cc.init)
+
+	if cc.Policy != nil {
+		c.UserAgent = cc.Policy.UserAgent
+		c.SetRequestTimeout(cc.Policy.Timeout)
+	}
+	c.WithTransport(cc.transport)
+	return c
+}
+
+/*
+Builds the transport chain shared by every collector:
+cache (hits never wait) -> polite throttle -> network.
+*/
+func (cc *CrawlConfig) init() {
+	var transport http.RoundTripper = http.DefaultTransport
+
+	if cc.Policy != nil {
+		transport = newPoliteTransport(transport, *cc.Policy)
+	}
+
+	if cc.Cache != nil {
+		transport = &cacheTransport{
+			cache:  cc.Cache,
+			next:   transport,
+			replay: cc.Replay,
+		}
+	}
+
+	cc.transport = transport
+	cc.robots = make(map[string]*robotstxt.Group)
+}
+
+// allowed checks a URL against the robots.txt of its host, fetching it once per host.
+func (cc *CrawlConfig) allowed(pageURL string) (bool, error) {
+	// a replayed crawl already passed the check when it was recorded
+	if cc == nil || cc.Policy == nil || !cc.Policy.RespectRobots || cc.Replay {
+		return true, nil
+	}
+
+	cc.initOnce.Do(cc.init)
+
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return false, err
+	}
+
+	cc.robotsMu.Lock()
+	defer cc.robotsMu.Unlock()
+
+	host := req.URL.Scheme + "://" + req.URL.Host
+	group, ok := cc.robots[host]
+	if !ok {
+		group, err = cc.fetchRobots(host)
+		if err != nil {
+			return false, err
+		}
+		cc.robots[host] = group
+	}
+
+	return group.Test(req.URL.Path), nil
+}
+
+func (cc *CrawlConfig) fetchRobots(host string) (*robotstxt.Group, error) {
+	req, err := http.NewRequest(http.MethodGet, host+"/robots.txt", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", cc.Policy.UserAgent)
+
+	client := &http.Client{Transport: cc.transport, Timeout: cc.Policy.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt of %s: %w", host, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read robots.txt of %s: %w", host, err)
+	}
+
+	robots, err := robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse robots.txt of %s: %w", host, err)
+	}
+	return robots.FindGroup(cc.Policy.UserAgent), nil
+}
+
+// maxRetries is the number of extra attempts a failed fetch gets.
+func (cc *CrawlConfig) maxRetries() int {
+	if cc == nil || cc.Policy == nil || cc.Replay {
+		return 0
+	}
+	return cc.Policy.MaxRetries
+}
+
+/*
+backoff returns how long to wait before retry number attempt (1-based).
+A Retry-After header from the server wins over the exponential schedule.
+*/
+func (cc *CrawlConfig) backoff(attempt int, retryAfter string) time.Duration {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+		return min(time.Duration(seconds)*time.Second, cc.Policy.BackoffMax)
+	}
+
+	delay := cc.Policy.BackoffBase << (attempt - 1)
+	if delay <= 0 || delay > cc.Policy.BackoffMax {
+		delay = cc.Policy.BackoffMax
+	}
+	return delay
+}
+
+// retryable reports whether a failed response is worth another attempt.
+func retryable(status int, err error) bool {
+	if errors.Is(err, ErrRobotsDisallowed) {
+		return false
+	}
+	if status == 0 {
+		return true // network error or timeout
+	}
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// recordFailure adds a chapter that could not be scraped to the report.
+func (cc *CrawlConfig) recordFailure(language, chapterURL string, attempts int, err error) {
+	if cc == nil || cc.Failures == nil {
+		return
+	}
+	cc.Failures.Add(ChapterFailure{
+		Language: language,
+		URL:      chapterURL,
+		Attempts: attempts,
+		Err:      err.Error(),
+	})
+}
+
+// politeTransport spaces out requests per host and caps how many run at once.
+type politeTransport struct {
+	next      http.RoundTripper
+	delay     time.Duration
+	slots     chan struct{}
+	mu        sync.Mutex
+	nextSlots map[string]time.Time // earliest start of the next request per host
+}
+
+func newPoliteTransport(next http.RoundTripper, policy CrawlPolicy) *politeTransport {
+	parallelism := max(policy.Parallelism, 1)
+	return &politeTransport{
+		next:      next,
+		delay:     policy.RequestDelay,
+		slots:     make(chan struct{}, parallelism),
+		nextSlots: make(map[string]time.Time),
+	}
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.slots <- struct{}{}
+	defer func() { <-t.slots }()
+
+	// reserve the next free start time for this host, then sleep until it
+	t.mu.Lock()
+	now := time.Now()
+	start := t.nextSlots[req.URL.Host]
+	if start.Before(now) {
+		start = now
+	}
+	t.nextSlots[req.URL.Host] = start.Add(t.delay)
+	t.mu.Unlock()
+
+	time.Sleep(time.Until(start))
+	return t.next.RoundTrip(req)
+}
+
+// ChapterFailure is a chapter that was still failing after every retry.
+type ChapterFailure struct {
+	Language string
+	URL      string
+	Attempts int
+	Err      string
+}
+
+// FailureReport collects failed chapters from every scraping goroutine.
+type FailureReport struct {
+	mu       sync.Mutex
+	failures []ChapterFailure
+}
+
+func (fr *FailureReport) Add(failure ChapterFailure) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.failures = append(fr.failures, failure)
+}
+
+// Failures returns the recorded failures ordered by language and URL.
+func (fr *FailureReport) Failures() []ChapterFailure {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	out := append([]ChapterFailure(nil), fr.failures...)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Language != out[j].Language {
+			return out[i].Language < out[j].Language
+		}
+		return out[i].URL < out[j].URL
+	})
+	return out
+}
+
+// Summarize prints the failed chapters and saves them as a TSV at path.
+func (fr *FailureReport) Summarize(path string) error {
+	failures := fr.Failures()
+
+	if len(failures) == 0 {
+		fmt.Println("All chapters scraped successfully.")
+		return nil
+	}
+
+	fmt.Printf("%d chapters failed after retries:\n", len(failures))
+	for _, f := range failures {
+		fmt.Printf("  %s  %s  (%d attempts) %s\n", f.Language, f.URL, f.Attempts, f.Err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create failure report: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, "language\turl\tattempts\terror"); err != nil {
+		return err
+	}
+	for _, f := range failures {
+		if _, err := fmt.Fprintf(file, "%s\t%s\t%d\t%s\n", f.Language, f.URL, f.Attempts, f.Err); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Saved failed chapters to %s\n", path)
+	return nil
+}
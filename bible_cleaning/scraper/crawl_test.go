@@ -0,0 +1,200 @@
+package scraper
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// fault is how the fake server fails a chapter: the status of each attempt in
+// turn, then the page, or the last status for good if always is set.
+type fault struct {
+	statuses   []int
+	retryAfter string
+	always     bool
+}
+
+/*
+faultyServer serves the pages of testdata as fixtureServer does, failing the
+chapters in faults by their file name and answering /robots.txt with robots.
+*/
+func faultyServer(t *testing.T, faults map[string]*fault, robots string) (*httptest.Server, *fixtureRequests) {
+	t.Helper()
+
+	requests := &fixtureRequests{paths: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte(robots))
+			return
+		}
+
+		name := path.Base(r.URL.Path)
+		requests.mu.Lock()
+		requests.paths[r.URL.Path]++
+		attempt := requests.paths[r.URL.Path]
+		requests.mu.Unlock()
+
+		if f, ok := faults[name]; ok && (f.always || attempt <= len(f.statuses)) {
+			if f.retryAfter != "" {
+				w.Header().Set("Retry-After", f.retryAfter)
+			}
+			w.WriteHeader(f.statuses[min(attempt, len(f.statuses))-1])
+			return
+		}
+
+		body, err := os.ReadFile(filepath.Join("testdata", name+".html"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// testPolicy retries twice with millisecond backoff, so failures are quick to reach.
+func testPolicy() *CrawlPolicy {
+	policy := DefaultCrawlPolicy()
+	policy.RequestDelay = 0
+	policy.MaxRetries = 2
+	policy.BackoffBase = time.Millisecond
+	policy.BackoffMax = 5 * time.Second
+	policy.Timeout = 5 * time.Second
+	return &policy
+}
+
+func TestBackoff(t *testing.T) {
+	crawl := &CrawlConfig{Policy: &CrawlPolicy{BackoffBase: time.Second, BackoffMax: 10 * time.Second}}
+
+	tests := []struct {
+		attempt    int
+		retryAfter string
+		want       time.Duration
+	}{
+		{1, "", time.Second},
+		{3, "", 4 * time.Second},
+		{5, "", 10 * time.Second}, // capped
+		{1, "7", 7 * time.Second}, // Retry-After wins
+		{1, "60", 10 * time.Second},
+		{2, "Wed, 21 Oct 2015 07:28:00 GMT", 2 * time.Second}, // dates fall back to the schedule
+	}
+	for _, tt := range tests {
+		if got := crawl.backoff(tt.attempt, tt.retryAfter); got != tt.want {
+			t.Errorf("backoff(%d, %q) = %s, want %s", tt.attempt, tt.retryAfter, got, tt.want)
+		}
+	}
+}
+
+func TestFetchChapterHonoursRetryAfter(t *testing.T) {
+	server, requests := faultyServer(t, map[string]*fault{
+		"GEN.1.ABTAG01": {statuses: []int{http.StatusTooManyRequests}, retryAfter: "1"},
+	}, "")
+	crawl := &CrawlConfig{Policy: testPolicy()}
+
+	start := time.Now()
+	page, attempts, err := fetchChapter(server.URL+"/bible/2195/GEN.1.ABTAG01", &textcleaning.Cleaner{}, crawl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want the 1s of Retry-After", elapsed)
+	}
+	if attempts != 2 || requests.total() != 2 {
+		t.Errorf("%d attempts and %d requests, want 2", attempts, requests.total())
+	}
+	if len(page.Verses) != 3 {
+		t.Errorf("%d verses after the retry, want 3", len(page.Verses))
+	}
+}
+
+func TestFetchChapterRetriesServerErrors(t *testing.T) {
+	server, requests := faultyServer(t, map[string]*fault{
+		"GEN.1.ABTAG01": {statuses: []int{http.StatusBadGateway}},
+		"GEN.2.ABTAG01": {statuses: []int{http.StatusServiceUnavailable}, always: true},
+		"GEN.3.ABTAG01": {statuses: []int{http.StatusNotFound}, always: true},
+	}, "")
+	crawl := &CrawlConfig{Policy: testPolicy()}
+
+	if _, attempts, err := fetchChapter(server.URL+"/bible/2195/GEN.1.ABTAG01", &textcleaning.Cleaner{}, crawl); err != nil || attempts != 2 {
+		t.Errorf("GEN.1: %d attempts, error %v; want success on the second", attempts, err)
+	}
+
+	_, attempts, err := fetchChapter(server.URL+"/bible/2195/GEN.2.ABTAG01", &textcleaning.Cleaner{}, crawl)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Status != http.StatusServiceUnavailable {
+		t.Errorf("GEN.2: error %v, want an HTTPError with status 503", err)
+	}
+	if want := crawl.Policy.MaxRetries + 1; attempts != want || requests.paths["/bible/2195/GEN.2.ABTAG01"] != want {
+		t.Errorf("GEN.2: %d attempts and %d requests, want %d", attempts, requests.paths["/bible/2195/GEN.2.ABTAG01"], want)
+	}
+
+	// a 404 will not go away by asking again
+	if _, attempts, err := fetchChapter(server.URL+"/bible/2195/GEN.3.ABTAG01", &textcleaning.Cleaner{}, crawl); err == nil || attempts != 1 {
+		t.Errorf("GEN.3: %d attempts, error %v; want a single failed attempt", attempts, err)
+	}
+}
+
+func TestFetchChapterRespectsRobots(t *testing.T) {
+	server, requests := faultyServer(t, nil, "User-agent: *\nDisallow: /bible/2195/GEN.2\n")
+	crawl := &CrawlConfig{Policy: testPolicy()}
+
+	if _, _, err := fetchChapter(server.URL+"/bible/2195/GEN.1.ABTAG01", &textcleaning.Cleaner{}, crawl); err != nil {
+		t.Errorf("GEN.1: %v, want it allowed", err)
+	}
+
+	_, attempts, err := fetchChapter(server.URL+"/bible/2195/GEN.2.ABTAG01", &textcleaning.Cleaner{}, crawl)
+	if !errors.Is(err, ErrRobotsDisallowed) {
+		t.Errorf("GEN.2: error %v, want ErrRobotsDisallowed", err)
+	}
+	if attempts != 1 || requests.paths["/bible/2195/GEN.2.ABTAG01"] != 0 {
+		t.Errorf("GEN.2: %d attempts and %d requests, want 1 attempt and no request", attempts, requests.paths["/bible/2195/GEN.2.ABTAG01"])
+	}
+
+	crawl.Policy.RespectRobots = false
+	if _, _, err := fetchChapter(server.URL+"/bible/2195/GEN.2.ABTAG01", &textcleaning.Cleaner{}, crawl); err != nil {
+		t.Errorf("GEN.2 without robots.txt: %v, want it fetched", err)
+	}
+}
+
+func TestFailedChapterIsReported(t *testing.T) {
+	server, _ := faultyServer(t, map[string]*fault{
+		"GEN.2.ABTAG01": {statuses: []int{http.StatusInternalServerError}, always: true},
+	}, "")
+	crawl := &CrawlConfig{Policy: testPolicy(), Failures: &FailureReport{}}
+
+	lang := &types.LanguageClass{Language: "tgl", OutputDir: t.TempDir()}
+	counter := 0
+	WebscrapeAndParse(server.URL+"/bible/2195/GEN.1.ABTAG01", lang, &textcleaning.Cleaner{}, make(map[string]bool), &counter, 10, crawl, nil)
+
+	failures := crawl.Failures.Failures()
+	if len(failures) != 1 {
+		t.Fatalf("%d failures reported, want 1: %+v", len(failures), failures)
+	}
+	failure := failures[0]
+	if failure.Language != "tgl" || !strings.HasSuffix(failure.URL, "/GEN.2.ABTAG01") || failure.Attempts != crawl.Policy.MaxRetries+1 {
+		t.Errorf("reported %+v, want GEN.2 of tgl after %d attempts", failure, crawl.Policy.MaxRetries+1)
+	}
+
+	reportPath := filepath.Join(t.TempDir(), "failed_chapters.tsv")
+	if err := crawl.Failures.Summarize(reportPath); err != nil {
+		t.Fatal(err)
+	}
+	report, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(report)), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "tgl\t"+failure.URL+"\t3\t") {
+		t.Errorf("failure report\n%s\nwant a header and the GEN.2 row", report)
+	}
+}
@@ -11,144 +11,86 @@ import (
 	"sync/atomic"
 	"time"
 	"strconv"
-	"github.com/PuerkitoBio/goquery"
-	"github.com/gocolly/colly"
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
 	"github.com/zrygan.nlp/bible_cleaning/types"
 )
 
-// ScrapeChapter scrapes a single chapter: returns verses and chapter name
-func scrapeChapter(chapterURL string, cleaningConfig *[]types.FindReplaceTuple[*regexp.Regexp]) ([]string, string, error) {
-	var verses []string
-	var chapterName string
-
-	c := colly.NewCollector()
-
-	c.OnHTML("span[class^='ChapterContent_verse__']", func(e *colly.HTMLElement) {
-		verseNumber := e.ChildText("span[class^='ChapterContent_label__']")
-		verseNumber = regexp.MustCompile(`\D`).ReplaceAllString(verseNumber, "")
-
-		var verseTexts []string
-		e.DOM.Find("span[class^='ChapterContent_content__']").Each(func(_ int, s *goquery.Selection) {
-			text := strings.TrimSpace(s.Text())
-			if text != "" {
-				clean := text
-
-				for _, tuple := range *cleaningConfig {
-					clean = tuple.Find.ReplaceAllString(clean, tuple.Replace.String())
-				}
-
-				if clean != "" {
-					verseTexts = append(verseTexts, clean)
-				}
-			}
-		})
-		var verse = strings.Join(verseTexts, " ");
-		for _, tuple := range *cleaningConfig {
-			verse = tuple.Find.ReplaceAllString(verse, tuple.Replace.String())
-		}
-		if len(verseTexts) > 0 {
-			verses = append(verses, verse)
-		}
-	})
-
-	c.OnHTML("h1", func(e *colly.HTMLElement) {
-		chapterName = strings.TrimSpace(e.Text)
-	})
-
-	err := c.Visit(chapterURL)
-	if err != nil {
-		return nil, "", err
-	}
-
-	return verses, chapterName, nil
-}
-
 // SaveChapter saves the verses to a file
-func saveChapter(lang types.LanguageClass, chapterName string, verses []string,  url string) error {
+func saveChapter(lang types.LanguageClass, page *ChapterPage) error {
+	chapterName := page.Title
 	if chapterName == "" {
 		chapterName = "chapter"
 	}
 
-	cleaningTuples := types.TurnToRegexpsTuple([]types.FindReplaceTuple[string]{
-		{
-			Find:    `(https:\/\/www\.bible\.com\/bible\/[0-9]+\/)`,
-			Replace: "",
-		},
-	})
-
-	for _, tuple := range cleaningTuples {
-		url = tuple.Find.ReplaceAllString(url, tuple.Replace.String())
+	if page.Code == "" {
+		return fmt.Errorf("cannot determine book and chapter of %s", page.URL)
 	}
 
-	// extract chapter number string
-	chapterStr := regexp.MustCompile(`([0-9]+)`).FindString(url)
-
 	// convert to int
-	chapterNum, _ := strconv.Atoi(chapterStr)
+	chapterNum, _ := strconv.Atoi(page.Chapter())
 
 	// format with leading zeroes, e.g. 3 digits
 	chapterNumberPadded := fmt.Sprintf("%03d", chapterNum)
 
-	CleanUniversalName := regexp.MustCompile(`(...)`).FindString(url)
-
 	bookClean := regexp.MustCompile(`[^a-zA-Z0-9_-]+`).ReplaceAllString(chapterName, "_")
 	bookClean = regexp.MustCompile(`[_0-9]+`).ReplaceAllString(bookClean, "")
 
 
-	filename := fmt.Sprintf("%s_%s_%s_%s.txt", lang.Language, CleanUniversalName, bookClean, chapterNumberPadded)
+	filename := fmt.Sprintf("%s_%s_%s_%s.txt", lang.Language, page.Book(), bookClean, chapterNumberPadded)
 	filePath := filepath.Join(lang.OutputDir, filename)
 
 	if err := os.MkdirAll(lang.OutputDir, os.ModePerm); err != nil {
 		return err
 	}
 
-	err := os.WriteFile(filePath, []byte(strings.Join(verses, "\n")), 0644)
+	err := types.WriteChapterFile(filePath, page.Verses)
 	if err != nil {
 		return err
 	}
 
+	if lang.AnnotationDir != "" {
+		annotationPath := filepath.Join(lang.AnnotationDir, strings.TrimSuffix(filename, ".txt")+".json")
+		err := types.WriteAnnotationFile(annotationPath, types.ChapterAnnotations{
+			Language:  lang.Language,
+			Code:      page.Code,
+			URL:       page.URL,
+			ScrapedAt: page.FetchedAt,
+			Verses:    page.Annotations,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// fmt.Println("Saved:", filePath)
 	return nil
 }
 
-// GetNextChapterURL finds the next chapter link
-func getNextChapterURL(chapterURL string) (string, error) {
-	var nextURL string
-	c := colly.NewCollector()
+// scrapeAndSaveChapter fetches a chapter once, saves its verses and returns
+// the parsed page together with its word count.
+func scrapeAndSaveChapter(url string, langClass types.LanguageClass, cleaner *textcleaning.Cleaner, crawl *CrawlConfig) (*ChapterPage, int, error) {
 
-	c.OnHTML("a[href^='/bible/']", func(e *colly.HTMLElement) {
-		title := e.DOM.Find("svg title").Text()
-		if title == "Next Chapter" {
-			nextURL = "https://www.bible.com" + e.Attr("href")
-		}
-	})
+	page, attempts, err := fetchChapter(url, cleaner, crawl)
 
-	err := c.Visit(chapterURL)
 	if err != nil {
-		return "", err
+		crawl.recordFailure(langClass.Language, url, attempts, err)
+		return nil, 0, err
 	}
 
-	return nextURL, nil
+	return page, saveChapterPage(langClass, page), nil
 }
 
-func countWordsFromURL(url string, langClass types.LanguageClass, cleaningConfig *[]types.FindReplaceTuple[*regexp.Regexp]) int {
-
-	verses, chapterName, err := scrapeChapter(url, cleaningConfig)
-
-	if err != nil {
-		log.Println("Error scraping chapter:", err)
-		return 0
-	}
-
-	if len(verses) > 0 {
-		if err := saveChapter(langClass, chapterName, verses, url); err != nil {
+// saveChapterPage saves the verses of a fetched chapter and returns its word count.
+func saveChapterPage(langClass types.LanguageClass, page *ChapterPage) int {
+	if len(page.Verses) > 0 {
+		if err := saveChapter(langClass, page); err != nil {
 			log.Println("Error saving chapter:", err)
 		}
 	}
 
 	wordCount := 0
-	for _, v := range verses {
-		words := strings.Fields(v)
+	for _, v := range page.Verses {
+		words := strings.Fields(v.Text)
 		wordCount += len(words)
 	}
 
@@ -159,10 +101,12 @@ func countWordsFromURL(url string, langClass types.LanguageClass, cleaningConfig
 func WebscrapeAndParse(
 	websiteURL string,
 	langClass *types.LanguageClass,
-	cleaningConfig *[]types.FindReplaceTuple[*regexp.Regexp],
+	cleaner *textcleaning.Cleaner,
 	visited map[string]bool,
 	chapterCounter *int,
 	maxCount int,
+	crawl *CrawlConfig,
+	checkpoint *Checkpoint,
 ) int {
 	// base/edge
 	if visited[websiteURL] || *chapterCounter > maxCount {
@@ -170,67 +114,87 @@ func WebscrapeAndParse(
 	}
 	visited[websiteURL] = true
 
-	wordCount := countWordsFromURL(websiteURL, *langClass, cleaningConfig)
+	page, wordCount, err := scrapeAndSaveChapter(websiteURL, *langClass, cleaner, crawl)
 	*chapterCounter++
 
-	// Get next chapter
-	nextURL, err := getNextChapterURL(websiteURL)
 	if err != nil {
-		log.Println("Error getting next chapter URL:", err)
+		log.Println("Error scraping chapter:", err)
 		return wordCount
 	}
 
+	// Get next chapter
+	nextURL := page.NextURL
+
+	if err := checkpoint.Complete(langClass.Language, websiteURL, wordCount, nextURL); err != nil {
+		log.Println("Error saving checkpoint:", err)
+	}
+
 	if nextURL != "" && !visited[nextURL] {
 		// maybe we can parallelize this to mkae it faster?
 		// recursive call
 		wordCount += WebscrapeAndParse(
 			nextURL,
 			langClass,
-			cleaningConfig,
+			cleaner,
 			visited,
 			chapterCounter,
 			maxCount,
+			crawl,
+			checkpoint,
 		)
 	}
 
 	return wordCount
 }
 
+// queuedChapter is a chapter waiting for a worker; page is set if it was already fetched.
+type queuedChapter struct {
+	url  string
+	page *ChapterPage
+}
+
 // Context for concurrent web scraping
 type WebscrapeContext struct {
 	visited        map[string]bool
 	visitedMu      *sync.Mutex
-	urlCh          chan string     // Channel for URLs to process
-	tasks          *sync.WaitGroup // Wait group for tracking tasks
+	urlCh          chan queuedChapter // Channel for chapters to process
+	tasks          *sync.WaitGroup    // Wait group for tracking tasks
 	langClass      *types.LanguageClass
-	cleaningConfig *[]types.FindReplaceTuple[*regexp.Regexp]
+	cleaner *textcleaning.Cleaner
 	chapterCounter *atomic.Int64
 	maxCount       int
 	totalWordCount *int64
+	crawl          *CrawlConfig
+	checkpoint     *Checkpoint
 }
 
+// prefetchStaringURLs follows the next links from url to queue the first depth
+// chapters, handing each worker the page already fetched so none is fetched twice.
 func prefetchStaringURLs(url string, depth int, ctx *WebscrapeContext) {
 	if depth <= 0 {
 		return
 	}
 
-	ctx.tasks.Add(1)
-	ctx.urlCh <- url
-
-	nextURL, err := getNextChapterURL(url)
+	page, attempts, err := fetchChapter(url, ctx.cleaner, ctx.crawl)
 	if err != nil {
+		ctx.crawl.recordFailure(ctx.langClass.Language, url, attempts, err)
 		log.Println("[Prefetch] Error fetching next URL:", err)
 		return
 	}
-	if nextURL != "" {
-		prefetchStaringURLs(nextURL, depth-1, ctx)
+
+	ctx.tasks.Add(1)
+	ctx.urlCh <- queuedChapter{url: url, page: page}
+
+	if page.NextURL != "" {
+		prefetchStaringURLs(page.NextURL, depth-1, ctx)
 	}
 }
 
 // BFSWebscrape is a worker function for concurrent web scraping
 // Check [here](../docs/devs.md)
 func BFSWebscrape(ctx *WebscrapeContext) {
-	for url := range ctx.urlCh {
+	for job := range ctx.urlCh {
+		url := job.url
 
 		// check visited
 		(*ctx.visitedMu).Lock()
@@ -249,19 +213,31 @@ func BFSWebscrape(ctx *WebscrapeContext) {
 			continue
 		}
 
-		// process URL
-		wordCount := countWordsFromURL(url, *ctx.langClass, ctx.cleaningConfig)
+		// process URL, unless it was prefetched
+		var wordCount int
+		var err error
+		page := job.page
+		if page != nil {
+			wordCount = saveChapterPage(*ctx.langClass, page)
+		} else {
+			page, wordCount, err = scrapeAndSaveChapter(url, *ctx.langClass, ctx.cleaner, ctx.crawl)
+		}
 		atomic.AddInt64(ctx.totalWordCount, int64(wordCount))
 
-		// fetch next
-		nextURL, err := getNextChapterURL(url)
+		if err == nil {
+			if err := ctx.checkpoint.Complete(ctx.langClass.Language, url, wordCount, page.NextURL); err != nil {
+				log.Println("Error saving checkpoint:", err)
+			}
+		}
+
+		// queue next from the same response
 		if err != nil {
-			log.Println("Error getting next chapter URL:", err)
-		} else if nextURL != "" {
+			log.Println("Error scraping chapter:", err)
+		} else if page.NextURL != "" {
 			(*ctx.visitedMu).Lock()
-			if !ctx.visited[nextURL] {
+			if !ctx.visited[page.NextURL] {
 				ctx.tasks.Add(1)
-				ctx.urlCh <- nextURL
+				ctx.urlCh <- queuedChapter{url: page.NextURL}
 			}
 			(*ctx.visitedMu).Unlock()
 		}
@@ -282,18 +258,24 @@ func Timer() {
 func ConcurrentWebscrapeAndParse(
 	startURL string,
 	langClass *types.LanguageClass,
-	cleaningConfig *[]types.FindReplaceTuple[*regexp.Regexp],
+	cleaner *textcleaning.Cleaner,
 	maxCount int,
 	numWorkers int,
+	crawl *CrawlConfig,
+	checkpoint *Checkpoint,
 ) int {
+	// resume from the checkpointed frontier, if the language has one
+	frontier := checkpoint.Start(langClass.Language, startURL)
+
 	var (
-		visited        = make(map[string]bool)
+		visited        = frontier.Visited
 		visitedMu      sync.Mutex
 		totalWordCount int64
 		chapterCounter atomic.Int64
 	)
+	chapterCounter.Store(int64(frontier.ChapterCount))
 
-	urlCh := make(chan string, 100) // 100 string buffer
+	urlCh := make(chan queuedChapter, 100) // 100 chapter buffer
 	var tasks sync.WaitGroup
 
 	ctx := &WebscrapeContext{
@@ -302,14 +284,26 @@ func ConcurrentWebscrapeAndParse(
 		urlCh:          urlCh,
 		tasks:          &tasks,
 		langClass:      langClass,
-		cleaningConfig: cleaningConfig,
+		cleaner: cleaner,
 		chapterCounter: &chapterCounter,
 		maxCount:       maxCount,
 		totalWordCount: &totalWordCount,
+		crawl:          crawl,
+		checkpoint:     checkpoint,
 	}
 
-	// enqueue initial URL
-	prefetchStaringURLs(startURL, numWorkers, ctx)
+	// enqueue initial URL, or every URL left pending by the last run
+	if frontier.ChapterCount == 0 {
+		prefetchStaringURLs(startURL, numWorkers, ctx)
+	} else {
+		// sent from a goroutine since the pending list may outgrow the buffer
+		tasks.Add(len(frontier.Pending))
+		go func() {
+			for _, url := range frontier.Pending {
+				urlCh <- queuedChapter{url: url}
+			}
+		}()
+	}
 
 	// workers
 	for i := 0; i < numWorkers; i++ {
@@ -320,5 +314,9 @@ func ConcurrentWebscrapeAndParse(
 	// wait until all workers finish
 	tasks.Wait()
 	close(urlCh)
+
+	if err := checkpoint.Finish(langClass.Language); err != nil {
+		log.Println("Error saving checkpoint:", err)
+	}
 	return int(totalWordCount)
 }
@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/textcleaning"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// fixtureRequests counts the requests a fixture server got, by path.
+type fixtureRequests struct {
+	mu    sync.Mutex
+	paths map[string]int
+}
+
+func (fr *fixtureRequests) total() int {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	total := 0
+	for _, n := range fr.paths {
+		total += n
+	}
+	return total
+}
+
+// fixtureServer serves the saved chapter pages of testdata under /bible/2195/.
+func fixtureServer(t *testing.T) (*httptest.Server, *fixtureRequests) {
+	t.Helper()
+
+	requests := &fixtureRequests{paths: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.mu.Lock()
+		requests.paths[r.URL.Path]++
+		requests.mu.Unlock()
+
+		body, err := os.ReadFile(filepath.Join("testdata", path.Base(r.URL.Path)+".html"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// scrapeFixtures crawls the chapters from startURL into a new folder and reads back what was saved, by file name.
+func scrapeFixtures(t *testing.T, startURL string, crawl *CrawlConfig) (int, map[string][]types.Verse) {
+	t.Helper()
+
+	lang := &types.LanguageClass{Language: "tgl", OutputDir: t.TempDir()}
+	counter := 0
+	words := WebscrapeAndParse(startURL, lang, &textcleaning.Cleaner{}, make(map[string]bool), &counter, 10, crawl, nil)
+
+	entries, err := os.ReadDir(lang.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chapters := make(map[string][]types.Verse)
+	for _, entry := range entries {
+		verses, err := types.ReadChapterFile(filepath.Join(lang.OutputDir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chapters[entry.Name()] = verses
+	}
+	return words, chapters
+}
+
+func TestWebscrapeReplaysFromCache(t *testing.T) {
+	server, requests := fixtureServer(t)
+	startURL := server.URL + "/bible/2195/GEN.1.ABTAG01"
+	cacheDir := t.TempDir()
+
+	cache, err := NewHTMLCache(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	liveWords, live := scrapeFixtures(t, startURL, &CrawlConfig{Cache: cache})
+	if len(live) != 3 {
+		t.Fatalf("live crawl saved %d chapters, want 3", len(live))
+	}
+	if cache.Size() != 3 {
+		t.Errorf("cache holds %d URLs, want 3", cache.Size())
+	}
+
+	server.Close()
+	fetched := requests.total()
+
+	cache, err = NewHTMLCache(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayWords, replayed := scrapeFixtures(t, startURL, &CrawlConfig{Cache: cache, Replay: true})
+
+	if requests.total() != fetched {
+		t.Errorf("replay made %d requests, want none", requests.total()-fetched)
+	}
+	if replayWords != liveWords {
+		t.Errorf("replay counted %d words, live crawl %d", replayWords, liveWords)
+	}
+	if len(replayed) != len(live) {
+		t.Fatalf("replay saved %d chapters, live crawl %d", len(replayed), len(live))
+	}
+	for name, verses := range live {
+		got := replayed[name]
+		if len(got) != len(verses) {
+			t.Errorf("%s: replay saved %d verses, live crawl %d", name, len(got), len(verses))
+			continue
+		}
+		for i := range verses {
+			if got[i] != verses[i] {
+				t.Errorf("%s: replayed verse %v, live crawl %v", name, got[i], verses[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package sentencealignment
+
+import (
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+/*
+Aligner aligns the sentences of one verse (or span of merged verses). It profiles
+every sentence once, builds the profile of a merged group from the group one
+sentence shorter, and keeps the similarity of every pair of groups it scores, so
+aligning the verse again under another configuration scores nothing twice.
+*/
+type Aligner struct {
+	src, tgt []string
+	cache    *types.ProperNounCache
+
+	srcGroups, tgtGroups [][]*groupProfile          // [end][count]: the group of count sentences ending before end
+	scores               map[[5]int]similarityParts // by source end and count, target end and count, n-gram order
+	nounMatches          map[[2]string]bool         // source and target proper nouns ProperNounSimilarity matches
+	wholeVerse           bool                       // the last Align found no alignment and kept the verse as one bead
+}
+
+func NewAligner(srcSents, tgtSents []string, cache *types.ProperNounCache) *Aligner {
+	return &Aligner{
+		src:         srcSents,
+		tgt:         tgtSents,
+		cache:       cache,
+		srcGroups:   make([][]*groupProfile, len(srcSents)+1),
+		tgtGroups:   make([][]*groupProfile, len(tgtSents)+1),
+		scores:      make(map[[5]int]similarityParts),
+		nounMatches: make(map[[2]string]bool),
+	}
+}
+
+// group returns the profile of the count sentences ending before end, extending the group one sentence shorter.
+func (a *Aligner) group(groups [][]*groupProfile, sentences []string, end, count int) *groupProfile {
+	for len(groups[end]) <= count {
+		groups[end] = append(groups[end], nil)
+	}
+	if p := groups[end][count]; p != nil {
+		return p
+	}
+
+	var p *groupProfile
+	if count == 1 {
+		p = newSentenceProfile(sentences[end-1], a.cache)
+	} else {
+		p = a.group(groups, sentences, end-1, count-1).extend(a.group(groups, sentences, end, 1))
+	}
+	groups[end][count] = p
+	return p
+}
+
+func (a *Aligner) nounsMatch(s, t string) bool {
+	key := [2]string{s, t}
+	match, ok := a.nounMatches[key]
+	if !ok {
+		match = ProperNounSimilarity(s, t) > 0.85
+		a.nounMatches[key] = match
+	}
+	return match
+}
+
+// properNounOverlap is ProperNounOverlapScore over the nouns of two profiles.
+func (a *Aligner) properNounOverlap(src, tgt *groupProfile) float64 {
+	if len(src.nouns) == 0 {
+		return 0.0
+	}
+
+	matches := 0
+	for _, s := range src.nouns {
+		for _, t := range tgt.nouns {
+			if a.nounsMatch(s, t) {
+				matches++
+				break
+			}
+		}
+	}
+	return float64(matches) / float64(len(src.nouns))
+}
+
+// similarity is SentenceSimilarity of the joined groups, split into its measures and cached.
+func (a *Aligner) similarity(i, srcCount, j, tgtCount, ngram int) similarityParts {
+	key := [5]int{i, srcCount, j, tgtCount, ngram}
+	if parts, ok := a.scores[key]; ok {
+		return parts
+	}
+
+	src := a.group(a.srcGroups, a.src, i, srcCount)
+	tgt := a.group(a.tgtGroups, a.tgt, j, tgtCount)
+
+	var parts similarityParts
+	if src.length > 0 && tgt.length > 0 {
+		n := min(ngram, src.length, tgt.length) // as SentenceSimilarity
+		parts = similarityParts{
+			dice:        diceOfSets(src.ngrams(n), tgt.ngrams(n)),
+			lengthRatio: float64(min(src.length, tgt.length)) / float64(max(src.length, tgt.length)),
+			properNouns: a.properNounOverlap(src, tgt),
+		}
+	}
+
+	a.scores[key] = parts
+	return parts
+}
+
+// groupLength is the rune length of the joined group, 0 for an empty one.
+func (a *Aligner) groupLength(groups [][]*groupProfile, sentences []string, end, count int) int {
+	if count == 0 {
+		return 0
+	}
+	return a.group(groups, sentences, end, count).length
+}
+
+// beadGain is beadGain over the profiles of the groups.
+func (a *Aligner) beadGain(i, srcCount, j, tgtCount int, cfg AlignerConfig) (gain, score float64, ok bool) {
+	null := srcCount == 0 || tgtCount == 0
+	if null && (srcCount+tgtCount != 1 || !cfg.allowsNull()) {
+		return 0, 0, false
+	}
+
+	if cfg.Scoring == ScoringGaleChurch {
+		srcLen := a.groupLength(a.srcGroups, a.src, i, srcCount)
+		tgtLen := a.groupLength(a.tgtGroups, a.tgt, j, tgtCount)
+		gain, ok = galeChurchGain(srcCount, tgtCount, srcLen, tgtLen, cfg)
+		if !ok {
+			return 0, 0, false
+		}
+		if !null {
+			score = galeChurchLengthProbability(srcLen, tgtLen, cfg)
+		}
+		return gain, score, true
+	}
+
+	if null {
+		return -cfg.NullPenalty, 0, true
+	}
+	similarity := a.similarity(i, srcCount, j, tgtCount, cfg.NGram).weighted(cfg)
+	return similarity, similarity, true
+}
+
+/*
+Align aligns the verse like AlignSentencesByGaleChurchDP. Only the cells within
+cfg.Band sentences of the diagonal are searched; if no alignment fits in the
+band, the whole table is. If none fits in the table either, the verse is kept
+as one bead, which WholeVerse reports.
+*/
+func (a *Aligner) Align(verse types.PairID, cfg AlignerConfig) []types.TextPair {
+	m, n := len(a.src), len(a.tgt)
+	if m == 0 || n == 0 {
+		return nil
+	}
+
+	gain := func(i, srcCount, j, tgtCount int) (float64, float64, bool) {
+		return a.beadGain(i, srcCount, j, tgtCount, cfg)
+	}
+
+	beads := alignBeads(m, n, cfg.maxMerge(), cfg.Band, gain)
+	if beads == nil && cfg.Band > 0 {
+		beads = alignBeads(m, n, cfg.maxMerge(), 0, gain)
+	}
+	a.wholeVerse = beads == nil
+	if a.wholeVerse {
+		beads = wholeVerse(m, n)
+	}
+	return pairsFromBeads(a.src, a.tgt, beads, verse)
+}
+
+// WholeVerse reports whether the last Align found no alignment and kept the verse as one m-n bead.
+func (a *Aligner) WholeVerse() bool {
+	return a.wholeVerse
+}
@@ -0,0 +1,86 @@
+package sentencealignment
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// A long verse, split into sentences in both translations, with a sentence more in Tagalog.
+var (
+	benchVerse = types.PairID{Book: "EXO", Chapter: 12, Verse: types.VerseNumber{Start: 1, End: 12}}
+
+	benchSource = []string{
+		"At nagsalita ang Panginoon kay Moises at kay Aaron sa lupain ng Egipto, na sinasabi,",
+		"Ang buwang ito ay magiging pasimula ng mga buwan sa inyo.",
+		"Salitain ninyo sa buong kapisanan ng Israel, na sabihin, Sa ikasangpung araw ng buwang ito ay kukuha ang bawa't lalake ng isang kordero.",
+		"At kung ang sangbahayan ay napakaliit sa isang kordero, ay makikisama siya at ang kaniyang kapuwa na malapit sa kaniyang bahay.",
+		"Ang inyong kordero ay walang kapintasan, lalake, na iisahing taon.",
+		"At inyong iingatan hanggang sa ikalabing apat na araw ng buwan ding ito.",
+		"At papatayin ng buong kapisanan ng Israel sa pagitan ng dalawang gabi.",
+		"At kukuha sila ng dugo, at ilalagay sa dalawang haligi ng pintuan at sa itaas ng pintuan.",
+		"At kanilang kakanin ang laman sa gabing yaon, inihaw sa apoy, at tinapay na walang lebadura.",
+		"Huwag ninyong kanin na hilaw, ni luto man sa tubig, kundi inihaw sa apoy.",
+		"At huwag kayong magtitira ng anoman niyaon hanggang sa kinaumagahan.",
+		"Ito ang Paskua ng Panginoon.",
+		"Sapagka't ako'y dadaan sa lupain ng Egipto sa gabing yaon, at aking papatayin ang lahat ng panganay sa lupain ng Egipto.",
+	}
+	benchTarget = []string{
+		"Ug si Jehova misulti kang Moises ug kang Aaron didto sa yuta sa Egipto, nga nagaingon:",
+		"Kining bulana alang kaninyo mao ang sinugdan sa mga bulan.",
+		"Sultihi ninyo ang tibook nga katilingban sa Israel, nga magaingon: Sa ikanapulo niining bulana magkuha ang tagsatagsa ka tawo ug usa ka nating carnero.",
+		"Apan kong ang panimalay diyutay ra alang sa usa ka nating carnero, nan magadapig siya ug ang iyang silingan nga haduol sa iyang balay.",
+		"Ang inyong nating carnero walay buling, laki, sa usa ka tuig.",
+		"Ug inyong bantayan kini hangtud sa ikanapulo ug upat ka adlaw niining bulana.",
+		"Ug ang tibook nga katilingban sa Israel magapatay niini sa pagkahapon.",
+		"Ug magakuha sila sa dugo, ug igabutang nila sa duruha ka haligi sa pultahan ug sa ibabaw sa pultahan.",
+		"Ug kanilang pagakan-on ang unod niini nianang gabhiona, sinugba sa kalayo, ug tinapay nga walay levadura.",
+		"Dili kamo magkaon niini nga hilaw, ni linung-ag sa tubig, kondili sinugba sa kalayo.",
+		"Ug dili kamo magbilin bisan unsa niini hangtud sa buntag.",
+		"Kay molatas ako sa yuta sa Egipto niadtong gabhiona, ug pagapatyon ko ang tanang panganay sa yuta sa Egipto.",
+	}
+)
+
+func benchCache() *types.ProperNounCache {
+	cache := types.NewProperNounCache()
+	for _, s := range append(slices.Clone(benchSource), benchTarget...) {
+		cache.Add(s)
+	}
+	return cache
+}
+
+func TestAlignerMatchesReference(t *testing.T) {
+	cfg := DefaultAlignerConfig()
+	cfg.Band = 0 // the reference searches the whole table
+	cache := benchCache()
+
+	reference := AlignSentencesReference(benchSource, benchTarget, benchVerse, cache, cfg)
+	aligned := NewAligner(benchSource, benchTarget, cache).Align(benchVerse, cfg)
+	if !slices.Equal(merges(aligned), merges(reference)) {
+		t.Errorf("aligner merges %q, reference %q", merges(aligned), merges(reference))
+	}
+}
+
+/*
+BenchmarkAlign compares the reference aligner, which joins and scores every bead
+from its text over the whole table, with the Aligner, which profiles every
+sentence once and searches the band around the diagonal:
+
+	go test -bench Align ./sentencealignment
+*/
+func BenchmarkAlign(b *testing.B) {
+	cfg := DefaultAlignerConfig()
+	cache := benchCache()
+
+	b.Run("reference", func(b *testing.B) {
+		for b.Loop() {
+			AlignSentencesReference(benchSource, benchTarget, benchVerse, cache, cfg)
+		}
+	})
+	b.Run("aligner", func(b *testing.B) {
+		for b.Loop() {
+			NewAligner(benchSource, benchTarget, cache).Align(benchVerse, cfg)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package sentencealignment
+
+import (
+	"strings"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// AlignmentScores counts the aligned pairs that match a gold alignment.
+type AlignmentScores struct {
+	Gold      int // pairs of the gold alignment
+	Predicted int // pairs of the aligner
+	Correct   int // pairs of the aligner found in the gold alignment
+}
+
+func (s AlignmentScores) Precision() float64 {
+	if s.Predicted == 0 {
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Predicted)
+}
+
+func (s AlignmentScores) Recall() float64 {
+	if s.Gold == 0 {
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Gold)
+}
+
+func (s AlignmentScores) F1() float64 {
+	p, r := s.Precision(), s.Recall()
+	if p+r == 0 {
+		return 0
+	}
+	return 2 * p * r / (p + r)
+}
+
+func (s *AlignmentScores) Add(other AlignmentScores) {
+	s.Gold += other.Gold
+	s.Predicted += other.Predicted
+	s.Correct += other.Correct
+}
+
+/*
+alignedPairKey identifies a pair by its verse and its two texts, whitespace
+collapsed, so that a hand-aligned pair matches however it was numbered.
+*/
+func alignedPairKey(pair types.TextPair) [3]string {
+	verse := pair.ID
+	if id, err := types.ParsePairID(pair.ID); err == nil {
+		id.Sentence = 0
+		verse = id.String()
+	}
+	return [3]string{
+		verse,
+		strings.Join(strings.Fields(pair.SourceText), " "),
+		strings.Join(strings.Fields(pair.TargetText), " "),
+	}
+}
+
+/*
+EvaluateAlignment scores predicted pairs against gold pairs of the same verses. A
+predicted pair is correct if the gold alignment has a pair of the same verse with
+the same source and target text: every sentence of the bead must be right.
+*/
+func EvaluateAlignment(gold, predicted []types.TextPair) AlignmentScores {
+	golden := make(map[[3]string]int, len(gold))
+	for _, pair := range gold {
+		golden[alignedPairKey(pair)]++
+	}
+
+	scores := AlignmentScores{Gold: len(gold), Predicted: len(predicted)}
+	for _, pair := range predicted {
+		key := alignedPairKey(pair)
+		if golden[key] > 0 {
+			golden[key]--
+			scores.Correct++
+		}
+	}
+	return scores
+}
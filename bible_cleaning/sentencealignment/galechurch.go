@@ -0,0 +1,165 @@
+package sentencealignment
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+)
+
+// Scoring is how the aligner rates a bead: a group of source sentences aligned to a group of target sentences.
+type Scoring string
+
+const (
+	ScoringSimilarity Scoring = "similarity"  // SentenceSimilarity: n-gram Dice, length ratio and proper nouns
+	ScoringGaleChurch Scoring = "gale-church" // Gale and Church's length-based probability
+)
+
+// AlignerConfig chooses the scoring of the sentence aligner, its weights and the beads it may use.
+type AlignerConfig struct {
+	Scoring          Scoring `json:"scoring"`
+	MaxMerge         int     `json:"max_merge"`          // most sentences merged per side by the similarity scoring
+	NullPenalty      float64 `json:"null_penalty"`       // similarity given up by a 1-0 or 0-1 bead; negative to forbid them
+	NGram            int     `json:"ngram"`              // order of the character n-grams the similarity compares
+	DiceWeight       float64 `json:"dice_weight"`        // weight of the character n-gram Dice coefficient in the similarity
+	LengthWeight     float64 `json:"length_weight"`      // weight of the length ratio in the similarity
+	ProperNounWeight float64 `json:"proper_noun_weight"` // weight of the proper noun overlap in the similarity
+	Mean             float64 `json:"gc_mean"`            // Gale-Church: expected target characters per source character
+	Variance         float64 `json:"gc_variance"`        // Gale-Church: variance of that ratio per character
+	Band             int     `json:"band"`               // sentences an alignment may stray from the diagonal of the verse; 0 for no limit
+}
+
+func DefaultAlignerConfig() AlignerConfig {
+	return AlignerConfig{
+		Scoring:          config.ALIGNER_SCORING,
+		MaxMerge:         config.ALIGNER_MAX_MERGE,
+		NullPenalty:      config.ALIGNER_NULL_PENALTY,
+		NGram:            config.SIMILARITY_NGRAM,
+		DiceWeight:       config.NGRAMS_DICE_SIMILARITY_BIAS,
+		LengthWeight:     config.LENGTH_RATIO_SIMILARITY_BIAS,
+		ProperNounWeight: config.PROPER_NOUNS_SIMILARITY_BIAS,
+		Mean:             config.GALE_CHURCH_MEAN,
+		Variance:         config.GALE_CHURCH_VARIANCE,
+		Band:             config.ALIGNER_BAND,
+	}
+}
+
+/*
+LoadAlignerConfig reads an aligner configuration from a JSON file, such as the
+one the tune command writes. Fields the file leaves out keep their defaults.
+*/
+func LoadAlignerConfig(path string) (AlignerConfig, error) {
+	cfg := DefaultAlignerConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, cfg.Validate()
+}
+
+// Save writes the configuration as JSON for LoadAlignerConfig.
+func (cfg AlignerConfig) Save(path string) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func (cfg AlignerConfig) Validate() error {
+	switch cfg.Scoring {
+	case ScoringSimilarity, ScoringGaleChurch:
+	default:
+		return fmt.Errorf("unknown aligner scoring %q", cfg.Scoring)
+	}
+	if cfg.MaxMerge < 1 {
+		return fmt.Errorf("max merge must be at least 1, got %d", cfg.MaxMerge)
+	}
+	if cfg.NGram < 1 || cfg.NGram > maxGram {
+		return fmt.Errorf("n-gram order must be from 1 to %d, got %d", maxGram, cfg.NGram)
+	}
+	if cfg.DiceWeight < 0 || cfg.LengthWeight < 0 || cfg.ProperNounWeight < 0 {
+		return fmt.Errorf("similarity weights must not be negative")
+	}
+	if cfg.DiceWeight+cfg.LengthWeight+cfg.ProperNounWeight == 0 {
+		return fmt.Errorf("similarity weights must not all be zero")
+	}
+	if cfg.Band < 0 {
+		return fmt.Errorf("band must not be negative, got %d", cfg.Band)
+	}
+	if cfg.Mean <= 0 || cfg.Variance <= 0 {
+		return fmt.Errorf("Gale-Church mean and variance must be positive")
+	}
+	return nil
+}
+
+// allowsNull reports whether sentences may be left unaligned.
+func (cfg AlignerConfig) allowsNull() bool {
+	return cfg.NullPenalty >= 0
+}
+
+// maxMerge is the most sentences the scoring merges per side.
+func (cfg AlignerConfig) maxMerge() int {
+	if cfg.Scoring == ScoringGaleChurch {
+		return 2 // the largest bead Gale and Church use
+	}
+	return cfg.MaxMerge
+}
+
+/*
+Prior probabilities of the beads of "A Program for Aligning Sentences in
+Bilingual Corpora" by William A. Gale and Kenneth W. Church.
+https://aclanthology.org/J93-1004.pdf
+The Gale-Church scoring only uses these beads.
+*/
+var galeChurchPriors = map[[2]int]float64{
+	{1, 1}: 0.89,
+	{1, 0}: 0.0099,
+	{0, 1}: 0.0099,
+	{2, 1}: 0.089,
+	{1, 2}: 0.089,
+	{2, 2}: 0.011,
+}
+
+// normalCDF is the cumulative distribution function of the standard normal distribution.
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+/*
+galeChurchLengthProbability is the probability that groups of srcLen and tgtLen
+characters are translations of each other by their lengths alone: two-tailed,
+1 when the lengths match the expected ratio exactly.
+*/
+func galeChurchLengthProbability(srcLen, tgtLen int, cfg AlignerConfig) float64 {
+	if srcLen == 0 && tgtLen == 0 {
+		return 1
+	}
+
+	l1, l2 := float64(srcLen), float64(tgtLen)
+	mean := (l1 + l2/cfg.Mean) / 2
+	delta := (l2 - l1*cfg.Mean) / math.Sqrt(mean*cfg.Variance)
+	return 2 * (1 - normalCDF(math.Abs(delta)))
+}
+
+/*
+galeChurchGain is the log-probability of a bead, the negative of its
+Gale-Church cost, so the aligner maximizes it like the similarity. It is
+false for beads Gale and Church do not use.
+*/
+func galeChurchGain(srcCount, tgtCount, srcLen, tgtLen int, cfg AlignerConfig) (float64, bool) {
+	prior, ok := galeChurchPriors[[2]int{srcCount, tgtCount}]
+	if !ok {
+		return 0, false
+	}
+
+	// keep the log finite when the lengths are far off
+	p := max(galeChurchLengthProbability(srcLen, tgtLen, cfg), 1e-300)
+	return math.Log(prior) + math.Log(p), true
+}
@@ -0,0 +1,133 @@
+package sentencealignment
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// merges lists the merge types of the aligned pairs, "1-1", "1-0" and so on, in order.
+func merges(pairs []types.TextPair) []string {
+	out := make([]string, len(pairs))
+	for i, pair := range pairs {
+		out[i] = pair.Merge
+	}
+	return out
+}
+
+func TestAlignNullBeads(t *testing.T) {
+	tagalog := []string{
+		"At sinabi ni Moises kay Aaron, Kunin mo ang iyong tungkod.",
+		"Ito ang salitang iniutos ng Panginoon sa kanila sa ilang, at sila ay nagsiyukod at sumamba sa harap ng tabernakulo.",
+		"At iniunat ni Aaron ang kaniyang kamay sa tubig ng Egipto.",
+	}
+	cebuano := []string{
+		"Ug si Moises miingon kang Aaron, Kuhaa ang imong sungkod.",
+		"Ug gituy-od ni Aaron ang iyang kamot sa tubig sa Egipto.",
+	}
+
+	similarity := DefaultAlignerConfig()
+	galeChurch := DefaultAlignerConfig()
+	galeChurch.Scoring = ScoringGaleChurch
+	forbidNull := func(cfg AlignerConfig) AlignerConfig {
+		cfg.NullPenalty = -1
+		return cfg
+	}
+
+	tests := []struct {
+		name     string
+		cfg      AlignerConfig
+		src, tgt []string
+		want     []string // merge types, nil for any alignment without a null bead
+	}{
+		{"extra source sentence left unaligned", similarity, tagalog, cebuano, []string{"1-1", "1-0", "1-1"}},
+		{"extra target sentence left unaligned", similarity, cebuano, tagalog, []string{"1-1", "0-1", "1-1"}},
+		{"negative null penalty forbids null beads", forbidNull(similarity), tagalog, cebuano, nil},
+		{"gale-church: negative null penalty forbids null beads", forbidNull(galeChurch), tagalog, cebuano, nil},
+		{"gale-church: no alignment without null beads keeps the verse as one bead", forbidNull(galeChurch), cebuano[:1], tagalog, []string{"1-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verse := types.PairID{Book: "EXO", Chapter: 7, Verse: types.VerseNumber{Start: 19, End: 19}}
+			got := merges(AlignSentencesByGaleChurchDP(tt.src, tt.tgt, verse, types.NewProperNounCache(), tt.cfg))
+
+			if tt.want != nil {
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("merges %q, want %q", got, tt.want)
+				}
+				return
+			}
+			if len(got) != 2 || slices.Contains(got, "1-0") || slices.Contains(got, "0-1") {
+				t.Errorf("merges %q, want the extra sentence merged into one of two beads", got)
+			}
+		})
+	}
+}
+
+func TestAlignWholeVerse(t *testing.T) {
+	cfg := DefaultAlignerConfig()
+	cfg.Scoring = ScoringGaleChurch
+	cfg.NullPenalty = -1
+
+	src := []string{"Mihilak si Jesus."}
+	tgt := []string{"Tumangis si Jesus.", "Kaya't sinabi ng mga Judio.", "Narito, kung gaano ang pagibig niya sa kaniya!"}
+	verse := types.PairID{Book: "JHN", Chapter: 11, Verse: types.VerseNumber{Start: 35, End: 36}}
+
+	aligner := NewAligner(src, tgt, types.NewProperNounCache())
+	pairs := aligner.Align(verse, cfg)
+	if len(pairs) != 1 || pairs[0].Merge != "1-3" || pairs[0].ScoreLabel() != "0.0000" || !aligner.WholeVerse() {
+		t.Fatalf("pairs %+v, whole verse %v; want one 1-3 pair scored 0", pairs, aligner.WholeVerse())
+	}
+	if want := "Tumangis si Jesus. Kaya't sinabi ng mga Judio. Narito, kung gaano ang pagibig niya sa kaniya!"; pairs[0].TargetText != want {
+		t.Errorf("target %q, want every sentence joined", pairs[0].TargetText)
+	}
+
+	reference := AlignSentencesReference(src, tgt, verse, types.NewProperNounCache(), cfg)
+	if got := merges(reference); !slices.Equal(got, []string{"1-3"}) {
+		t.Errorf("reference merges %q, want [1-3]", got)
+	}
+
+	cfg.NullPenalty = DefaultAlignerConfig().NullPenalty
+	aligner.Align(verse, cfg)
+	if aligner.WholeVerse() {
+		t.Error("WholeVerse after an alignment with null beads")
+	}
+}
+
+func TestGaleChurchLengthCost(t *testing.T) {
+	cfg := DefaultAlignerConfig()
+	cfg.Mean = 1.2
+
+	if p := galeChurchLengthProbability(100, 120, cfg); math.Abs(p-1) > 1e-9 {
+		t.Errorf("probability of lengths in the ratio of the mean = %g, want 1", p)
+	}
+
+	best, bestLen := math.Inf(-1), 0
+	for tgtLen := 60; tgtLen <= 180; tgtLen++ {
+		gain, ok := galeChurchGain(1, 1, 100, tgtLen, cfg)
+		if !ok {
+			t.Fatal("1-1 bead not scored")
+		}
+		if gain > best {
+			best, bestLen = gain, tgtLen
+		}
+	}
+	if bestLen != 120 {
+		t.Errorf("lowest cost of 100 source characters at %d target characters, want 120", bestLen)
+	}
+
+	// the further from the ratio, the costlier, on either side
+	near, _ := galeChurchGain(1, 1, 100, 130, cfg)
+	far, _ := galeChurchGain(1, 1, 100, 160, cfg)
+	short, _ := galeChurchGain(1, 1, 100, 80, cfg)
+	if !(best > near && near > far && best > short) {
+		t.Errorf("gains 120: %g, 130: %g, 160: %g, 80: %g; want them falling away from 120", best, near, far, short)
+	}
+
+	if _, ok := galeChurchGain(3, 1, 100, 120, cfg); ok {
+		t.Error("3-1 bead scored, want it outside the Gale-Church beads")
+	}
+}
@@ -0,0 +1,125 @@
+package sentencealignment
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// maxGram is the longest character n-gram the similarity may compare.
+const maxGram = 5
+
+/*
+groupProfile is what SentenceSimilarity reads of a group of sentences joined by
+spaces, computed once per group. A merged group is built from its prefix (the
+group one sentence shorter) and its last sentence: its n-grams are theirs plus
+the few that run across the join, since spaces are not characters of an n-gram.
+*/
+type groupProfile struct {
+	length int      // runes of the joined text
+	runes  []rune   // lowercased characters of a single sentence, spaces left out
+	head   []rune   // first maxGram-1 characters of the group, spaces left out
+	tail   []rune   // last maxGram-1 characters of the group, spaces left out
+	nouns  []string // words found in the proper noun cache, in order
+
+	grams        [maxGram + 1]map[string]struct{} // character n-gram sets by n, built on demand
+	prefix, last *groupProfile                    // nil for a single sentence
+}
+
+func newSentenceProfile(sentence string, cache *types.ProperNounCache) *groupProfile {
+	sentence = strings.TrimSpace(sentence)
+	p := &groupProfile{length: len([]rune(sentence))}
+
+	for _, r := range strings.ToLower(sentence) {
+		if !unicode.IsSpace(r) {
+			p.runes = append(p.runes, r)
+		}
+	}
+	p.head = p.runes[:min(len(p.runes), maxGram-1)]
+	p.tail = p.runes[max(len(p.runes)-(maxGram-1), 0):]
+
+	for _, word := range strings.Fields(sentence) {
+		word = strings.Trim(word, ".,;:!?\"'")
+		if _, ok := cache.Words[word]; ok {
+			p.nouns = append(p.nouns, word)
+		}
+	}
+	return p
+}
+
+// extend profiles the group followed by one more sentence.
+func (p *groupProfile) extend(last *groupProfile) *groupProfile {
+	head := slices.Concat(p.head, last.head)
+	tail := slices.Concat(p.tail, last.tail)
+	return &groupProfile{
+		length: p.length + 1 + last.length,
+		head:   head[:min(len(head), maxGram-1)],
+		tail:   tail[max(len(tail)-(maxGram-1), 0):],
+		nouns:  slices.Concat(p.nouns, last.nouns),
+		prefix: p,
+		last:   last,
+	}
+}
+
+// ngrams is the set of character n-grams of the group, as CharNGrams gives for its joined text.
+func (p *groupProfile) ngrams(n int) map[string]struct{} {
+	if p.grams[n] != nil {
+		return p.grams[n]
+	}
+
+	var set map[string]struct{}
+	if p.prefix == nil {
+		set = make(map[string]struct{}, max(len(p.runes)-n+1, 0))
+		for i := 0; i+n <= len(p.runes); i++ {
+			set[string(p.runes[i:i+n])] = struct{}{}
+		}
+	} else {
+		prefix, last := p.prefix.ngrams(n), p.last.ngrams(n)
+		set = make(map[string]struct{}, len(prefix)+len(last)+n)
+		for g := range prefix {
+			set[g] = struct{}{}
+		}
+		for g := range last {
+			set[g] = struct{}{}
+		}
+
+		// the n-grams across the join start in the prefix's tail and end in the sentence's head
+		seam := slices.Concat(p.prefix.tail[max(len(p.prefix.tail)-(n-1), 0):], p.last.head[:min(len(p.last.head), n-1)])
+		for i := 0; i+n <= len(seam); i++ {
+			set[string(seam[i:i+n])] = struct{}{}
+		}
+	}
+
+	p.grams[n] = set
+	return set
+}
+
+func diceOfSets(set1, set2 map[string]struct{}) float64 {
+	if len(set1)+len(set2) == 0 {
+		return 0.0
+	}
+	if len(set1) > len(set2) {
+		set1, set2 = set2, set1
+	}
+
+	intersection := 0
+	for g := range set1 {
+		if _, exists := set2[g]; exists {
+			intersection++
+		}
+	}
+	return (2.0 * float64(intersection)) / float64(len(set1)+len(set2))
+}
+
+// similarityParts are the measures SentenceSimilarity weighs, kept apart so a score can be reweighed.
+type similarityParts struct {
+	dice        float64 // character n-gram Dice coefficient
+	lengthRatio float64
+	properNouns float64 // share of the source proper nouns found in the target
+}
+
+func (s similarityParts) weighted(cfg AlignerConfig) float64 {
+	return cfg.LengthWeight*s.lengthRatio + cfg.DiceWeight*s.dice + cfg.ProperNounWeight*s.properNouns
+}
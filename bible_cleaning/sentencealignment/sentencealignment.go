@@ -2,6 +2,7 @@ package sentencealignment
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"unicode"
 
@@ -175,8 +176,9 @@ func ProperNounOverlapScore(src, tgt string, cache *types.ProperNounCache) float
 Does a sentence similarity based on a combination of Dice coefficient of n-grams and length ratio.
 Based on "A Fast, Flexible Model for Sentence Alignment" by Daniel M. Cer et al.
 https://aclanthology.org/W17-2511.pdf
+The weights and n-gram order are those of cfg; n is lowered for shorter sentences.
 */
-func SentenceSimilarity(sent1, sent2 string, cache *types.ProperNounCache) float64 {
+func SentenceSimilarity(sent1, sent2 string, cache *types.ProperNounCache, cfg AlignerConfig) float64 {
 	sent1 = strings.TrimSpace(sent1)
 	sent2 = strings.TrimSpace(sent2)
 
@@ -188,38 +190,77 @@ func SentenceSimilarity(sent1, sent2 string, cache *types.ProperNounCache) float
 	len2 := len([]rune(sent2))
 
 	// Dynamically choose n based on shortest length
-	n := 3
-	if len1 < 3 || len2 < 3 {
-		if len1 < 2 || len2 < 2 {
-			n = 1 // fallback for very short strings
-		} else {
-			n = 2
-		}
-	}
+	n := min(cfg.NGram, len1, len2)
 
 	// Compute character n-gram Dice similarity
 	NGramDiceSim := CharNGramDiceSimilarity(sent1, sent2, n)
 	LenRatio := LengthRatioSimilarity(sent1, sent2)
 	PropSim := ProperNounOverlapScore(sent1, sent2, cache)
-	return config.LENGTH_RATIO_SIMILARITY_BIAS*LenRatio + config.NGRAMS_DICE_SIMILARITY_BIAS*NGramDiceSim + config.PROPER_NOUNS_SIMILARITY_BIAS*PropSim
+	return cfg.LengthWeight*LenRatio + cfg.DiceWeight*NGramDiceSim + cfg.ProperNounWeight*PropSim
 }
 
-func AlignSentencesByGaleChurchDP(srcSents, tgtSents []string, verseID string, cache *types.ProperNounCache) []types.TextPair {
-	m, n := len(srcSents), len(tgtSents)
-	if m == 0 || n == 0 {
-		return nil
+/*
+beadGain rates aligning srcGroup (srcCount sentences) to tgtGroup (tgtCount sentences).
+The gain is what the aligner maximizes over the verse, the score the confidence kept
+on the pair, from 0 to 1. It is false for beads the configuration does not allow.
+*/
+func beadGain(srcGroup, tgtGroup string, srcCount, tgtCount int, cache *types.ProperNounCache, cfg AlignerConfig) (gain, score float64, ok bool) {
+	null := srcCount == 0 || tgtCount == 0
+	if null && (srcCount+tgtCount != 1 || !cfg.allowsNull()) {
+		return 0, 0, false
+	}
+
+	if cfg.Scoring == ScoringGaleChurch {
+		srcLen, tgtLen := len([]rune(srcGroup)), len([]rune(tgtGroup))
+		gain, ok = galeChurchGain(srcCount, tgtCount, srcLen, tgtLen, cfg)
+		if !ok {
+			return 0, 0, false
+		}
+		if !null {
+			score = galeChurchLengthProbability(srcLen, tgtLen, cfg)
+		}
+		return gain, score, true
+	}
+
+	if null {
+		return -cfg.NullPenalty, 0, true
 	}
+	similarity := SentenceSimilarity(srcGroup, tgtGroup, cache, cfg)
+	return similarity, similarity, true
+}
+
+// bead is a step of an alignment: srcCount source sentences aligned to tgtCount target sentences.
+type bead struct {
+	srcCount, tgtCount int
+	score              float64 // confidence of the bead
+}
 
-	const maxMerge = 5 // support up to 7 sentences merged per side
+// inBand reports whether cell (i, j) of an m by n table lies within band sentences of its diagonal; band 0 allows all.
+func inBand(i, j, m, n, band int) bool {
+	if band <= 0 {
+		return true
+	}
+	deviation := i*n - j*m
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	return deviation*max(m, n) <= band*m*n
+}
 
+/*
+alignBeads finds the beads of highest total gain from cell (0, 0) to (m, n), where
+gain rates the bead of srcCount sentences ending before i and tgtCount ending
+before j. It returns nil if no alignment fits in the band.
+*/
+func alignBeads(m, n, maxMerge, band int, gain func(i, srcCount, j, tgtCount int) (float64, float64, bool)) []bead {
 	// DP tables
 	dp := make([][]float64, m+1)
-	bt := make([][]struct{ srcCount, tgtCount int }, m+1)
+	bt := make([][]bead, m+1)
 	for i := range dp {
 		dp[i] = make([]float64, n+1)
-		bt[i] = make([]struct{ srcCount, tgtCount int }, n+1)
+		bt[i] = make([]bead, n+1)
 		for j := range dp[i] {
-			dp[i][j] = -1e9
+			dp[i][j] = math.Inf(-1)
 		}
 	}
 	dp[0][0] = 0
@@ -227,46 +268,121 @@ func AlignSentencesByGaleChurchDP(srcSents, tgtSents []string, verseID string, c
 	// Transition loop
 	for i := 0; i <= m; i++ {
 		for j := 0; j <= n; j++ {
-			for srcCount := 1; srcCount <= maxMerge && i-srcCount >= 0; srcCount++ {
-				for tgtCount := 1; tgtCount <= maxMerge && j-tgtCount >= 0; tgtCount++ {
+			if !inBand(i, j, m, n, band) {
+				continue
+			}
+			for srcCount := 0; srcCount <= maxMerge && i-srcCount >= 0; srcCount++ {
+				for tgtCount := 0; tgtCount <= maxMerge && j-tgtCount >= 0; tgtCount++ {
+					if (srcCount == 0 && tgtCount == 0) || math.IsInf(dp[i-srcCount][j-tgtCount], -1) {
+						continue
+					}
 
-					// concatenate source/tgt group
-					srcGroup := strings.Join(srcSents[i-srcCount:i], " ")
-					tgtGroup := strings.Join(tgtSents[j-tgtCount:j], " ")
+					gain, confidence, ok := gain(i, srcCount, j, tgtCount)
+					if !ok {
+						continue
+					}
 
-					score := dp[i-srcCount][j-tgtCount] + SentenceSimilarity(srcGroup, tgtGroup, cache)
+					score := dp[i-srcCount][j-tgtCount] + gain
 					if score > dp[i][j] {
 						dp[i][j] = score
-						bt[i][j] = struct{ srcCount, tgtCount int }{srcCount, tgtCount}
+						bt[i][j] = bead{srcCount, tgtCount, confidence}
 					}
 				}
 			}
 		}
 	}
+	if math.IsInf(dp[m][n], -1) {
+		return nil
+	}
 
 	// Backtrack
-	var pairs []types.TextPair
+	var beads []bead
 	i, j := m, n
-	count := 1
-	for i > 0 && j > 0 {
-		step := bt[i][j]
-		if step.srcCount == 0 || step.tgtCount == 0 {
-			break
+	for i > 0 || j > 0 {
+		best := bt[i][j]
+		beads = append([]bead{best}, beads...)
+		i -= best.srcCount
+		j -= best.tgtCount
+	}
+	return beads
+}
+
+/*
+wholeVerse is the alignment left when alignBeads finds none: null beads are
+forbidden and the sentence counts differ by more than the largest bead takes
+in, say 1 against 3 under Gale-Church. The verse becomes one m-n bead with a
+confidence of 0, so it is kept rather than dropped, and --min-score can leave
+it out.
+*/
+func wholeVerse(m, n int) []bead {
+	return []bead{{srcCount: m, tgtCount: n}}
+}
+
+/*
+pairsFromBeads joins the sentences of each bead into a pair, numbered in order
+within the verse: verse "GEN_001_003" gives "GEN_001_003_001", "GEN_001_003_002", ...
+The empty side of an unaligned sentence is labelled as a missing translation.
+*/
+func pairsFromBeads(srcSents, tgtSents []string, beads []bead, verse types.PairID) []types.TextPair {
+	pairs := make([]types.TextPair, 0, len(beads))
+	i, j := 0, 0
+	for k, b := range beads {
+		srcGroup := strings.Join(srcSents[i:i+b.srcCount], " ")
+		tgtGroup := strings.Join(tgtSents[j:j+b.tgtCount], " ")
+		i += b.srcCount
+		j += b.tgtCount
+
+		var lengthRatio float64
+		if b.srcCount == 0 {
+			srcGroup = config.TOKEN_MISSING_TRANSLATION
+		} else if b.tgtCount == 0 {
+			tgtGroup = config.TOKEN_MISSING_TRANSLATION
+		} else {
+			lengthRatio = LengthRatioSimilarity(srcGroup, tgtGroup)
 		}
 
-		srcGroup := strings.Join(srcSents[i-step.srcCount:i], " ")
-		tgtGroup := strings.Join(tgtSents[j-step.tgtCount:j], " ")
+		id := verse
+		id.Sentence = k + 1
+		pair := types.NewTextPair(id, srcGroup, tgtGroup)
+		pair.Merge = fmt.Sprintf("%d-%d", b.srcCount, b.tgtCount)
+		pair.SetScore(b.score)
+		pair.LengthRatio = lengthRatio
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
 
-		pairs = append([]types.TextPair{{
-			ID:         fmt.Sprintf("%s_%03d", verseID, count),
-			SourceText: srcGroup,
-			TargetText: tgtGroup,
-		}}, pairs...)
+/*
+Aligns the sentences of a verse (or span of merged verses) by dynamic programming over
+beads: groups of source sentences aligned to groups of target sentences, including a
+sentence left unaligned (1-0, 0-1) if the configuration allows it. The pairs are numbered
+in order within the verse: verse "GEN_001_003" gives "GEN_001_003_001", "GEN_001_003_002", ...
+Each pair keeps the score of its bead, its merge type and length ratio. The empty side of
+an unaligned sentence is labelled as a missing translation.
+*/
+func AlignSentencesByGaleChurchDP(srcSents, tgtSents []string, verse types.PairID, cache *types.ProperNounCache, cfg AlignerConfig) []types.TextPair {
+	return NewAligner(srcSents, tgtSents, cache).Align(verse, cfg)
+}
 
-		count++
-		i -= step.srcCount
-		j -= step.tgtCount
+/*
+AlignSentencesReference aligns like AlignSentencesByGaleChurchDP but joins and scores
+every bead from its text with SentenceSimilarity over the whole table, as the aligner
+did before sentence profiles. It is the baseline the bench command measures against.
+*/
+func AlignSentencesReference(srcSents, tgtSents []string, verse types.PairID, cache *types.ProperNounCache, cfg AlignerConfig) []types.TextPair {
+	m, n := len(srcSents), len(tgtSents)
+	if m == 0 || n == 0 {
+		return nil
 	}
 
-	return pairs
+	beads := alignBeads(m, n, cfg.maxMerge(), 0, func(i, srcCount, j, tgtCount int) (float64, float64, bool) {
+		// concatenate source/tgt group
+		srcGroup := strings.Join(srcSents[i-srcCount:i], " ")
+		tgtGroup := strings.Join(tgtSents[j-tgtCount:j], " ")
+		return beadGain(srcGroup, tgtGroup, srcCount, tgtCount, cache, cfg)
+	})
+	if beads == nil {
+		beads = wholeVerse(m, n)
+	}
+	return pairsFromBeads(srcSents, tgtSents, beads, verse)
 }
@@ -0,0 +1,181 @@
+package sentencecleaning
+
+import (
+	"iter"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+/*
+An unsupervised Punkt-style trainer, after "Unsupervised Multilingual Sentence
+Boundary Detection" by Tibor Kiss and Jan Strunk.
+https://aclanthology.org/J06-4003.pdf
+
+It learns the abbreviations of a translation from how often a word is written
+with a final period compared to without, and the words that start a sentence
+often enough to end one even after an abbreviation.
+*/
+
+const (
+	punktAbbreviationScore = 0.3 // minimum score of a word to be taken as an abbreviation
+	punktStarterScore      = 30  // minimum log-likelihood of a sentence starter
+)
+
+// PunktModel is what the trainer learned from a translation.
+type PunktModel struct {
+	Abbreviations    []string // lowercased, with the final period
+	SentenceStarters []string // lowercased
+}
+
+// punktToken is a word of the training text with its surrounding punctuation taken off.
+type punktToken struct {
+	word      string // lowercased, without the final period
+	period    bool   // ends in a period
+	endsBreak bool   // ends in '?' or '!', or in a period after the word
+}
+
+func punktTokens(text string) []punktToken {
+	var tokens []punktToken
+	for _, field := range strings.Fields(text) {
+		field = strings.Trim(strings.ToLower(field), openers+closers)
+		word := strings.TrimRight(field, ".?!,;:")
+		if !strings.ContainsFunc(word, unicode.IsLetter) {
+			continue
+		}
+
+		tail := field[len(word):]
+		tokens = append(tokens, punktToken{
+			word:      word,
+			period:    strings.HasPrefix(tail, "."),
+			endsBreak: strings.ContainsAny(tail, ".?!"),
+		})
+	}
+	return tokens
+}
+
+/*
+TrainPunkt fits a model on the texts of one translation. The texts are read
+twice: once to find the abbreviations and once to find the sentence starters
+after the breaks that are not abbreviations. Only counts per word are kept.
+*/
+func TrainPunkt(texts iter.Seq[string]) *PunktModel {
+	withPeriod := make(map[string]int)
+	withoutPeriod := make(map[string]int)
+	total, periods := 0, 0
+
+	for text := range texts {
+		for _, token := range punktTokens(text) {
+			total++
+			if token.period {
+				periods++
+				withPeriod[token.word]++
+			} else {
+				withoutPeriod[token.word]++
+			}
+		}
+	}
+
+	model := &PunktModel{}
+	if periods == 0 || periods == total {
+		return model
+	}
+
+	abbreviations := make(map[string]bool)
+	for word, with := range withPeriod {
+		without := withoutPeriod[word]
+		ll := dunningLogLikelihood(with+without, periods, with, total)
+
+		numPeriods := float64(strings.Count(word, ".") + 1)
+		numNonPeriods := float64(len([]rune(word))) - numPeriods + 1
+		score := ll * math.Exp(-numNonPeriods) * numPeriods * math.Pow(numNonPeriods, -float64(without))
+
+		if score >= punktAbbreviationScore {
+			abbreviations[word] = true
+			model.Abbreviations = append(model.Abbreviations, word+".")
+		}
+	}
+	sort.Strings(model.Abbreviations)
+
+	atBreak := make(map[string]int)
+	breaks := 0
+	for text := range texts {
+		tokens := punktTokens(text)
+		for i := 1; i < len(tokens); i++ {
+			previous := tokens[i-1]
+			if previous.endsBreak && !(previous.period && abbreviations[previous.word]) {
+				breaks++
+				atBreak[tokens[i].word]++
+			}
+		}
+	}
+
+	for word, count := range atBreak {
+		wordCount := withPeriod[word] + withoutPeriod[word]
+		if wordCount < count {
+			continue
+		}
+
+		ll := collocationLogLikelihood(breaks, wordCount, count, total)
+		if ll >= punktStarterScore && float64(total)/float64(breaks) > float64(wordCount)/float64(count) {
+			model.SentenceStarters = append(model.SentenceStarters, word)
+		}
+	}
+	sort.Strings(model.SentenceStarters)
+
+	return model
+}
+
+// Segmenter returns a rule-based segmenter using the learned abbreviations on top of the given ones.
+func (m *PunktModel) Segmenter(abbreviations []string) *RuleSegmenter {
+	s := NewRuleSegmenter(append(append([]string{}, abbreviations...), m.Abbreviations...))
+	for _, word := range m.SentenceStarters {
+		s.SentenceStarters[word] = true
+	}
+	return s
+}
+
+// dunningLogLikelihood scores how much more often a word of countA occurrences ends in a period than the countB periods of n words predict.
+func dunningLogLikelihood(countA, countB, countAB, n int) float64 {
+	p1 := float64(countB) / float64(n)
+	p2 := 0.99
+
+	null := float64(countAB)*math.Log(p1) + float64(countA-countAB)*math.Log(1-p1)
+	alt := float64(countAB)*math.Log(p2) + float64(countA-countAB)*math.Log(1-p2)
+	return -2 * (null - alt)
+}
+
+// xLogY is x*log(y), taken as 0 when the logarithm is undefined.
+func xLogY(x, y float64) float64 {
+	if x == 0 || y <= 0 {
+		return 0
+	}
+	return x * math.Log(y)
+}
+
+// collocationLogLikelihood scores how strongly a word of countB occurrences follows the countA breaks, countAB times out of n words.
+func collocationLogLikelihood(countA, countB, countAB, n int) float64 {
+	a, b, ab, total := float64(countA), float64(countB), float64(countAB), float64(n)
+
+	p := b / total
+	p1 := ab / a
+	p2 := 1.0
+	if total != a {
+		p2 = (b - ab) / (total - a)
+	}
+
+	summand1 := xLogY(ab, p) + xLogY(a-ab, 1-p)
+	summand2 := xLogY(b-ab, p) + xLogY(total-a-b+ab, 1-p)
+
+	summand3 := 0.0
+	if countA != countAB && p1 > 0 && p1 < 1 {
+		summand3 = xLogY(ab, p1) + xLogY(a-ab, 1-p1)
+	}
+	summand4 := 0.0
+	if countB != countAB && p2 > 0 && p2 < 1 {
+		summand4 = xLogY(b-ab, p2) + xLogY(total-a-b+ab, 1-p2)
+	}
+
+	return -2 * (summand1 + summand2 - summand3 - summand4)
+}
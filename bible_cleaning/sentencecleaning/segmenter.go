@@ -0,0 +1,225 @@
+package sentencecleaning
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"unicode"
+)
+
+// Segmenter splits the text of a verse into sentences.
+type Segmenter interface {
+	Segment(text string) []string
+}
+
+// Names of the segmenters a translation can pick in the registry.
+const (
+	SegmenterRules = "rules"
+	SegmenterPunkt = "punkt"
+)
+
+// IsSegmenter reports whether name is a segmenter, the empty name meaning the default.
+func IsSegmenter(name string) bool {
+	return name == "" || name == SegmenterRules || name == SegmenterPunkt
+}
+
+// DefaultAbbreviations are never taken as the end of a sentence, whatever the language.
+var DefaultAbbreviations = []string{
+	"St.", "Sto.", "Sta.", "Sr.", "Jr.", "Dr.", "Mr.", "Mrs.",
+	"etc.", "vs.", "cf.", "p.", "pp.", "vv.",
+}
+
+/*
+NewSegmenter builds the named segmenter for a language. abbreviations are
+added to DefaultAbbreviations. The Punkt segmenter is trained on texts first,
+which it reads twice; the rule-based one ignores them.
+*/
+func NewSegmenter(name string, abbreviations []string, texts iter.Seq[string]) (Segmenter, error) {
+	known := append(append([]string{}, DefaultAbbreviations...), abbreviations...)
+
+	switch name {
+	case "", SegmenterRules:
+		return NewRuleSegmenter(known), nil
+	case SegmenterPunkt:
+		return TrainPunkt(texts).Segmenter(known), nil
+	}
+	return nil, fmt.Errorf("unknown segmenter %q", name)
+}
+
+/*
+RuleSegmenter ends a sentence at '.', '?' or '!' (and ';' if SplitOnSemicolon)
+followed by a space and a word that does not start in lowercase. A period
+after an abbreviation or an initial does not end a sentence unless the next
+word is a known sentence starter. No sentence ends inside quotes or brackets,
+unless the verse leaves a quote open (quotations often run across verses), in
+which case the quotes are ignored.
+*/
+type RuleSegmenter struct {
+	Abbreviations    map[string]bool // lowercased, with the final period
+	SentenceStarters map[string]bool // lowercased words that start a sentence even after an abbreviation
+	SplitOnSemicolon bool
+}
+
+func NewRuleSegmenter(abbreviations []string) *RuleSegmenter {
+	s := &RuleSegmenter{
+		Abbreviations:    make(map[string]bool, len(abbreviations)),
+		SentenceStarters: make(map[string]bool),
+	}
+	for _, a := range abbreviations {
+		a = strings.ToLower(a)
+		if !strings.HasSuffix(a, ".") {
+			a += "."
+		}
+		s.Abbreviations[a] = true
+	}
+	return s
+}
+
+const (
+	openers = "\"“‘([«"
+	closers = "\"”’)]»"
+)
+
+func (s *RuleSegmenter) isTerminator(r rune) bool {
+	return r == '.' || r == '?' || r == '!' || (s.SplitOnSemicolon && r == ';')
+}
+
+/*
+nesting tracks how deep in quotes and brackets a position of the text is.
+Single quotes are counted apart from double quotes: ’ is also the apostrophe
+of contractions such as the Tagalog "ako’y", so it only closes a quote opened
+with ‘ and never when a letter follows it.
+*/
+type nesting struct {
+	quotes   int // double quotes and guillemets
+	singles  int
+	brackets int
+	straight bool // inside a straight double quote
+}
+
+// inQuotes tells whether the position is inside any quote.
+func (n nesting) inQuotes() bool {
+	return n.quotes > 0 || n.singles > 0
+}
+
+// update takes in the rune r, followed by next (0 at the end of the text).
+func (n *nesting) update(r, next rune) {
+	switch r {
+	case '"':
+		if n.straight {
+			n.quotes--
+		} else {
+			n.quotes++
+		}
+		n.straight = !n.straight
+	case '“', '«':
+		n.quotes++
+	case '”', '»':
+		n.quotes = max(n.quotes-1, 0)
+	case '‘':
+		n.singles++
+	case '’':
+		// an apostrophe unless a single quote is open and no letter follows
+		if n.singles > 0 && !unicode.IsLetter(next) {
+			n.singles--
+		}
+	case '(', '[':
+		n.brackets++
+	case ')', ']':
+		n.brackets = max(n.brackets-1, 0)
+	}
+}
+
+type boundary struct {
+	end     int // the sentence ends before this rune index
+	nesting nesting
+}
+
+func (s *RuleSegmenter) Segment(text string) []string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+
+	var candidates []boundary
+	var depth nesting
+	for i := 0; i < len(runes); i++ {
+		depth.update(runes[i], runeAt(runes, i+1))
+		if !s.isTerminator(runes[i]) {
+			continue
+		}
+
+		// take in the punctuation and closing quotes that follow
+		end := i + 1
+		for end < len(runes) && (s.isTerminator(runes[end]) || strings.ContainsRune(closers, runes[end])) {
+			depth.update(runes[end], runeAt(runes, end+1))
+			end++
+		}
+		terminator, at := runes[i], i
+		i = end - 1
+
+		if end >= len(runes) || runes[end] != ' ' {
+			continue
+		}
+
+		next := nextWord(runes[end+1:])
+		if next == "" || unicode.IsLower([]rune(next)[0]) {
+			continue
+		}
+		if terminator == '.' && s.isAbbreviation(previousWord(runes[:at+1])) && !s.SentenceStarters[strings.ToLower(next)] {
+			continue
+		}
+
+		candidates = append(candidates, boundary{end: end, nesting: depth})
+	}
+
+	quotesBalanced := !depth.inQuotes()
+	bracketsBalanced := depth.brackets == 0
+
+	var sentences []string
+	start := 0
+	for _, b := range candidates {
+		if (quotesBalanced && b.nesting.inQuotes()) || (bracketsBalanced && b.nesting.brackets > 0) {
+			continue
+		}
+		if sentence := strings.TrimSpace(string(runes[start:b.end])); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = b.end
+	}
+	if rest := strings.TrimSpace(string(runes[start:])); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
+
+// runeAt returns the rune at index i, or 0 past the end of the text.
+func runeAt(runes []rune, i int) rune {
+	if i < len(runes) {
+		return runes[i]
+	}
+	return 0
+}
+
+// isAbbreviation tells whether a word ending in a period is a known abbreviation, an initial ("J.") or dotted ("a.m.").
+func (s *RuleSegmenter) isAbbreviation(word string) bool {
+	word = strings.ToLower(word)
+	if s.Abbreviations[word] {
+		return true
+	}
+
+	stem := []rune(strings.TrimSuffix(word, "."))
+	return (len(stem) == 1 && unicode.IsLetter(stem[0])) || strings.Contains(string(stem), ".")
+}
+
+// previousWord returns the word the text ends with, without the quotes and brackets before it.
+func previousWord(runes []rune) string {
+	start := len(runes)
+	for start > 0 && runes[start-1] != ' ' {
+		start--
+	}
+	return strings.TrimLeft(string(runes[start:]), openers)
+}
+
+// nextWord returns the first word of the text, without the quotes and brackets before it or the punctuation after it.
+func nextWord(runes []rune) string {
+	word, _, _ := strings.Cut(string(runes), " ")
+	return strings.TrimRightFunc(strings.TrimLeft(word, openers), unicode.IsPunct)
+}
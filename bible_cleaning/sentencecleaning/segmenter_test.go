@@ -0,0 +1,113 @@
+package sentencecleaning
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRuleSegmenter(t *testing.T) {
+	segmenter, err := NewSegmenter(SegmenterRules, []string{"Gng.", "Bb."}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "sentence end",
+			text: "Siya ay umalis. Bumalik siya kinabukasan!  Saan siya nagpunta?",
+			want: []string{"Siya ay umalis.", "Bumalik siya kinabukasan!", "Saan siya nagpunta?"},
+		},
+		{
+			name: "lowercase next word",
+			text: "Siya ay umalis. at bumalik.",
+			want: []string{"Siya ay umalis. at bumalik."},
+		},
+		{
+			name: "registry abbreviation",
+			text: "Si Gng. Santos ay dumating. Siya ay masaya.",
+			want: []string{"Si Gng. Santos ay dumating.", "Siya ay masaya."},
+		},
+		{
+			name: "default abbreviations",
+			text: "Nagdasal sila kay Sto. Niño at kay St. Pedro. Umuwi sila.",
+			want: []string{"Nagdasal sila kay Sto. Niño at kay St. Pedro.", "Umuwi sila."},
+		},
+		{
+			name: "initial",
+			text: "Isinulat ito ni J. Cruz sa bayan.",
+			want: []string{"Isinulat ito ni J. Cruz sa bayan."},
+		},
+		{
+			name: "straight quotes",
+			text: `Sinabi niya, "Humayo ka. Huwag kang matakot." At siya'y humayo.`,
+			want: []string{`Sinabi niya, "Humayo ka. Huwag kang matakot."`, "At siya'y humayo."},
+		},
+		{
+			name: "curly quotes",
+			text: "Sumagot siya, “Narito ako. Suguin mo ako.” Kaya siya ay sinugo.",
+			want: []string{"Sumagot siya, “Narito ako. Suguin mo ako.”", "Kaya siya ay sinugo."},
+		},
+		{
+			name: "contractions inside curly quotes",
+			text: "Sinabi niya, “Ako’y Diyos. Kayo’y aking bayan.” At umalis siya.",
+			want: []string{"Sinabi niya, “Ako’y Diyos. Kayo’y aking bayan.”", "At umalis siya."},
+		},
+		{
+			name: "single quotes inside double quotes",
+			text: "Sinabi niya, “Sinabi nila, ‘Siya’y hari. Sundin siya.’ Hindi ito totoo.” Umalis sila.",
+			want: []string{"Sinabi niya, “Sinabi nila, ‘Siya’y hari. Sundin siya.’ Hindi ito totoo.”", "Umalis sila."},
+		},
+		{
+			name: "parentheses",
+			text: "Ang lupain (Ito ay mabuti. Ito ay malawak.) ay ibinigay sa kanila. Sila ay nagpasalamat.",
+			want: []string{"Ang lupain (Ito ay mabuti. Ito ay malawak.) ay ibinigay sa kanila.", "Sila ay nagpasalamat."},
+		},
+		{
+			name: "quote left open by the verse",
+			text: "At sinabi niya, “Humayo ka. Huwag kang matakot.",
+			want: []string{"At sinabi niya, “Humayo ka.", "Huwag kang matakot."},
+		},
+		{
+			name: "semicolon",
+			text: "Siya ay umalis; Siya ay bumalik.",
+			want: []string{"Siya ay umalis; Siya ay bumalik."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := segmenter.Segment(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Segment(%q)\n= %q\nwant %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrainPunkt(t *testing.T) {
+	var texts []string
+	for range 8 {
+		texts = append(texts,
+			"Ang talaan Blg. 5 ay nasa aklat ng mga hari. Sila ay umuwi sa kanilang bayan.",
+			"Ang mga hari ay umuwi sa bayan. Binilang nila ang mga tao sa bukid.",
+			"Isinulat ito sa Blg. 7 ng talaan ng mga saserdote. Ang mga saserdote ay nasa bayan ng hari.",
+		)
+	}
+
+	model := TrainPunkt(slices.Values(texts))
+	if !slices.Equal(model.Abbreviations, []string{"blg."}) {
+		t.Errorf("learned abbreviations %q, want [blg.]", model.Abbreviations)
+	}
+
+	text := "Tingnan ang Blg. Siyam sa talaan. Ito ay nasa aklat."
+	if got := NewRuleSegmenter(DefaultAbbreviations).Segment(text); len(got) != 3 {
+		t.Errorf("untrained rules split %q into %q, want 3 sentences", text, got)
+	}
+	want := []string{"Tingnan ang Blg. Siyam sa talaan.", "Ito ay nasa aklat."}
+	if got := model.Segmenter(DefaultAbbreviations).Segment(text); !slices.Equal(got, want) {
+		t.Errorf("Punkt split %q into %q, want %q", text, got, want)
+	}
+}
@@ -5,71 +5,28 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
-)
-
-// extractSentences splits the verse text into sentences
-func extractSentencesWithVerse(text, verseID string) []string {
-	reSentence := regexp.MustCompile(`([;.?!])\s+`)
-	reNormalize := regexp.MustCompile(`\s+`)
-
-	text = reNormalize.ReplaceAllString(text, " ")
-	matches := reSentence.FindAllStringIndex(text, -1)
-
-	var sentences []string
-	lastEnd := 0
-
-	for _, match := range matches {
-		end := match[1]
-		s := strings.TrimSpace(text[lastEnd:end])
-		if s != "" {
-			sentences = append(sentences, fmt.Sprintf("%s\t%s", verseID, s))
-		}
-		lastEnd = end
-	}
-
-	// remaining tail
-	remaining := strings.TrimSpace(text[lastEnd:])
-	if remaining != "" {
-		sentences = append(sentences, fmt.Sprintf("%s\t%s", verseID, remaining))
-	}
-
-	return sentences
-}
 
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
 
-func mergeOrAppend(sentences []string, remaining string) []string {
-	lastSentenceIndex := len(sentences) - 1
-	if lastSentenceIndex >= 0 && !strings.HasSuffix(sentences[lastSentenceIndex], ".") &&
-		!strings.HasSuffix(sentences[lastSentenceIndex], "?") &&
-		!strings.HasSuffix(sentences[lastSentenceIndex], "!") {
-		sentences[lastSentenceIndex] += " " + remaining
-	} else {
-		sentences = append(sentences, remaining)
-	}
-	return sentences
-}
-
-// SplitCorpusBySentence walks through files and processes each one
-func SplitCorpusBySentence(root, outRoot string) error {
+// SplitCorpusBySentence walks through files and splits the verses of each one with the segmenter
+func SplitCorpusBySentence(root, outRoot string, segmenter Segmenter) error {
 	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil || d.IsDir() {
 			return err
 		}
 
-		return processFile(path, root, outRoot)
+		return processFile(path, root, outRoot, segmenter)
 	})
 }
-func processFile(path, root, outRoot string) error {
-	data, err := os.ReadFile(path)
+func processFile(path, root, outRoot string, segmenter Segmenter) error {
+	verses, err := types.ReadChapterFile(path)
 	if err != nil {
 		return err
 	}
 	fmt.Println("Processing: ", path)
 
-	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
-
 	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 
 	parts := strings.Split(base, "_")
@@ -80,11 +37,11 @@ func processFile(path, root, outRoot string) error {
 	
 	var sentences []string
 
-	for i, verseText := range lines {
-		verseNum := fmt.Sprintf("%03d", i+1)
-		verseID := fmt.Sprintf("%s", verseNum)
-		verseSentences := extractSentencesWithVerse(verseText, verseID)
-		sentences = append(sentences, verseSentences...)
+	for _, verse := range verses {
+		verseID := verse.Number.String()
+		for _, sentence := range segmenter.Segment(verse.Text) {
+			sentences = append(sentences, fmt.Sprintf("%s\t%s", verseID, sentence))
+		}
 	}
 
 	outPath, err := makeOutputPath(path, root, outRoot)
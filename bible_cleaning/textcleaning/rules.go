@@ -0,0 +1,142 @@
+package textcleaning
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+)
+
+// Scope says which text a rule sees.
+type Scope string
+
+const (
+	ScopeSpan  Scope = "span"  // every content span of a verse, before they are joined
+	ScopeVerse Scope = "verse" // the joined text of a verse
+)
+
+// RuleSpec is a cleaning rule as written in a rule file.
+type RuleSpec struct {
+	Name      string   `json:"name"`
+	Note      string   `json:"note"`      // why the rule exists
+	Find      string   `json:"find"`      // Go regexp
+	Replace   string   `json:"replace"`   // literal text; $1 or ${name} insert a submatch
+	Scope     Scope    `json:"scope"`     // "span" (default) or "verse"
+	Languages []string `json:"languages"` // ISO codes the rule runs for, every language if empty
+	Disabled  bool     `json:"disabled"`
+}
+
+// Rule is a compiled cleaning rule.
+type Rule struct {
+	Name      string
+	Find      *regexp.Regexp
+	Replace   string
+	Scope     Scope
+	Languages []string
+}
+
+// Compile checks a rule spec and compiles its pattern.
+func (spec RuleSpec) Compile() (*Rule, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("rule %q has no name", spec.Find)
+	}
+
+	scope := spec.Scope
+	if scope == "" {
+		scope = ScopeSpan
+	}
+	if scope != ScopeSpan && scope != ScopeVerse {
+		return nil, fmt.Errorf("rule %s: unknown scope %q", spec.Name, spec.Scope)
+	}
+
+	find, err := regexp.Compile(spec.Find)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", spec.Name, err)
+	}
+
+	return &Rule{
+		Name:      spec.Name,
+		Find:      find,
+		Replace:   spec.Replace,
+		Scope:     scope,
+		Languages: spec.Languages,
+	}, nil
+}
+
+// AppliesTo reports whether the rule runs for a language.
+func (r *Rule) AppliesTo(language string) bool {
+	return len(r.Languages) == 0 || slices.Contains(r.Languages, language)
+}
+
+// Apply runs the rule over text and returns the result and the number of matches.
+func (r *Rule) Apply(text string) (string, int) {
+	hits := len(r.Find.FindAllStringIndex(text, -1))
+	if hits == 0 {
+		return text, 0
+	}
+	return r.Find.ReplaceAllString(text, r.Replace), hits
+}
+
+// CompileRules compiles rule specs in order, skipping disabled ones.
+func CompileRules(specs []RuleSpec) ([]*Rule, error) {
+	var rules []*Rule
+	seen := make(map[string]bool)
+
+	for _, spec := range specs {
+		if spec.Disabled {
+			continue
+		}
+
+		rule, err := spec.Compile()
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("rule %s is defined twice", rule.Name)
+		}
+		seen[rule.Name] = true
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+/*
+LoadRules reads an ordered rule file:
+
+	{"rules": [{"name": "...", "find": "...", "replace": "...", "scope": "span", "languages": ["tgl"]}]}
+
+Rules run in file order, each on the output of the previous one.
+*/
+func LoadRules(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cleaning rules: %w", err)
+	}
+
+	var file struct {
+		Rules []RuleSpec `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse cleaning rules %s: %w", path, err)
+	}
+
+	rules, err := CompileRules(file.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cleaning rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ForLanguage keeps the rules that run for a language, in order.
+func ForLanguage(rules []*Rule, language string) []*Rule {
+	var out []*Rule
+	for _, r := range rules {
+		if r.AppliesTo(language) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package textcleaning
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func compile(t *testing.T, specs ...RuleSpec) []*Rule {
+	t.Helper()
+
+	rules, err := CompileRules(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func ruleNames(rules []*Rule) []string {
+	var names []string
+	for _, r := range rules {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestRulesRunInOrder(t *testing.T) {
+	toB := RuleSpec{Name: "a-to-b", Find: "a", Replace: "b"}
+	toC := RuleSpec{Name: "b-to-c", Find: "b", Replace: "c"}
+
+	tests := []struct {
+		specs []RuleSpec
+		want  string
+	}{
+		{[]RuleSpec{toB, toC}, "ccc"}, // the second rule sees the output of the first
+		{[]RuleSpec{toC, toB}, "bbc"},
+	}
+	for _, tt := range tests {
+		cleaner := &Cleaner{Rules: compile(t, tt.specs...)}
+		if got := cleaner.CleanSpan("aab"); got != tt.want {
+			t.Errorf("rules %v: %q, want %q", ruleNames(cleaner.Rules), got, tt.want)
+		}
+	}
+}
+
+func TestRuleScope(t *testing.T) {
+	cleaner := &Cleaner{Rules: compile(t,
+		RuleSpec{Name: "trim-colons", Find: `[:\s]+$`},
+		RuleSpec{Name: "join-lord", Find: `\bL\s(ORD)\b`, Replace: "L$1", Scope: ScopeVerse},
+	)}
+
+	// span rules run on every span and not on the verse; verse rules the other way round
+	if got := cleaner.CleanSpan("ang L ORD:: "); got != "ang L ORD" {
+		t.Errorf("CleanSpan = %q, want the colons trimmed and L ORD left", got)
+	}
+	if got := cleaner.CleanVerse("ang L ORD::"); got != "ang LORD::" {
+		t.Errorf("CleanVerse = %q, want L ORD joined and the colons left", got)
+	}
+
+	// a verse rule sees the spans joined, so it mends a word split across them
+	spans := []string{cleaner.CleanSpan("Sinabi ng L:"), cleaner.CleanSpan("ORD")}
+	if got := cleaner.CleanVerse(strings.Join(spans, " ")); got != "Sinabi ng LORD" {
+		t.Errorf("verse of %q = %q, want Sinabi ng LORD", spans, got)
+	}
+}
+
+func TestRuleLanguages(t *testing.T) {
+	rules := compile(t,
+		RuleSpec{Name: "everyone", Find: "x"},
+		RuleSpec{Name: "tagalog", Find: "x", Languages: []string{"tgl"}},
+		RuleSpec{Name: "visayan", Find: "x", Languages: []string{"ceb", "war"}},
+		RuleSpec{Name: "off", Find: "x", Disabled: true},
+	)
+
+	tests := []struct {
+		language string
+		want     []string
+	}{
+		{"tgl", []string{"everyone", "tagalog"}},
+		{"war", []string{"everyone", "visayan"}},
+		{"ilo", []string{"everyone"}},
+	}
+	for _, tt := range tests {
+		if got := ruleNames(ForLanguage(rules, tt.language)); !slices.Equal(got, tt.want) {
+			t.Errorf("rules for %s %v, want %v", tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestRuleBackreferences(t *testing.T) {
+	tests := []struct {
+		spec RuleSpec
+		in   string
+		want string
+		hits int
+	}{
+		{RuleSpec{Name: "numbered", Find: `\b([Hh])\s(e)\b`, Replace: "$1$2"}, "H e said, and h e went", "He said, and he went", 2},
+		{RuleSpec{Name: "named", Find: `(?P<word>\w+)-(?P<word2>\w+)`, Replace: "${word2} ${word}"}, "isa-dalawa", "dalawa isa", 1},
+		{RuleSpec{Name: "literal", Find: `\$`, Replace: "$$"}, "$5", "$5", 1},
+		{RuleSpec{Name: "no-match", Find: `¶`}, "Ang aklat", "Ang aklat", 0},
+	}
+	for _, tt := range tests {
+		rule := compile(t, tt.spec)[0]
+		if got, hits := rule.Apply(tt.in); got != tt.want || hits != tt.hits {
+			t.Errorf("%s.Apply(%q) = %q, %d hits; want %q, %d", tt.spec.Name, tt.in, got, hits, tt.want, tt.hits)
+		}
+	}
+}
+
+func TestCompileRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []RuleSpec
+		err   string
+	}{
+		{"no name", []RuleSpec{{Find: "x"}}, "has no name"},
+		{"unknown scope", []RuleSpec{{Name: "r", Find: "x", Scope: "chapter"}}, `unknown scope "chapter"`},
+		{"bad pattern", []RuleSpec{{Name: "r", Find: "[("}}, "rule r:"},
+		{"defined twice", []RuleSpec{{Name: "r", Find: "x"}, {Name: "r", Find: "y"}}, "defined twice"},
+	}
+	for _, tt := range tests {
+		if _, err := CompileRules(tt.specs); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+
+	// a disabled rule is neither compiled nor counted as defined
+	if rules, err := CompileRules([]RuleSpec{{Name: "r", Find: "[(", Disabled: true}, {Name: "r", Find: "x"}}); err != nil || len(rules) != 1 {
+		t.Errorf("disabled rule: %d rules, error %v; want the enabled one alone", len(rules), err)
+	}
+}
+
+func TestRepositoryRules(t *testing.T) {
+	rules, err := LoadRules(filepath.Join("..", "cleaning_rules.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleaner := &Cleaner{Language: "tgl", Rules: ForLanguage(rules, "tgl")}
+
+	got, steps := cleaner.Trace("3 The L ORD said: ")
+	if got != "The LORD said" {
+		t.Errorf("Trace = %q, want The LORD said", got)
+	}
+	want := []string{"trim-trailing-colons", "strip-leading-verse-marks", "join-small-caps-lord"}
+	var applied []string
+	for _, step := range steps {
+		applied = append(applied, step.Rule)
+	}
+	if !slices.Equal(applied, want) {
+		t.Errorf("steps %v, want %v in file order", applied, want)
+	}
+}
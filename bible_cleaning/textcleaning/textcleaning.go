@@ -0,0 +1,256 @@
+package textcleaning
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/unicode/runenames"
+)
+
+/*
+DefaultPunctuation is the punctuation every language keeps: the ASCII marks
+the old ASCII-only filter allowed, their typographic forms (curly quotes,
+en and em dashes), parentheses and brackets, which translations use for
+asides and supplied words, and the grave, acute and circumflex accents some
+orthographies write on their own to mark a glottal stop.
+*/
+const DefaultPunctuation = `.,;:!?'"-()[]` + "‘’“”–—`´^"
+
+// CharSet decides which characters of a language survive cleaning.
+type CharSet struct {
+	Scripts []*unicode.RangeTable // scripts whose letters are kept, Latin by default
+	Extra   map[rune]bool         // punctuation and symbols kept besides letters, marks, digits and spaces
+}
+
+/*
+NewCharSet builds the allowed set of a language from Unicode script names
+(e.g. "Latin") and extra characters kept on top of DefaultPunctuation.
+*/
+func NewCharSet(scripts []string, extra string) (*CharSet, error) {
+	if len(scripts) == 0 {
+		scripts = []string{"Latin"}
+	}
+
+	cs := &CharSet{Extra: make(map[rune]bool)}
+	for _, name := range scripts {
+		table, ok := unicode.Scripts[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown Unicode script %q", name)
+		}
+		cs.Scripts = append(cs.Scripts, table)
+	}
+
+	for _, r := range DefaultPunctuation + extra {
+		cs.Extra[r] = true
+	}
+	return cs, nil
+}
+
+// Allows reports whether r is kept.
+func (cs *CharSet) Allows(r rune) bool {
+	switch {
+	case unicode.IsSpace(r), unicode.IsDigit(r), unicode.IsMark(r), cs.Extra[r]:
+		return true
+	case unicode.IsLetter(r):
+		return unicode.In(r, cs.Scripts...)
+	}
+	return false
+}
+
+/*
+Cleaner is the cleaning pipeline of one language. Every content span is NFC
+normalized, run through the character filter and then the span rules; the
+joined verse then goes through the verse rules. Rules run in order.
+*/
+type Cleaner struct {
+	Language string
+	Allowed  *CharSet // nil keeps every character
+	Rules    []*Rule
+	Dropped  *DropReport // nil does not record dropped characters
+}
+
+// Step is one change the pipeline made to a text.
+type Step struct {
+	Rule   string
+	Hits   int
+	Before string
+	After  string
+}
+
+// CleanSpan cleans one content span of a verse.
+func (c *Cleaner) CleanSpan(text string) string {
+	return c.cleanSpan(text, nil)
+}
+
+// CleanVerse cleans the joined text of a verse.
+func (c *Cleaner) CleanVerse(text string) string {
+	return c.applyRules(text, ScopeVerse, nil)
+}
+
+// Trace cleans text as a single-span verse and returns every step that changed it.
+func (c *Cleaner) Trace(text string) (string, []Step) {
+	var steps []Step
+	record := func(step Step) { steps = append(steps, step) }
+
+	text = c.cleanSpan(text, record)
+	text = c.applyRules(text, ScopeVerse, record)
+	return text, steps
+}
+
+func (c *Cleaner) cleanSpan(text string, record func(Step)) string {
+	normalized := norm.NFC.String(text)
+	if record != nil && normalized != text {
+		record(Step{Rule: "nfc", Hits: 1, Before: text, After: normalized})
+	}
+	text = normalized
+
+	if c.Allowed != nil {
+		dropped := 0
+		filtered := strings.Map(func(r rune) rune {
+			if c.Allowed.Allows(r) {
+				return r
+			}
+			c.Dropped.Add(c.Language, r)
+			dropped++
+			return -1
+		}, text)
+
+		if record != nil && dropped > 0 {
+			record(Step{Rule: "charset", Hits: dropped, Before: text, After: filtered})
+		}
+		text = filtered
+	}
+
+	return c.applyRules(text, ScopeSpan, record)
+}
+
+func (c *Cleaner) applyRules(text string, scope Scope, record func(Step)) string {
+	for _, rule := range c.Rules {
+		if rule.Scope != scope {
+			continue
+		}
+
+		cleaned, hits := rule.Apply(text)
+		if record != nil && hits > 0 {
+			record(Step{Rule: rule.Name, Hits: hits, Before: text, After: cleaned})
+		}
+		text = cleaned
+	}
+	return text
+}
+
+// DropReport counts the characters the filter removed, per language.
+type DropReport struct {
+	mu     sync.Mutex
+	counts map[string]map[rune]int
+}
+
+func NewDropReport() *DropReport {
+	return &DropReport{counts: make(map[string]map[rune]int)}
+}
+
+// Add counts one dropped character.
+func (dr *DropReport) Add(language string, r rune) {
+	if dr == nil {
+		return
+	}
+
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	if dr.counts[language] == nil {
+		dr.counts[language] = make(map[rune]int)
+	}
+	dr.counts[language][r]++
+}
+
+// DroppedCharacter is a character removed from a language and how often.
+type DroppedCharacter struct {
+	Language string
+	Char     rune
+	Count    int
+}
+
+// Dropped returns the dropped characters by language, most frequent first.
+func (dr *DropReport) Dropped() []DroppedCharacter {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	var out []DroppedCharacter
+	for language, counts := range dr.counts {
+		for r, n := range counts {
+			out = append(out, DroppedCharacter{Language: language, Char: r, Count: n})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Language != out[j].Language {
+			return out[i].Language < out[j].Language
+		}
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Char < out[j].Char
+	})
+	return out
+}
+
+// Summarize prints how many characters each language lost and saves the full list as a TSV at path.
+func (dr *DropReport) Summarize(path string) error {
+	dropped := dr.Dropped()
+
+	if len(dropped) == 0 {
+		fmt.Println("No characters were dropped during cleaning.")
+		return nil
+	}
+
+	totals := make(map[string]int)
+	kinds := make(map[string]int)
+	for _, d := range dropped {
+		totals[d.Language] += d.Count
+		kinds[d.Language]++
+	}
+
+	languages := make([]string, 0, len(totals))
+	for language := range totals {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	fmt.Println("Characters dropped during cleaning:")
+	for _, language := range languages {
+		fmt.Printf("  %s : %d characters (%d distinct)\n", language, totals[language], kinds[language])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create dropped character report: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintln(file, "language\tchar\tcodepoint\tname\tcount"); err != nil {
+		return err
+	}
+	for _, d := range dropped {
+		char := string(d.Char)
+		if !unicode.IsPrint(d.Char) || d.Char == '\t' {
+			char = ""
+		}
+		if _, err := fmt.Fprintf(file, "%s\t%s\t%U\t%s\t%d\n", d.Language, char, d.Char, runenames.Name(d.Char), d.Count); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Saved dropped characters to %s\n", path)
+	return nil
+}
@@ -0,0 +1,127 @@
+package textcleaning
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCharSetAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		scripts []string
+		extra   string
+		allowed string
+		dropped string
+	}{
+		{
+			name:    "Latin by default",
+			allowed: "aZñÑéü 09\t.,;:!?'\"-()[]‘’“”–—`´^\u0301", // and a combining acute
+			dropped: "ᜀαж¶©@*/{}<>",
+		},
+		{
+			name:    "Latin and Baybayin",
+			scripts: []string{"Latin", "Tagalog"},
+			allowed: "aᜀᜁᜋ᜔",
+			dropped: "αж",
+		},
+		{
+			name:    "Greek only",
+			scripts: []string{"Greek"},
+			allowed: "αΩ.(",
+			dropped: "aᜀ",
+		},
+		{
+			name:    "extra characters",
+			extra:   "@/",
+			allowed: "a@/",
+			dropped: "*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := NewCharSet(tt.scripts, tt.extra)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range tt.allowed {
+				if !cs.Allows(r) {
+					t.Errorf("%q (%U) dropped, want it kept", r, r)
+				}
+			}
+			for _, r := range tt.dropped {
+				if cs.Allows(r) {
+					t.Errorf("%q (%U) kept, want it dropped", r, r)
+				}
+			}
+		})
+	}
+
+	if _, err := NewCharSet([]string{"Baybayin"}, ""); err == nil {
+		t.Error("NewCharSet(Baybayin) made a set, want an unknown script error")
+	}
+}
+
+func TestCleanerNormalizesNFC(t *testing.T) {
+	latin, err := NewCharSet(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleaner := &Cleaner{Language: "tgl", Allowed: latin}
+
+	// decomposed ñ and á, as some pages send them
+	decomposed := "Si Sen\u0303or ay nagsalita\u0301."
+	want := "Si Señor ay nagsalitá."
+	if got := cleaner.CleanSpan(decomposed); got != want || len(got) != len(want) {
+		t.Errorf("CleanSpan(%q) = %q, want the composed %q", decomposed, got, want)
+	}
+
+	_, steps := cleaner.Trace(decomposed)
+	if len(steps) != 1 || steps[0].Rule != "nfc" {
+		t.Errorf("steps %+v, want the nfc step alone", steps)
+	}
+	if _, steps := cleaner.Trace(want); len(steps) != 0 {
+		t.Errorf("steps %+v of composed text, want none", steps)
+	}
+}
+
+func TestDropReport(t *testing.T) {
+	latin, err := NewCharSet(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := NewDropReport()
+	tagalog := &Cleaner{Language: "tgl", Allowed: latin, Dropped: report}
+	cebuano := &Cleaner{Language: "ceb", Allowed: latin, Dropped: report}
+
+	if got, want := tagalog.CleanSpan("¶ Ang aklat ©1905 (ABTAG) ¶"), " Ang aklat 1905 (ABTAG) "; got != want {
+		t.Errorf("CleanSpan = %q, want %q", got, want)
+	}
+	cebuano.CleanSpan("Ang libro * sa Dios")
+	(*DropReport)(nil).Add("tgl", '¶') // a nil report records nothing
+
+	want := []DroppedCharacter{
+		{Language: "ceb", Char: '*', Count: 1},
+		{Language: "tgl", Char: '¶', Count: 2},
+		{Language: "tgl", Char: '©', Count: 1},
+	}
+	if got := report.Dropped(); !slices.Equal(got, want) {
+		t.Errorf("dropped %+v, want %+v", got, want)
+	}
+
+	path := filepath.Join(t.TempDir(), "dropped_characters.tsv")
+	if err := report.Summarize(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 || lines[2] != "tgl\t¶\tU+00B6\tPILCROW SIGN\t2" {
+		t.Errorf("report\n%s\nwant a header and a row per character", data)
+	}
+}
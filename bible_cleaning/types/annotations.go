@@ -0,0 +1,85 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MarshalText stores a verse number the way the chapter files do, e.g. "003-004".
+func (vn VerseNumber) MarshalText() ([]byte, error) {
+	return []byte(vn.String()), nil
+}
+
+func (vn *VerseNumber) UnmarshalText(text []byte) error {
+	number, err := ParseVerseNumber(string(text))
+	if err != nil {
+		return err
+	}
+	*vn = number
+	return nil
+}
+
+// CrossReference is a cross-reference note and the verses it points to.
+type CrossReference struct {
+	Text    string   `json:"text"`              // as printed, e.g. "Juan 1:1-3; Heb. 11:3"
+	Targets []string `json:"targets,omitempty"` // USFM references, e.g. "JHN.1.1"
+}
+
+// VerseAnnotations is the markup of a verse that is kept out of its clean text.
+type VerseAnnotations struct {
+	Verse           VerseNumber      `json:"verse"`
+	Headings        []string         `json:"headings,omitempty"` // section headings printed before the verse
+	Footnotes       []string         `json:"footnotes,omitempty"`
+	CrossReferences []CrossReference `json:"cross_references,omitempty"`
+	WordsOfJesus    []string         `json:"words_of_jesus,omitempty"` // red-letter parts of the verse text
+}
+
+// Empty reports whether the verse has no annotations.
+func (va *VerseAnnotations) Empty() bool {
+	return len(va.Headings) == 0 && len(va.Footnotes) == 0 && len(va.CrossReferences) == 0 && len(va.WordsOfJesus) == 0
+}
+
+// ChapterAnnotations is the sidecar of one chapter file: where and when it was
+// scraped, and the annotations of its verses.
+type ChapterAnnotations struct {
+	Language  string             `json:"language"`
+	Code      string             `json:"code"` // USFM book and chapter, e.g. "GEN.1"
+	URL       string             `json:"url"`
+	ScrapedAt time.Time          `json:"scraped_at"`
+	Verses    []VerseAnnotations `json:"verses"`
+}
+
+// WriteAnnotationFile saves the annotations of a chapter as JSON.
+func WriteAnnotationFile(path string, annotations ChapterAnnotations) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(annotations, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write annotation file: %w", err)
+	}
+	return nil
+}
+
+// ReadAnnotationFile loads the annotations saved by WriteAnnotationFile.
+func ReadAnnotationFile(path string) (ChapterAnnotations, error) {
+	var annotations ChapterAnnotations
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return annotations, err
+	}
+
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return annotations, fmt.Errorf("failed to parse annotation file %s: %w", path, err)
+	}
+	return annotations, nil
+}
@@ -0,0 +1,91 @@
+package types
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zrygan.nlp/bible_cleaning/canon"
+)
+
+/*
+PairID identifies a pair of a parallel corpus: "BOOK_CCC_VVV" for a verse
+pair, "BOOK_CCC_VVV_NNN" for the NNN-th sentence pair aligned within the
+verse. The verse is a span ("003-004") where either translation merges
+verses, so every pair of a chapter has an ID of its own.
+*/
+type PairID struct {
+	Book     string // USFM book code
+	Chapter  int
+	Verse    VerseNumber // verse, or span of verses, the pair is aligned within
+	Sentence int         // 1-based number of the sentence pair in the verse, 0 for verse pairs
+}
+
+func (id PairID) String() string {
+	s := fmt.Sprintf("%s_%03d_%s", id.Book, id.Chapter, id.Verse)
+	if id.Sentence > 0 {
+		s += fmt.Sprintf("_%03d", id.Sentence)
+	}
+	return s
+}
+
+// ChapterLabel is the chapter as written in IDs and in the chapter column, e.g. "001".
+func (id PairID) ChapterLabel() string {
+	return fmt.Sprintf("%03d", id.Chapter)
+}
+
+// SentenceLabel is the sentence number as written in IDs, or "" for verse pairs.
+func (id PairID) SentenceLabel() string {
+	if id.Sentence == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%03d", id.Sentence)
+}
+
+// ParsePairID reads an ID written by PairID.String.
+func ParsePairID(s string) (PairID, error) {
+	parts := strings.Split(s, "_")
+	if len(parts) != 3 && len(parts) != 4 {
+		return PairID{}, fmt.Errorf("invalid pair ID %q", s)
+	}
+
+	chapter, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return PairID{}, fmt.Errorf("invalid chapter in pair ID %q", s)
+	}
+
+	verse, err := ParseVerseNumber(parts[2])
+	if err != nil {
+		return PairID{}, fmt.Errorf("invalid verse in pair ID %q", s)
+	}
+
+	id := PairID{Book: parts[0], Chapter: chapter, Verse: verse}
+	if len(parts) == 4 {
+		id.Sentence, err = strconv.Atoi(parts[3])
+		if err != nil || id.Sentence < 1 {
+			return PairID{}, fmt.Errorf("invalid sentence in pair ID %q", s)
+		}
+	}
+	return id, nil
+}
+
+// bookOrder sorts the books of the canon in canonical order and any other book after them.
+func bookOrder(code string) int {
+	if pos := canon.Position(code); pos >= 0 {
+		return pos
+	}
+	return len(canon.Books)
+}
+
+// Compare orders IDs by canonical book order, chapter, verse and sentence.
+func (id PairID) Compare(other PairID) int {
+	return cmp.Or(
+		cmp.Compare(bookOrder(id.Book), bookOrder(other.Book)),
+		cmp.Compare(id.Book, other.Book),
+		cmp.Compare(id.Chapter, other.Chapter),
+		cmp.Compare(id.Verse.Start, other.Verse.Start),
+		cmp.Compare(id.Verse.End, other.Verse.End),
+		cmp.Compare(id.Sentence, other.Sentence),
+	)
+}
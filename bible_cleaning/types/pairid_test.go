@@ -0,0 +1,87 @@
+package types
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestPairIDRoundTrip(t *testing.T) {
+	tests := []struct {
+		s  string
+		id PairID
+	}{
+		{"GEN_001_001", PairID{Book: "GEN", Chapter: 1, Verse: VerseNumber{1, 1}}},
+		{"PSA_119_176", PairID{Book: "PSA", Chapter: 119, Verse: VerseNumber{176, 176}}},
+		{"GEN_002_003-004", PairID{Book: "GEN", Chapter: 2, Verse: VerseNumber{3, 4}}},
+		{"1CO_013_004_002", PairID{Book: "1CO", Chapter: 13, Verse: VerseNumber{4, 4}, Sentence: 2}},
+		{"JHN_003_016-017_010", PairID{Book: "JHN", Chapter: 3, Verse: VerseNumber{16, 17}, Sentence: 10}},
+	}
+
+	for _, tt := range tests {
+		id, err := ParsePairID(tt.s)
+		if err != nil {
+			t.Errorf("ParsePairID(%q): %v", tt.s, err)
+			continue
+		}
+		if id != tt.id {
+			t.Errorf("ParsePairID(%q) = %+v, want %+v", tt.s, id, tt.id)
+		}
+		if s := tt.id.String(); s != tt.s {
+			t.Errorf("%+v.String() = %q, want %q", tt.id, s, tt.s)
+		}
+	}
+}
+
+func TestParsePairIDInvalid(t *testing.T) {
+	for _, s := range []string{"", "GEN", "GEN_001", "GEN_abc_001", "GEN_001_x", "GEN_001_004-003", "GEN_001_001_000", "GEN_001_001_001_001"} {
+		if id, err := ParsePairID(s); err == nil {
+			t.Errorf("ParsePairID(%q) = %+v, want an error", s, id)
+		}
+	}
+}
+
+func TestPairIDCompare(t *testing.T) {
+	// canonical book order, then chapter, verse start, span end and sentence
+	want := []string{
+		"GEN_001_001",
+		"GEN_001_002",
+		"GEN_001_002_001",
+		"GEN_001_002_002",
+		"GEN_001_003",
+		"GEN_001_003-004",
+		"GEN_001_003-004_001",
+		"GEN_001_004",
+		"GEN_001_010",
+		"GEN_002_001",
+		"GEN_010_001",
+		"EXO_001_001",
+		"MAT_001_001",
+		"1CO_001_001",
+		"REV_022_021",
+		"XYZ_001_001",
+	}
+
+	ids := make([]PairID, len(want))
+	for i, s := range want {
+		id, err := ParsePairID(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 5 {
+		r.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		slices.SortFunc(ids, PairID.Compare)
+
+		got := make([]string, len(ids))
+		for i, id := range ids {
+			got[i] = id.String()
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("sorted\n%q\nwant\n%q", got, want)
+		}
+	}
+}
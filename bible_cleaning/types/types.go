@@ -4,32 +4,17 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 	"github.com/zrygan.nlp/bible_cleaning/config"
 )
 
-type FindReplaceTuple[T string | *regexp.Regexp] struct {
-	Find    T
-	Replace T
-}
-
-func TurnToRegexpsTuple(tuples []FindReplaceTuple[string]) []FindReplaceTuple[*regexp.Regexp] {
-	var result []FindReplaceTuple[*regexp.Regexp]
-
-	for _, t := range tuples {
-		result = append(result, FindReplaceTuple[*regexp.Regexp]{
-			Find:    regexp.MustCompile(t.Find),
-			Replace: regexp.MustCompile(t.Replace),
-		})
-	}
-
-	return result
-}
 type VerseRef struct {
 	book string
 	chapter string
@@ -38,6 +23,7 @@ type VerseRef struct {
 type LanguageClass struct {
 	Language string
 	OutputDir string
+	AnnotationDir string // where chapter annotation sidecars go, empty to skip them
 }
 
 
@@ -58,6 +44,9 @@ type TextPair struct {
 	Chapter    string `json:"chapter,omitempty"`
 	Verse 	   string `json:"verse,omitempty"`
 	Sentence   string `json:"sentence,omitempty"`
+	Merge       string   `json:"merge,omitempty"`        // sentences merged per side, e.g. "2-1", for sentence pairs
+	Score       *float64 `json:"score,omitempty"`        // alignment confidence, from 0 to 1; nil for unscored verse pairs
+	LengthRatio float64  `json:"length_ratio,omitempty"` // shorter over longer side, in characters
 }
 
 
@@ -110,9 +99,54 @@ func LoadParallelCorpus(sourcePath, targetPath, sourceLang, targetLang string) (
 	return corpus, nil
 }
 
+// NewTextPair labels a pair with its ID and the book, chapter, verse and sentence the ID is made of.
+func NewTextPair(id PairID, source, target string) TextPair {
+	return TextPair{
+		SourceText: source,
+		TargetText: target,
+		ID:         id.String(),
+		Book:       id.Book,
+		Chapter:    id.ChapterLabel(),
+		Verse:      id.Verse.String(),
+		Sentence:   id.SentenceLabel(),
+	}
+}
+
+// SetScore records the alignment confidence of the pair; a score of 0 is kept apart from no score.
+func (tp *TextPair) SetScore(score float64) {
+	tp.Score = &score
+}
+
+// ScoreLabel formats the score for a TSV column, empty for an unscored pair.
+func (tp TextPair) ScoreLabel() string {
+	if tp.Score == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*tp.Score, 'f', 4, 64)
+}
+
+/*
+Sort orders the pairs by PairID: canonical book order, chapter, verse and
+sentence. Pairs whose IDs are not PairIDs come last, by ID.
+*/
 func (pc TextPairArray) Sort() {
-	sort.Slice(pc, func(i, j int) bool {
-		return pc[i].ID < pc[j].ID
+	ids := make(map[string]PairID, len(pc))
+	for _, pair := range pc {
+		if id, err := ParsePairID(pair.ID); err == nil {
+			ids[pair.ID] = id
+		}
+	}
+
+	sort.SliceStable(pc, func(i, j int) bool {
+		a, okA := ids[pc[i].ID]
+		b, okB := ids[pc[j].ID]
+		if okA != okB {
+			return okA
+		}
+		if !okA {
+			return pc[i].ID < pc[j].ID
+		}
+		return a.Compare(b) < 0
 	})
 }
 
@@ -192,7 +226,7 @@ func (pc *ParallelCorpusEntry) SaveAsTSVSentences(path string, outDir string) er
 	defer writer.Flush()
 
 	// Write header
-	_, err = writer.WriteString("id\tbook\tchapter\tsentence_no\tsource_text\ttarget_text\n")
+	_, err = writer.WriteString("id\tbook\tchapter\tverse\tsentence_no\tmerge\tscore\tlength_ratio\tsource_text\ttarget_text\n")
 
 	if err != nil {
 		return fmt.Errorf("failed to write header to TSV file: %w", err)
@@ -200,7 +234,7 @@ func (pc *ParallelCorpusEntry) SaveAsTSVSentences(path string, outDir string) er
 
 	// Write each text pair
 	for _, pair := range pc.Pairs {
-		line := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\n", pair.ID, pair.Book, pair.Chapter, pair.Sentence, TransfromEscapeCharTSV(pair.SourceText), TransfromEscapeCharTSV(pair.TargetText))
+		line := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%.4f\t%s\t%s\n", pair.ID, pair.Book, pair.Chapter, pair.Verse, pair.Sentence, pair.Merge, pair.ScoreLabel(), pair.LengthRatio, TransfromEscapeCharTSV(pair.SourceText), TransfromEscapeCharTSV(pair.TargetText))
 		_, err = writer.WriteString(line)
 		if err != nil {
 			return fmt.Errorf("failed to write line to TSV file: %w", err)
@@ -210,6 +244,97 @@ func (pc *ParallelCorpusEntry) SaveAsTSVSentences(path string, outDir string) er
 	return nil
 }
 
+/*
+ReadParallelTSV reads a parallel corpus written by SaveAsTSV or
+SaveAsTSVSentences, taking the languages from its "src_tgt.tsv" file name.
+Columns are found by their header, so files with extra columns still read.
+*/
+func ReadParallelTSV(path string) (*ParallelCorpusEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entry := &ParallelCorpusEntry{}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	entry.SourceLang, entry.TargetLang, _ = strings.Cut(name, "_")
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: missing header", path)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range strings.Split(scanner.Text(), "\t") {
+		columns[column] = i
+	}
+	for _, required := range []string{"id", "source_text", "target_text"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%s: missing column %s", path, required)
+		}
+	}
+
+	lineNum := 1
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < len(columns) {
+			return nil, fmt.Errorf("%s:%d: expected %d columns, found %d", path, lineNum, len(columns), len(fields))
+		}
+
+		field := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return fields[i]
+			}
+			return ""
+		}
+
+		number := func(column string) (float64, error) {
+			if value := field(column); value != "" {
+				return strconv.ParseFloat(value, 64)
+			}
+			return 0, nil
+		}
+
+		var score *float64
+		if field("score") != "" {
+			value, err := number("score")
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid score: %w", path, lineNum, err)
+			}
+			score = &value
+		}
+		lengthRatio, err := number("length_ratio")
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid length ratio: %w", path, lineNum, err)
+		}
+
+		entry.Pairs = append(entry.Pairs, TextPair{
+			SourceText:  RemoveEscapeCharTSV(field("source_text")),
+			TargetText:  RemoveEscapeCharTSV(field("target_text")),
+			ID:          field("id"),
+			Book:        field("book"),
+			Chapter:     field("chapter"),
+			Verse:       field("verse"),
+			Sentence:    field("sentence_no"),
+			Merge:       field("merge"),
+			Score:       score,
+			LengthRatio: lengthRatio,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return entry, nil
+}
+
 // Transform special characters for TSV format
 func TransfromEscapeCharTSV(text string) string {
 	text = strings.ReplaceAll(text, "\t", config.TOKEN_TAB)
@@ -260,24 +385,63 @@ func (pc *ParallelCorpusEntry) Filter(predicate func(TextPair) bool) *ParallelCo
 	return filtered
 }
 
+// Reversed swaps the languages of the entry, and the sides and merge of every pair.
+func (pc *ParallelCorpusEntry) Reversed() *ParallelCorpusEntry {
+	reversed := &ParallelCorpusEntry{
+		SourceLang: pc.TargetLang,
+		TargetLang: pc.SourceLang,
+		Pairs:      make(TextPairArray, len(pc.Pairs)),
+		Metadata:   pc.Metadata,
+	}
+	for i, pair := range pc.Pairs {
+		pair.SourceText, pair.TargetText = pair.TargetText, pair.SourceText
+		if src, tgt, ok := strings.Cut(pair.Merge, "-"); ok {
+			pair.Merge = tgt + "-" + src
+		}
+		reversed.Pairs[i] = pair
+	}
+	return reversed
+}
+
 type ProperNounCache struct {
 	Words map[string]struct{}
 }
 
-func ExtractProperNouns(sentences []string) *ProperNounCache {
-	cache := &ProperNounCache{Words: make(map[string]struct{})}
-	for _, s := range sentences {
-		tokens := strings.Fields (s)
-		for i, token := range tokens {
-			clean := strings.Trim(token, ".,;:!?\"'")
-			if len(clean) == 0 {
-				continue
-			}
+func NewProperNounCache() *ProperNounCache {
+	return &ProperNounCache{Words: make(map[string]struct{})}
+}
 
-			if i > 0 && unicode.IsUpper(rune(clean[0])) {
-				cache.Words[clean] = struct{}{}
-			}
+// Add records the capitalized words of a sentence, skipping the first one.
+func (cache *ProperNounCache) Add(sentence string) {
+	tokens := strings.Fields(sentence)
+	for i, token := range tokens {
+		clean := strings.Trim(token, ".,;:!?\"'")
+		if len(clean) == 0 {
+			continue
+		}
+
+		first, _ := utf8.DecodeRuneInString(clean)
+		if i > 0 && unicode.IsUpper(first) {
+			cache.Words[clean] = struct{}{}
 		}
 	}
+}
+
+func ExtractProperNouns(sentences iter.Seq[string]) *ProperNounCache {
+	cache := NewProperNounCache()
+	for s := range sentences {
+		cache.Add(s)
+	}
 	return cache
-}
\ No newline at end of file
+}
+
+// JoinProperNouns merges the caches of several languages into one, leaving them untouched.
+func JoinProperNouns(caches ...*ProperNounCache) *ProperNounCache {
+	joined := NewProperNounCache()
+	for _, cache := range caches {
+		for word := range cache.Words {
+			joined.Words[word] = struct{}{}
+		}
+	}
+	return joined
+}
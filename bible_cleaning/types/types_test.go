@@ -0,0 +1,65 @@
+package types
+
+import (
+	"encoding/json"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProperNounCacheAdd(t *testing.T) {
+	cache := NewProperNounCache()
+	cache.Add("Ang ábaca ni Ñora ay dinala sa Éfeso, hindi sa ñgayon.")
+
+	got := slices.Sorted(maps.Keys(cache.Words))
+	want := []string{"Éfeso", "Ñora"}
+	if !slices.Equal(got, want) {
+		t.Errorf("proper nouns %q, want %q", got, want)
+	}
+}
+
+func TestTextPairScore(t *testing.T) {
+	unaligned := NewTextPair(PairID{Book: "GEN", Chapter: 1, Verse: VerseNumber{1, 1}, Sentence: 2}, "Nilikha ng Dios.", "<MISSING_TRANSLATION>")
+	unaligned.Merge = "1-0"
+	unaligned.SetScore(0)
+	scored := NewTextPair(PairID{Book: "GEN", Chapter: 1, Verse: VerseNumber{1, 1}, Sentence: 1}, "Nang pasimula.", "Sa sinugdan.")
+	scored.SetScore(0.75)
+	verse := NewTextPair(PairID{Book: "GEN", Chapter: 1, Verse: VerseNumber{2, 2}}, "At ang lupa.", "Ug ang yuta.")
+
+	for _, tt := range []struct {
+		pair TextPair
+		want string
+	}{
+		{unaligned, `"score":0}`},
+		{scored, `"score":0.75}`},
+		{verse, ""},
+	} {
+		data, err := json.Marshal(tt.pair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); (tt.want == "" && strings.Contains(got, "score")) || !strings.Contains(got, tt.want) {
+			t.Errorf("JSON %s, want it to hold %q", got, tt.want)
+		}
+	}
+
+	entry := &ParallelCorpusEntry{SourceLang: "tgl", TargetLang: "ceb", Pairs: TextPairArray{scored, unaligned, verse}}
+	dir := t.TempDir()
+	if err := entry.SaveAsTSVSentences("tgl_ceb.tsv", dir); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadParallelTSV(filepath.Join(dir, "tgl_ceb.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scores []string
+	for _, pair := range read.Pairs {
+		scores = append(scores, pair.ScoreLabel())
+	}
+	if !slices.Equal(scores, []string{"0.7500", "0.0000", ""}) {
+		t.Errorf("scores read back %q, want 0.75, 0 and none", scores)
+	}
+}
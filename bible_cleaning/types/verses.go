@@ -0,0 +1,269 @@
+package types
+
+import (
+	"bufio"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VerseNumber is a verse or, when a translation merges verses, a range of them.
+type VerseNumber struct {
+	Start int
+	End   int // equal to Start for a single verse
+}
+
+var verseNumberPattern = regexp.MustCompile(`^\s*(\d+)[a-z]?\s*(?:[-–—]\s*(\d+)[a-z]?)?\s*$`)
+
+/*
+ParseVerseNumber reads a verse label such as "3", "3-4", "3–4" or "003-004".
+Letter suffixes ("3a") are dropped since the corpus does not split verses.
+*/
+func ParseVerseNumber(label string) (VerseNumber, error) {
+	m := verseNumberPattern.FindStringSubmatch(label)
+	if m == nil {
+		return VerseNumber{}, fmt.Errorf("invalid verse number %q", label)
+	}
+
+	start, _ := strconv.Atoi(m[1])
+	end := start
+	if m[2] != "" {
+		end, _ = strconv.Atoi(m[2])
+	}
+
+	if end < start {
+		return VerseNumber{}, fmt.Errorf("invalid verse range %q", label)
+	}
+	return VerseNumber{Start: start, End: end}, nil
+}
+
+// String formats the number the way the corpus files store it: "003" or "003-004".
+func (vn VerseNumber) String() string {
+	if vn.End <= vn.Start {
+		return fmt.Sprintf("%03d", vn.Start)
+	}
+	return fmt.Sprintf("%03d-%03d", vn.Start, vn.End)
+}
+
+// IsRange reports whether the number covers more than one verse.
+func (vn VerseNumber) IsRange() bool {
+	return vn.End > vn.Start
+}
+
+// Overlaps reports whether two verse numbers share at least one verse.
+func (vn VerseNumber) Overlaps(other VerseNumber) bool {
+	return vn.Start <= other.End && other.Start <= vn.End
+}
+
+// Verse is one numbered verse of a chapter.
+type Verse struct {
+	Number VerseNumber
+	Text   string
+}
+
+// ChapterFile is what a chapter file name says about its contents.
+type ChapterFile struct {
+	Language string // ISO 639-3 code
+	Book     string // USFM book code
+	Name     string // book name as printed in the chapter title, letters only
+	Chapter  int
+}
+
+var chapterFileNamePattern = regexp.MustCompile(`^([a-z]+)_([0-9A-Z]{3})_([^_]*)_(\d+)\.txt$`)
+
+// ParseChapterFileName reads a chapter file name such as "tgl_GEN_Genesis_001.txt".
+func ParseChapterFileName(name string) (ChapterFile, error) {
+	m := chapterFileNamePattern.FindStringSubmatch(filepath.Base(name))
+	if m == nil {
+		return ChapterFile{}, fmt.Errorf("not a chapter file name: %s", name)
+	}
+
+	chapter, _ := strconv.Atoi(m[4])
+	return ChapterFile{Language: m[1], Book: m[2], Name: m[3], Chapter: chapter}, nil
+}
+
+// ID returns the "BOOK_CCC" key the parallel builders join chapters on.
+func (cf ChapterFile) ID() string {
+	return fmt.Sprintf("%s_%03d", cf.Book, cf.Chapter)
+}
+
+// WriteChapterFile saves a chapter as a "verse\tcontent" TSV, one verse per row.
+func WriteChapterFile(path string, verses []Verse) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create chapter file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+
+	if _, err := writer.WriteString("verse\tcontent\n"); err != nil {
+		return fmt.Errorf("failed to write header to chapter file: %w", err)
+	}
+
+	for _, v := range verses {
+		if _, err := fmt.Fprintf(writer, "%s\t%s\n", v.Number, TransfromEscapeCharTSV(v.Text)); err != nil {
+			return fmt.Errorf("failed to write verse to chapter file: %w", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write chapter file: %w", err)
+	}
+	return nil
+}
+
+/*
+ReadChapterFile loads the verses of a chapter file in file order.
+Files written before verse numbers were kept (one verse per line, no header)
+are numbered by position.
+*/
+func ReadChapterFile(path string) ([]Verse, error) {
+	var verses []Verse
+	for verse, err := range ScanChapterFile(path) {
+		if err != nil {
+			return nil, err
+		}
+		verses = append(verses, verse)
+	}
+	return verses, nil
+}
+
+/*
+ScanChapterFile streams the verses of a chapter file in file order, reading
+one line at a time. An error ends the sequence.
+*/
+func ScanChapterFile(path string) iter.Seq2[Verse, error] {
+	return func(yield func(Verse, error) bool) {
+		file, err := os.Open(path)
+		if err != nil {
+			yield(Verse{}, err)
+			return
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		lineNum := 0
+		structured := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			lineNum++
+
+			if lineNum == 1 && strings.HasPrefix(line, "verse\t") {
+				structured = true
+				continue
+			}
+
+			if !structured {
+				if text := strings.TrimSpace(line); text != "" {
+					if !yield(Verse{Number: VerseNumber{Start: lineNum, End: lineNum}, Text: text}, nil) {
+						return
+					}
+				}
+				continue
+			}
+
+			label, text, ok := strings.Cut(line, "\t")
+			if !ok {
+				continue
+			}
+
+			number, err := ParseVerseNumber(label)
+			if err != nil {
+				yield(Verse{}, fmt.Errorf("%s:%d: %w", path, lineNum, err))
+				return
+			}
+
+			text = strings.TrimSpace(RemoveEscapeCharTSV(text))
+			if text != "" {
+				if !yield(Verse{Number: number, Text: text}, nil) {
+					return
+				}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			yield(Verse{}, fmt.Errorf("error reading chapter file: %w", err))
+		}
+	}
+}
+
+// VerseGroup is a span of verses and the indices of the source and target
+// units that fall inside it. An empty side means the verses are missing there.
+type VerseGroup struct {
+	Number VerseNumber
+	Src    []int
+	Tgt    []int
+}
+
+/*
+GroupVerseNumbers joins two chapters on verse number.
+Overlapping numbers are merged into one group, so a "3-4" on one side collects
+both 3 and 4 on the other. Verses present on only one side become groups with
+an empty opposite side. Groups are returned in verse order.
+*/
+func GroupVerseNumbers(src, tgt []VerseNumber) []VerseGroup {
+	multiway := GroupVerseNumbersMultiway([][]VerseNumber{src, tgt})
+
+	groups := make([]VerseGroup, len(multiway))
+	for i, g := range multiway {
+		groups[i] = VerseGroup{Number: g.Number, Src: g.Members[0], Tgt: g.Members[1]}
+	}
+	return groups
+}
+
+// MultiwayVerseGroup is a verse, or span of verses, with the indices of its verses in each chapter.
+type MultiwayVerseGroup struct {
+	Number  VerseNumber
+	Members [][]int // per chapter, in the order given
+}
+
+/*
+GroupVerseNumbersMultiway joins the same chapter of several translations on
+verse number, as GroupVerseNumbers does for two: a "3-4" in any of them
+collects 3 and 4 in all the others.
+*/
+func GroupVerseNumbersMultiway(chapters [][]VerseNumber) []MultiwayVerseGroup {
+	type unit struct {
+		number  VerseNumber
+		chapter int
+		index   int
+	}
+
+	var units []unit
+	for c, numbers := range chapters {
+		for i, n := range numbers {
+			units = append(units, unit{n, c, i})
+		}
+	}
+
+	sort.SliceStable(units, func(i, j int) bool {
+		return units[i].number.Start < units[j].number.Start
+	})
+
+	var groups []MultiwayVerseGroup
+	for _, u := range units {
+		last := len(groups) - 1
+		if last < 0 || !groups[last].Number.Overlaps(u.number) {
+			groups = append(groups, MultiwayVerseGroup{Number: u.number, Members: make([][]int, len(chapters))})
+			last++
+		}
+
+		g := &groups[last]
+		g.Number.End = max(g.Number.End, u.number.End)
+		g.Members[u.chapter] = append(g.Members[u.chapter], u.index)
+	}
+
+	return groups
+}
@@ -0,0 +1,229 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseVerseNumber(t *testing.T) {
+	tests := []struct {
+		label string
+		want  VerseNumber
+	}{
+		{"3", VerseNumber{3, 3}},
+		{"003", VerseNumber{3, 3}},
+		{"3-4", VerseNumber{3, 4}},
+		{"003-004", VerseNumber{3, 4}},
+		{"3–4", VerseNumber{3, 4}},
+		{" 12 — 14b ", VerseNumber{12, 14}},
+		{"3a", VerseNumber{3, 3}},
+		{"5-5", VerseNumber{5, 5}},
+	}
+	for _, tt := range tests {
+		got, err := ParseVerseNumber(tt.label)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseVerseNumber(%q) = %v, %v; want %v", tt.label, got, err, tt.want)
+		}
+	}
+
+	for _, label := range []string{"", "a", "4-3", "3-", "-3", "3,4", "3 4", "3ab"} {
+		if got, err := ParseVerseNumber(label); err == nil {
+			t.Errorf("ParseVerseNumber(%q) = %v, want an error", label, got)
+		}
+	}
+}
+
+func TestVerseNumberString(t *testing.T) {
+	for _, tt := range []struct {
+		number VerseNumber
+		want   string
+	}{
+		{VerseNumber{3, 3}, "003"},
+		{VerseNumber{3, 4}, "003-004"},
+		{VerseNumber{120, 121}, "120-121"},
+	} {
+		if got := tt.number.String(); got != tt.want {
+			t.Errorf("%v.String() = %q, want %q", tt.number, got, tt.want)
+		}
+		if back, err := ParseVerseNumber(tt.number.String()); err != nil || back != tt.number {
+			t.Errorf("%q parsed back as %v, %v", tt.want, back, err)
+		}
+	}
+}
+
+func verses(numbers ...VerseNumber) []VerseNumber {
+	return numbers
+}
+
+func one(v int) VerseNumber {
+	return VerseNumber{v, v}
+}
+
+func TestGroupVerseNumbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, tgt []VerseNumber
+		want     []VerseGroup
+	}{
+		{
+			name: "same verses",
+			src:  verses(one(1), one(2)),
+			tgt:  verses(one(1), one(2)),
+			want: []VerseGroup{
+				{Number: one(1), Src: []int{0}, Tgt: []int{0}},
+				{Number: one(2), Src: []int{1}, Tgt: []int{1}},
+			},
+		},
+		{
+			name: "range on one side",
+			src:  verses(one(1), VerseNumber{2, 3}, one(4)),
+			tgt:  verses(one(1), one(2), one(3), one(4)),
+			want: []VerseGroup{
+				{Number: one(1), Src: []int{0}, Tgt: []int{0}},
+				{Number: VerseNumber{2, 3}, Src: []int{1}, Tgt: []int{1, 2}},
+				{Number: one(4), Src: []int{2}, Tgt: []int{3}},
+			},
+		},
+		{
+			name: "verse missing on one side",
+			src:  verses(one(1), one(2), one(3)),
+			tgt:  verses(one(1), one(3)),
+			want: []VerseGroup{
+				{Number: one(1), Src: []int{0}, Tgt: []int{0}},
+				{Number: one(2), Src: []int{1}},
+				{Number: one(3), Src: []int{2}, Tgt: []int{1}},
+			},
+		},
+		{
+			name: "overlapping ranges on both sides",
+			src:  verses(VerseNumber{1, 2}, VerseNumber{3, 4}, one(5)),
+			tgt:  verses(one(1), VerseNumber{2, 3}, one(4), one(5)),
+			want: []VerseGroup{
+				{Number: VerseNumber{1, 4}, Src: []int{0, 1}, Tgt: []int{0, 1, 2}},
+				{Number: one(5), Src: []int{2}, Tgt: []int{3}},
+			},
+		},
+		{
+			name: "empty side",
+			src:  verses(one(1)),
+			want: []VerseGroup{{Number: one(1), Src: []int{0}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GroupVerseNumbers(tt.src, tt.tgt)
+			if !slices.EqualFunc(got, tt.want, func(a, b VerseGroup) bool {
+				return a.Number == b.Number && slices.Equal(a.Src, b.Src) && slices.Equal(a.Tgt, b.Tgt)
+			}) {
+				t.Errorf("groups\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupVerseNumbersMultiway(t *testing.T) {
+	chapters := [][]VerseNumber{
+		verses(one(1), one(2), one(3)),
+		verses(VerseNumber{1, 2}, one(3)),
+		verses(one(1), one(3), one(4)),
+	}
+	want := []MultiwayVerseGroup{
+		{Number: VerseNumber{1, 2}, Members: [][]int{{0, 1}, {0}, {0}}},
+		{Number: one(3), Members: [][]int{{2}, {1}, {1}}},
+		{Number: one(4), Members: [][]int{nil, nil, {2}}},
+	}
+
+	got := GroupVerseNumbersMultiway(chapters)
+	if !slices.EqualFunc(got, want, func(a, b MultiwayVerseGroup) bool {
+		return a.Number == b.Number && slices.EqualFunc(a.Members, b.Members, slices.Equal)
+	}) {
+		t.Errorf("groups\n%v\nwant\n%v", got, want)
+	}
+}
+
+func writeTestFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tgl_GEN_Genesis_001.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestScanChapterFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Verse
+		err     string
+	}{
+		{
+			name:    "verse numbers",
+			content: "verse\tcontent\n001\tNang pasimula.\n003-004\tAt sinabi ng Dios.\n005\t\n",
+			want: []Verse{
+				{Number: one(1), Text: "Nang pasimula."},
+				{Number: VerseNumber{3, 4}, Text: "At sinabi ng Dios."},
+			},
+		},
+		{
+			name:    "old format numbered by line",
+			content: "Nang pasimula.\n\nAt sinabi ng Dios.\n",
+			want: []Verse{
+				{Number: one(1), Text: "Nang pasimula."},
+				{Number: one(3), Text: "At sinabi ng Dios."},
+			},
+		},
+		{
+			name:    "reversed range",
+			content: "verse\tcontent\n001\tNang pasimula.\n004-003\tAt sinabi ng Dios.\n",
+			err:     ":3: invalid verse range",
+		},
+		{
+			name:    "invalid label",
+			content: "verse\tcontent\nisa\tNang pasimula.\n",
+			err:     ":2: invalid verse number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadChapterFile(writeTestFile(t, tt.content))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("verses %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteChapterFileRoundTrip(t *testing.T) {
+	want := []Verse{
+		{Number: one(1), Text: "Nang pasimula."},
+		{Number: VerseNumber{2, 3}, Text: "Isang\ttab at\nisang bagong linya."},
+	}
+	path := filepath.Join(t.TempDir(), "tgl", "tgl_GEN_Genesis_001.txt")
+	if err := WriteChapterFile(path, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadChapterFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("read back %q, want %q", got, want)
+	}
+}
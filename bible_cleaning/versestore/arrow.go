@@ -0,0 +1,454 @@
+package versestore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+/*
+Records are stored columnar as an Arrow IPC stream (the format of
+pyarrow.ipc.open_stream and Arrow's .arrows files), written without the Arrow
+library: a schema message, one record batch per DefaultBatchSize verses and an
+end-of-stream marker. The writer never emits nulls; the reader accepts them
+and reads them as zero values.
+*/
+
+// DefaultBatchSize is the number of verses in each Arrow record batch.
+const DefaultBatchSize = 10000
+
+const (
+	metadataV5 = 4 // MetadataVersion.V5
+
+	headerSchema      = 1 // MessageHeader union
+	headerRecordBatch = 3
+
+	typeInt       = 2 // Type union
+	typeUtf8      = 5
+	typeTimestamp = 10
+
+	timeUnitMicrosecond = 2
+
+	continuationMarker = 0xFFFFFFFF
+)
+
+type columnKind int
+
+const (
+	utf8Column columnKind = iota
+	int32Column
+	timestampColumn
+)
+
+type arrowColumn struct {
+	name string
+	kind columnKind
+}
+
+// recordColumns is the Arrow schema of a Record, in column order.
+var recordColumns = []arrowColumn{
+	{"lang", utf8Column},
+	{"book", utf8Column},
+	{"chapter", int32Column},
+	{"verse", utf8Column},
+	{"text", utf8Column},
+	{"source_url", utf8Column},
+	{"scraped_at", timestampColumn},
+}
+
+func (c arrowColumn) typeTable() (uint8, fbTable) {
+	switch c.kind {
+	case int32Column:
+		return typeInt, fbTable{fbScalar(0, 4, 32), fbScalar(1, 1, 1)}
+	case timestampColumn:
+		return typeTimestamp, fbTable{fbScalar(0, 2, timeUnitMicrosecond), fbOffset(1, fbString("UTC"))}
+	}
+	return typeUtf8, fbTable{}
+}
+
+func schemaMessage() []byte {
+	var fields fbTables
+	for _, c := range recordColumns {
+		typeID, typeTable := c.typeTable()
+		fields = append(fields, fbTable{
+			fbOffset(0, fbString(c.name)),
+			fbScalar(1, 1, 0), // not nullable
+			fbScalar(2, 1, uint64(typeID)),
+			fbOffset(3, typeTable),
+			fbOffset(5, fbTables{}), // Arrow readers require the children vector
+		})
+	}
+
+	schema := fbTable{
+		fbScalar(0, 2, 0), // little endian
+		fbOffset(1, fields),
+	}
+	return messageFlatbuffer(headerSchema, schema, 0)
+}
+
+func messageFlatbuffer(headerType uint8, header fbTable, bodyLength int) []byte {
+	return finishFlatbuffer(fbTable{
+		fbScalar(0, 2, metadataV5),
+		fbScalar(1, 1, uint64(headerType)),
+		fbOffset(2, header),
+		fbScalar(3, 8, uint64(bodyLength)),
+	})
+}
+
+// batchBody collects the buffers and field nodes of one record batch.
+type batchBody struct {
+	body    []byte
+	nodes   []byte
+	buffers []byte
+}
+
+func (b *batchBody) addNode(length int) {
+	b.nodes = binary.LittleEndian.AppendUint64(b.nodes, uint64(length))
+	b.nodes = binary.LittleEndian.AppendUint64(b.nodes, 0) // null count
+}
+
+func (b *batchBody) addBuffer(data []byte) {
+	b.buffers = binary.LittleEndian.AppendUint64(b.buffers, uint64(len(b.body)))
+	b.buffers = binary.LittleEndian.AppendUint64(b.buffers, uint64(len(data)))
+
+	b.body = append(b.body, data...)
+	for len(b.body)%8 != 0 {
+		b.body = append(b.body, 0)
+	}
+}
+
+func (b *batchBody) addColumn(c arrowColumn, records []Record) {
+	b.addNode(len(records))
+	b.addBuffer(nil) // no validity bitmap: every value is present
+
+	switch c.kind {
+	case int32Column:
+		var data []byte
+		for _, r := range records {
+			data = binary.LittleEndian.AppendUint32(data, uint32(int32(r.Chapter)))
+		}
+		b.addBuffer(data)
+
+	case timestampColumn:
+		var data []byte
+		for _, r := range records {
+			data = binary.LittleEndian.AppendUint64(data, uint64(r.ScrapedAt.UnixMicro()))
+		}
+		b.addBuffer(data)
+
+	case utf8Column:
+		offsets := binary.LittleEndian.AppendUint32(nil, 0)
+		var data []byte
+		for _, r := range records {
+			data = append(data, stringValue(c.name, r)...)
+			offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(data)))
+		}
+		b.addBuffer(offsets)
+		b.addBuffer(data)
+	}
+}
+
+func stringValue(column string, r Record) string {
+	switch column {
+	case "lang":
+		return r.Lang
+	case "book":
+		return r.Book
+	case "verse":
+		return r.Verse.String()
+	case "text":
+		return r.Text
+	case "source_url":
+		return r.SourceURL
+	}
+	return ""
+}
+
+// ArrowWriter writes records as an Arrow IPC stream, batchSize verses per record batch.
+type ArrowWriter struct {
+	w         *bufio.Writer
+	closer    io.Closer
+	batchSize int
+	pending   []Record
+	started   bool
+}
+
+func NewArrowWriter(w io.Writer, batchSize int) *ArrowWriter {
+	aw := &ArrowWriter{w: bufio.NewWriter(w), batchSize: max(batchSize, 1)}
+	if closer, ok := w.(io.Closer); ok {
+		aw.closer = closer
+	}
+	return aw
+}
+
+func (aw *ArrowWriter) Write(record Record) error {
+	aw.pending = append(aw.pending, record)
+	if len(aw.pending) < aw.batchSize {
+		return nil
+	}
+	return aw.flushBatch()
+}
+
+// Close writes the last batch and the end-of-stream marker, then closes the underlying file, if any.
+func (aw *ArrowWriter) Close() error {
+	if err := aw.flushBatch(); err != nil {
+		return err
+	}
+	if err := aw.writeMessage(nil, nil); err != nil {
+		return err
+	}
+	if err := aw.w.Flush(); err != nil {
+		return err
+	}
+	if aw.closer != nil {
+		return aw.closer.Close()
+	}
+	return nil
+}
+
+func (aw *ArrowWriter) flushBatch() error {
+	if !aw.started {
+		if err := aw.writeMessage(schemaMessage(), nil); err != nil {
+			return err
+		}
+		aw.started = true
+	}
+
+	if len(aw.pending) == 0 {
+		return nil
+	}
+
+	batch := &batchBody{}
+	for _, c := range recordColumns {
+		batch.addColumn(c, aw.pending)
+	}
+
+	header := fbTable{
+		fbScalar(0, 8, uint64(len(aw.pending))),
+		fbOffset(1, fbStructs{count: len(recordColumns), data: batch.nodes}),
+		fbOffset(2, fbStructs{count: len(batch.buffers) / 16, data: batch.buffers}),
+	}
+
+	aw.pending = aw.pending[:0]
+	return aw.writeMessage(messageFlatbuffer(headerRecordBatch, header, len(batch.body)), batch.body)
+}
+
+// writeMessage frames one IPC message; nil metadata writes the end-of-stream marker.
+func (aw *ArrowWriter) writeMessage(metadata, body []byte) error {
+	var prefix []byte
+	prefix = binary.LittleEndian.AppendUint32(prefix, continuationMarker)
+	prefix = binary.LittleEndian.AppendUint32(prefix, uint32(len(metadata)))
+
+	for _, part := range [][]byte{prefix, metadata, body} {
+		if _, err := aw.w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadArrow reads an Arrow IPC stream written by ArrowWriter, calling fn for every record in order.
+func ReadArrow(r io.Reader, fn func(Record) error) error {
+	sawSchema := false
+
+	for {
+		metadata, err := readMessageMetadata(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		headerType, header, bodyLength, err := parseMessage(metadata)
+		if err != nil {
+			return err
+		}
+
+		body := make([]byte, bodyLength)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return fmt.Errorf("truncated Arrow message body: %w", err)
+		}
+
+		switch headerType {
+		case headerSchema:
+			if err := checkSchema(header); err != nil {
+				return err
+			}
+			sawSchema = true
+		case headerRecordBatch:
+			if !sawSchema {
+				return errors.New("Arrow record batch before the schema")
+			}
+			records, err := decodeBatch(header, body)
+			if err != nil {
+				return err
+			}
+			for _, record := range records {
+				if err := fn(record); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("unsupported Arrow message type %d", headerType)
+		}
+	}
+}
+
+// readMessageMetadata reads the framing and flatbuffer of the next message; io.EOF ends the stream.
+func readMessageMetadata(r io.Reader) ([]byte, error) {
+	var word [4]byte
+	if _, err := io.ReadFull(r, word[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("truncated Arrow stream: %w", err)
+		}
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(word[:])
+	if size == continuationMarker {
+		if _, err := io.ReadFull(r, word[:]); err != nil {
+			return nil, fmt.Errorf("truncated Arrow stream: %w", err)
+		}
+		size = binary.LittleEndian.Uint32(word[:])
+	}
+	if size == 0 {
+		return nil, io.EOF
+	}
+
+	metadata := make([]byte, size)
+	if _, err := io.ReadFull(r, metadata); err != nil {
+		return nil, fmt.Errorf("truncated Arrow message: %w", err)
+	}
+	return metadata, nil
+}
+
+func parseMessage(metadata []byte) (headerType uint8, header fbReader, bodyLength int64, err error) {
+	defer recoverMalformed(&err)
+
+	message := fbRoot(metadata)
+	header, ok := message.table(2)
+	if !ok {
+		return 0, header, 0, errors.New("Arrow message without a header")
+	}
+
+	bodyLength = message.int64(3)
+	if bodyLength < 0 {
+		return 0, header, 0, errors.New("negative Arrow body length")
+	}
+	return message.uint8(1), header, bodyLength, nil
+}
+
+// checkSchema makes sure a stream holds verse records.
+func checkSchema(schema fbReader) (err error) {
+	defer recoverMalformed(&err)
+
+	elems, n := schema.vector(1)
+	if n != len(recordColumns) {
+		return fmt.Errorf("Arrow schema has %d columns, verse records have %d", n, len(recordColumns))
+	}
+
+	for i, want := range recordColumns {
+		field := schema.tableAt(elems, i)
+		wantType, _ := want.typeTable()
+		if name := field.string(0); name != want.name || field.uint8(2) != wantType {
+			return fmt.Errorf("Arrow column %d is %q, expected %q", i, name, want.name)
+		}
+	}
+	return nil
+}
+
+func decodeBatch(header fbReader, body []byte) (records []Record, err error) {
+	defer recoverMalformed(&err)
+
+	if _, compressed := header.field(3); compressed {
+		return nil, errors.New("compressed Arrow record batches are not supported")
+	}
+
+	length := int(header.int64(0))
+	nodeStart, nodeCount := header.vector(1)
+	bufferStart, bufferCount := header.vector(2)
+
+	nextBuffer := 0
+	buffer := func() []byte {
+		if nextBuffer >= bufferCount {
+			panic("missing buffer")
+		}
+		pos := bufferStart + 16*nextBuffer
+		offset := binary.LittleEndian.Uint64(header.buf[pos:])
+		size := binary.LittleEndian.Uint64(header.buf[pos+8:])
+		nextBuffer++
+		return body[offset : offset+size]
+	}
+
+	if nodeCount != len(recordColumns) {
+		return nil, fmt.Errorf("Arrow record batch has %d columns, expected %d", nodeCount, len(recordColumns))
+	}
+
+	records = make([]Record, length)
+	for i, c := range recordColumns {
+		nullCount := binary.LittleEndian.Uint64(header.buf[nodeStart+16*i+8:])
+		validity := buffer()
+		isNull := func(row int) bool {
+			return nullCount > 0 && len(validity) > 0 && validity[row/8]&(1<<(row%8)) == 0
+		}
+
+		switch c.kind {
+		case int32Column:
+			data := buffer()
+			for row := range records {
+				if !isNull(row) {
+					records[row].Chapter = int(int32(binary.LittleEndian.Uint32(data[4*row:])))
+				}
+			}
+
+		case timestampColumn:
+			data := buffer()
+			for row := range records {
+				if !isNull(row) {
+					records[row].ScrapedAt = time.UnixMicro(int64(binary.LittleEndian.Uint64(data[8*row:]))).UTC()
+				}
+			}
+
+		case utf8Column:
+			offsets, data := buffer(), buffer()
+			for row := range records {
+				if isNull(row) {
+					continue
+				}
+				start := binary.LittleEndian.Uint32(offsets[4*row:])
+				end := binary.LittleEndian.Uint32(offsets[4*row+4:])
+				if err := setStringValue(c.name, &records[row], string(data[start:end])); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return records, nil
+}
+
+func setStringValue(column string, r *Record, value string) error {
+	switch column {
+	case "lang":
+		r.Lang = value
+	case "book":
+		r.Book = value
+	case "verse":
+		number, err := types.ParseVerseNumber(value)
+		if err != nil {
+			return err
+		}
+		r.Verse = number
+	case "text":
+		r.Text = value
+	case "source_url":
+		r.SourceURL = value
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package versestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zrygan.nlp/bible_cleaning/biblecorpus"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+/*
+ConvertTree writes every verse of a by_verses tree (root/<lang>/*.txt) to out,
+ordered by language, canonical book order, chapter and verse. The source URL
+and scrape time come from the chapter's annotation sidecar under
+annotationRoot; chapters scraped before sidecars existed fall back to the
+file's modification time and an empty URL.
+Files whose names do not parse are reported as an error instead of skipped.
+*/
+func ConvertTree(root, annotationRoot string, out Writer) (int, error) {
+	index, err := biblecorpus.Open(root)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(index.Skipped) > 0 {
+		return 0, fmt.Errorf("%d files are not chapter files: %s", len(index.Skipped), strings.Join(index.Skipped, ", "))
+	}
+
+	count := 0
+	for _, lang := range index.Languages() {
+		for _, chapter := range index.Chapters(lang) {
+			n, err := convertChapter(chapter, annotationRoot, out)
+			if err != nil {
+				return count, fmt.Errorf("%s: %w", chapter.Path, err)
+			}
+			count += n
+		}
+	}
+	return count, nil
+}
+
+func convertChapter(chapter biblecorpus.Chapter, annotationRoot string, out Writer) (int, error) {
+	verses, err := types.ReadChapterFile(chapter.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	template := Record{
+		Lang:    chapter.Language,
+		Book:    chapter.Book,
+		Chapter: chapter.Chapter,
+	}
+
+	sidecar := filepath.Join(annotationRoot, chapter.Language, strings.TrimSuffix(filepath.Base(chapter.Path), ".txt")+".json")
+	annotations, err := types.ReadAnnotationFile(sidecar)
+	switch {
+	case err == nil:
+		template.SourceURL = annotations.URL
+		template.ScrapedAt = annotations.ScrapedAt
+	case errors.Is(err, os.ErrNotExist):
+		info, err := os.Stat(chapter.Path)
+		if err != nil {
+			return 0, err
+		}
+		template.ScrapedAt = info.ModTime().UTC()
+	default:
+		return 0, err
+	}
+
+	for _, verse := range verses {
+		record := template
+		record.Verse = verse.Number
+		record.Text = verse.Text
+		if err := out.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	return len(verses), nil
+}
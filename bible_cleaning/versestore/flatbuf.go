@@ -0,0 +1,254 @@
+package versestore
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+A minimal FlatBuffers encoder and decoder, enough for the Arrow IPC metadata
+(Message, Schema, Field, RecordBatch). Objects are written front to back: a
+table comes first, followed by the strings, vectors and tables it points to,
+so every offset points forward as the format requires.
+*/
+
+type fbObject interface {
+	write(w *fbWriter) int // returns the position the object starts at
+}
+
+// fbField is one field of a table: a scalar of size bytes, or an offset to child.
+type fbField struct {
+	id     int
+	size   int
+	scalar uint64
+	child  fbObject
+}
+
+func fbScalar(id, size int, value uint64) fbField {
+	return fbField{id: id, size: size, scalar: value}
+}
+
+func fbOffset(id int, child fbObject) fbField {
+	return fbField{id: id, size: 4, child: child}
+}
+
+type fbTable []fbField
+
+type fbString string
+
+type fbTables []fbTable
+
+// fbStructs is a vector of fixed-size structs stored inline, 8-byte aligned.
+type fbStructs struct {
+	count int
+	data  []byte
+}
+
+type fbWriter struct {
+	buf []byte
+}
+
+// align pads the buffer until position+offset is a multiple of n.
+func (w *fbWriter) align(n, offset int) {
+	for (len(w.buf)+offset)%n != 0 {
+		w.buf = append(w.buf, 0)
+	}
+}
+
+func (w *fbWriter) putUint32(pos int, v uint32) {
+	binary.LittleEndian.PutUint32(w.buf[pos:], v)
+}
+
+func (w *fbWriter) appendUint32(v uint32) int {
+	pos := len(w.buf)
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, v)
+	return pos
+}
+
+// patch points the offset slot at pos to target, which lies after it.
+func (w *fbWriter) patch(pos, target int) {
+	w.putUint32(pos, uint32(target-pos))
+}
+
+func (t fbTable) write(w *fbWriter) int {
+	maxID := -1
+	for _, f := range t {
+		maxID = max(maxID, f.id)
+	}
+
+	// lay the fields out after the 4-byte vtable offset
+	slots := make([]int, maxID+1)
+	inline := 4
+	for _, f := range t {
+		inline = (inline + f.size - 1) / f.size * f.size
+		slots[f.id] = inline
+		inline += f.size
+	}
+
+	w.align(2, 0)
+	vtable := len(w.buf)
+	w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(4+2*len(slots)))
+	w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(inline))
+	for _, slot := range slots {
+		w.buf = binary.LittleEndian.AppendUint16(w.buf, uint16(slot))
+	}
+
+	w.align(8, 0)
+	table := len(w.buf)
+	w.buf = append(w.buf, make([]byte, inline)...)
+	w.putUint32(table, uint32(int32(table-vtable)))
+
+	for _, f := range t {
+		pos := table + slots[f.id]
+		switch f.size {
+		case 1:
+			w.buf[pos] = byte(f.scalar)
+		case 2:
+			binary.LittleEndian.PutUint16(w.buf[pos:], uint16(f.scalar))
+		case 4:
+			w.putUint32(pos, uint32(f.scalar))
+		case 8:
+			binary.LittleEndian.PutUint64(w.buf[pos:], f.scalar)
+		}
+	}
+
+	for _, f := range t {
+		if f.child != nil {
+			w.patch(table+slots[f.id], f.child.write(w))
+		}
+	}
+	return table
+}
+
+func (s fbString) write(w *fbWriter) int {
+	w.align(4, 0)
+	pos := w.appendUint32(uint32(len(s)))
+	w.buf = append(w.buf, s...)
+	w.buf = append(w.buf, 0)
+	return pos
+}
+
+func (ts fbTables) write(w *fbWriter) int {
+	w.align(4, 0)
+	pos := w.appendUint32(uint32(len(ts)))
+	for range ts {
+		w.appendUint32(0)
+	}
+	for i, t := range ts {
+		w.patch(pos+4+4*i, t.write(w))
+	}
+	return pos
+}
+
+func (s fbStructs) write(w *fbWriter) int {
+	w.align(8, 4)
+	pos := w.appendUint32(uint32(s.count))
+	w.buf = append(w.buf, s.data...)
+	return pos
+}
+
+// finishFlatbuffer serializes root as a complete buffer, padded to a multiple of 8 bytes.
+func finishFlatbuffer(root fbTable) []byte {
+	w := &fbWriter{}
+	w.appendUint32(0)
+	w.patch(0, root.write(w))
+	w.align(8, 0)
+	return w.buf
+}
+
+// fbReader reads tables out of a FlatBuffers buffer. Malformed input panics
+// with an out-of-range error, which the callers recover into an error.
+type fbReader struct {
+	buf []byte
+	pos int // start of the table
+}
+
+func fbRoot(buf []byte) fbReader {
+	return fbReader{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+// field returns where field id is stored, or false if the table does not have it.
+func (t fbReader) field(id int) (int, bool) {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	size := int(binary.LittleEndian.Uint16(t.buf[vtable:]))
+	if 4+2*id >= size {
+		return 0, false
+	}
+	offset := int(binary.LittleEndian.Uint16(t.buf[vtable+4+2*id:]))
+	if offset == 0 {
+		return 0, false
+	}
+	return t.pos + offset, true
+}
+
+func (t fbReader) deref(pos int) int {
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbReader) uint8(id int) uint8 {
+	if pos, ok := t.field(id); ok {
+		return t.buf[pos]
+	}
+	return 0
+}
+
+func (t fbReader) int16(id int) int16 {
+	if pos, ok := t.field(id); ok {
+		return int16(binary.LittleEndian.Uint16(t.buf[pos:]))
+	}
+	return 0
+}
+
+func (t fbReader) int32(id int) int32 {
+	if pos, ok := t.field(id); ok {
+		return int32(binary.LittleEndian.Uint32(t.buf[pos:]))
+	}
+	return 0
+}
+
+func (t fbReader) int64(id int) int64 {
+	if pos, ok := t.field(id); ok {
+		return int64(binary.LittleEndian.Uint64(t.buf[pos:]))
+	}
+	return 0
+}
+
+func (t fbReader) table(id int) (fbReader, bool) {
+	pos, ok := t.field(id)
+	if !ok {
+		return fbReader{}, false
+	}
+	return fbReader{buf: t.buf, pos: t.deref(pos)}, true
+}
+
+func (t fbReader) string(id int) string {
+	pos, ok := t.field(id)
+	if !ok {
+		return ""
+	}
+	start := t.deref(pos)
+	n := int(binary.LittleEndian.Uint32(t.buf[start:]))
+	return string(t.buf[start+4 : start+4+n])
+}
+
+// vector returns the start of the elements of vector field id and their count.
+func (t fbReader) vector(id int) (int, int) {
+	pos, ok := t.field(id)
+	if !ok {
+		return 0, 0
+	}
+	start := t.deref(pos)
+	return start + 4, int(binary.LittleEndian.Uint32(t.buf[start:]))
+}
+
+// tableAt reads the i-th table of a vector of tables starting at elems.
+func (t fbReader) tableAt(elems, i int) fbReader {
+	return fbReader{buf: t.buf, pos: t.deref(elems + 4*i)}
+}
+
+// recoverMalformed turns a panic from reading a malformed buffer into an error.
+func recoverMalformed(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("malformed Arrow metadata: %v", r)
+	}
+}
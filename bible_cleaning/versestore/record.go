@@ -0,0 +1,160 @@
+package versestore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// Record is one verse of one translation with everything known about where it came from.
+type Record struct {
+	Lang      string            `json:"lang"`    // ISO 639-3 code
+	Book      string            `json:"book"`    // USFM book code, e.g. "GEN"
+	Chapter   int               `json:"chapter"` // chapter number
+	Verse     types.VerseNumber `json:"verse"`   // "003", or "003-004" for merged verses
+	Text      string            `json:"text"`
+	SourceURL string            `json:"source_url"`
+	ScrapedAt time.Time         `json:"scraped_at"`
+}
+
+// Writer appends records to a corpus file.
+type Writer interface {
+	Write(record Record) error
+	Close() error
+}
+
+// JSONLWriter writes one JSON object per line.
+type JSONLWriter struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	encoder.SetEscapeHTML(false)
+
+	jw := &JSONLWriter{w: buffered, encoder: encoder}
+	if closer, ok := w.(io.Closer); ok {
+		jw.closer = closer
+	}
+	return jw
+}
+
+func (jw *JSONLWriter) Write(record Record) error {
+	return jw.encoder.Encode(record)
+}
+
+// Close flushes the writer and closes the underlying file, if any.
+func (jw *JSONLWriter) Close() error {
+	if err := jw.w.Flush(); err != nil {
+		return err
+	}
+	if jw.closer != nil {
+		return jw.closer.Close()
+	}
+	return nil
+}
+
+// ReadJSONL reads every record of a JSONL corpus, calling fn for each one in order.
+func ReadJSONL(r io.Reader, fn func(Record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Format is an on-disk corpus format.
+type Format string
+
+const (
+	FormatJSONL Format = "jsonl"
+	FormatArrow Format = "arrow" // Arrow IPC stream
+)
+
+// Extension returns the file extension of the format.
+func (f Format) Extension() string {
+	if f == FormatArrow {
+		return ".arrows"
+	}
+	return ".jsonl"
+}
+
+// FormatOf tells the format of a corpus file from its extension.
+func FormatOf(path string) (Format, error) {
+	switch filepath.Ext(path) {
+	case ".jsonl":
+		return FormatJSONL, nil
+	case ".arrows", ".arrow":
+		return FormatArrow, nil
+	}
+	return "", fmt.Errorf("unknown corpus format of %s", path)
+}
+
+// Create opens a writer for a new corpus file, picking the format from its extension.
+func Create(path string) (Writer, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create corpus file: %w", err)
+	}
+
+	if format == FormatArrow {
+		return NewArrowWriter(file, DefaultBatchSize), nil
+	}
+	return NewJSONLWriter(file), nil
+}
+
+// ReadFile reads every record of a corpus file in either format.
+func ReadFile(path string, fn func(Record) error) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if format == FormatArrow {
+		err = ReadArrow(bufio.NewReader(file), fn)
+	} else {
+		err = ReadJSONL(file, fn)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
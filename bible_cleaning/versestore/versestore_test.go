@@ -0,0 +1,329 @@
+package versestore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+var scrapedAt = time.Date(2025, 3, 4, 5, 6, 7, 123456000, time.UTC)
+
+func sampleRecords() []Record {
+	return []Record{
+		{Lang: "ceb", Book: "JHN", Chapter: 11, Verse: types.VerseNumber{Start: 35, End: 35}, Text: "Mihilak si Jesus.", SourceURL: "https://www.bible.com/bible/562/JHN.11.RCPV", ScrapedAt: scrapedAt},
+		{Lang: "tgl", Book: "GEN", Chapter: 1, Verse: types.VerseNumber{Start: 1, End: 1}, Text: "Nang pasimula ay nilikha ng Dios ang langit at ang lupa.", SourceURL: "https://www.bible.com/bible/2195/GEN.1.ABTAG01", ScrapedAt: scrapedAt},
+		{Lang: "tgl", Book: "GEN", Chapter: 1, Verse: types.VerseNumber{Start: 2, End: 3}, Text: "At ang lupa ay walang anyo, “at sinabi ng Dios.”", SourceURL: "https://www.bible.com/bible/2195/GEN.1.ABTAG01", ScrapedAt: scrapedAt},
+		{Lang: "tgl", Book: "GEN", Chapter: 1, Verse: types.VerseNumber{Start: 4, End: 4}}, // empty text, no URL, never scraped
+		{Lang: "tgl", Book: "EST", Chapter: 120, Verse: types.VerseNumber{Start: 7, End: 7}, Text: "ᜀᜅ᜔ ᜊᜒᜈ᜔ᜆᜓ", ScrapedAt: scrapedAt.Add(time.Hour)},
+	}
+}
+
+func sameRecords(got, want []Record) bool {
+	return slices.EqualFunc(got, want, func(a, b Record) bool {
+		return a.Lang == b.Lang && a.Book == b.Book && a.Chapter == b.Chapter && a.Verse == b.Verse &&
+			a.Text == b.Text && a.SourceURL == b.SourceURL && a.ScrapedAt.Equal(b.ScrapedAt)
+	})
+}
+
+func writeAll(t *testing.T, w Writer, records []Record) {
+	t.Helper()
+
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func collect(read func(func(Record) error) error) ([]Record, error) {
+	var records []Record
+	err := read(func(r Record) error {
+		records = append(records, r)
+		return nil
+	})
+	return records, err
+}
+
+func TestJSONLRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	writeAll(t, NewJSONLWriter(&buf), sampleRecords())
+
+	if lines := strings.Count(buf.String(), "\n"); lines != len(sampleRecords()) {
+		t.Errorf("%d lines, want one per record", lines)
+	}
+	if !strings.Contains(buf.String(), `"verse":"002-003"`) || !strings.Contains(buf.String(), "“at sinabi ng Dios.”") {
+		t.Errorf("JSONL\n%s\nwant the verse range as a label and the text unescaped", buf.String())
+	}
+
+	got, err := collect(func(fn func(Record) error) error { return ReadJSONL(&buf, fn) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameRecords(got, sampleRecords()) {
+		t.Errorf("read back\n%+v\nwant\n%+v", got, sampleRecords())
+	}
+
+	if _, err := collect(func(fn func(Record) error) error {
+		return ReadJSONL(strings.NewReader(`{"lang":"tgl"}`+"\n"+`{"verse":"4-3"}`+"\n"), fn)
+	}); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error %v, want one on line 2", err)
+	}
+}
+
+func TestArrowRoundTrip(t *testing.T) {
+	for _, batchSize := range []int{1, 2, len(sampleRecords()), DefaultBatchSize} {
+		var buf bytes.Buffer
+		writeAll(t, NewArrowWriter(&buf, batchSize), sampleRecords())
+
+		got, err := collect(func(fn func(Record) error) error { return ReadArrow(&buf, fn) })
+		if err != nil {
+			t.Fatalf("batch size %d: %v", batchSize, err)
+		}
+		if !sameRecords(got, sampleRecords()) {
+			t.Errorf("batch size %d: read back\n%+v\nwant\n%+v", batchSize, got, sampleRecords())
+		}
+	}
+
+	var empty bytes.Buffer
+	writeAll(t, NewArrowWriter(&empty, DefaultBatchSize), nil)
+	if got, err := collect(func(fn func(Record) error) error { return ReadArrow(&empty, fn) }); err != nil || len(got) != 0 {
+		t.Errorf("empty stream: %d records, %v", len(got), err)
+	}
+}
+
+// arrowMessage is one framed message of an IPC stream.
+type arrowMessage struct {
+	headerType uint8
+	header     fbReader
+	body       []byte
+}
+
+// splitArrowStream walks the stream framing the way the Arrow format
+// specification lays it out, checking the alignment every reader relies on.
+func splitArrowStream(t *testing.T, stream []byte) []arrowMessage {
+	t.Helper()
+
+	var messages []arrowMessage
+	for pos := 0; ; {
+		if pos%8 != 0 {
+			t.Fatalf("message at byte %d is not 8-byte aligned", pos)
+		}
+		if binary.LittleEndian.Uint32(stream[pos:]) != continuationMarker {
+			t.Fatalf("message at byte %d has no continuation marker", pos)
+		}
+		size := int(binary.LittleEndian.Uint32(stream[pos+4:]))
+		pos += 8
+		if size == 0 {
+			if pos != len(stream) {
+				t.Fatalf("%d bytes after the end-of-stream marker", len(stream)-pos)
+			}
+			return messages
+		}
+		if size%8 != 0 {
+			t.Fatalf("metadata of %d bytes is not padded to 8 bytes", size)
+		}
+
+		headerType, header, bodyLength, err := parseMessage(stream[pos : pos+size])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version := fbRoot(stream[pos : pos+size]).int16(0); version != metadataV5 {
+			t.Errorf("metadata version %d, want V5", version)
+		}
+		pos += size
+		if bodyLength%8 != 0 {
+			t.Fatalf("body of %d bytes is not padded to 8 bytes", bodyLength)
+		}
+		messages = append(messages, arrowMessage{headerType, header, stream[pos : pos+int(bodyLength)]})
+		pos += int(bodyLength)
+	}
+}
+
+func TestArrowStreamLayout(t *testing.T) {
+	var buf bytes.Buffer
+	writeAll(t, NewArrowWriter(&buf, 2), sampleRecords())
+
+	messages := splitArrowStream(t, buf.Bytes())
+	if len(messages) != 4 || messages[0].headerType != headerSchema {
+		t.Fatalf("%d messages, want a schema and 3 record batches", len(messages))
+	}
+
+	elems, n := messages[0].header.vector(1)
+	var columns []string
+	for i := range n {
+		field := messages[0].header.tableAt(elems, i)
+		typeTable, _ := field.table(3)
+		column := field.string(0) + ":" + map[uint8]string{typeInt: "int", typeUtf8: "utf8", typeTimestamp: "timestamp"}[field.uint8(2)]
+		switch field.uint8(2) {
+		case typeInt:
+			if typeTable.int32(0) != 32 || typeTable.uint8(1) != 1 {
+				t.Errorf("%s is int%d, want a signed int32", field.string(0), typeTable.int32(0))
+			}
+		case typeTimestamp:
+			if typeTable.int16(0) != timeUnitMicrosecond || typeTable.string(1) != "UTC" {
+				t.Errorf("%s is in unit %d of %q, want microseconds in UTC", field.string(0), typeTable.int16(0), typeTable.string(1))
+			}
+		}
+		if _, children := field.vector(5); children != 0 {
+			t.Errorf("%s has %d children", field.string(0), children)
+		}
+		columns = append(columns, column)
+	}
+	want := []string{"lang:utf8", "book:utf8", "chapter:int", "verse:utf8", "text:utf8", "source_url:utf8", "scraped_at:timestamp"}
+	if !slices.Equal(columns, want) {
+		t.Errorf("schema %v, want %v", columns, want)
+	}
+
+	for i, batch := range messages[1:] {
+		if batch.headerType != headerRecordBatch {
+			t.Fatalf("message %d has type %d, want a record batch", i+1, batch.headerType)
+		}
+		if rows, want := batch.header.int64(0), min(2, len(sampleRecords())-2*i); rows != int64(want) {
+			t.Errorf("batch %d has %d rows, want %d", i, rows, want)
+		}
+		buffersStart, buffers := batch.header.vector(2)
+		for b := range buffers {
+			offset := binary.LittleEndian.Uint64(batch.header.buf[buffersStart+16*b:])
+			size := binary.LittleEndian.Uint64(batch.header.buf[buffersStart+16*b+8:])
+			if offset%8 != 0 || offset+size > uint64(len(batch.body)) {
+				t.Errorf("batch %d buffer %d at %d+%d of a %d byte body", i, b, offset, size, len(batch.body))
+			}
+		}
+	}
+}
+
+// testdata/verses.arrows was written by the Apache Arrow Go library
+// (ipc.NewWriter) from sampleRecords in batches of 2, with nullable columns
+// and the missing source URLs as nulls.
+func TestReadArrowFromArrowLibrary(t *testing.T) {
+	got, err := collect(func(fn func(Record) error) error {
+		return ReadFile(filepath.Join("testdata", "verses.arrows"), fn)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameRecords(got, sampleRecords()) {
+		t.Errorf("read\n%+v\nwant\n%+v", got, sampleRecords())
+	}
+}
+
+func TestReadArrowMalformed(t *testing.T) {
+	var buf bytes.Buffer
+	writeAll(t, NewArrowWriter(&buf, 2), sampleRecords())
+	stream := buf.Bytes()
+
+	var jsonl bytes.Buffer
+	writeAll(t, NewJSONLWriter(&jsonl), sampleRecords())
+
+	tests := []struct {
+		name   string
+		stream []byte
+	}{
+		{"truncated body", stream[:len(stream)-20]},
+		{"truncated framing", stream[:6]},
+		{"not Arrow", jsonl.Bytes()},
+		{"batch before schema", stream[8+binary.LittleEndian.Uint32(stream[4:]):]},
+	}
+	for _, tt := range tests {
+		if _, err := collect(func(fn func(Record) error) error { return ReadArrow(bytes.NewReader(tt.stream), fn) }); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+func TestCreateAndReadFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"verses.jsonl", "verses.arrows", "nested/verses.arrow"} {
+		path := filepath.Join(dir, name)
+		w, err := Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeAll(t, w, sampleRecords())
+
+		got, err := collect(func(fn func(Record) error) error { return ReadFile(path, fn) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameRecords(got, sampleRecords()) {
+			t.Errorf("%s: read back %d records, want %d", name, len(got), len(sampleRecords()))
+		}
+	}
+
+	if _, err := Create(filepath.Join(dir, "verses.csv")); err == nil {
+		t.Error("Create(verses.csv) made a writer, want an unknown format error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "verses.csv")); !os.IsNotExist(err) {
+		t.Errorf("Create(verses.csv) left a file behind: %v", err)
+	}
+}
+
+func TestConvertTree(t *testing.T) {
+	root, annotationRoot := t.TempDir(), t.TempDir()
+
+	genesis := filepath.Join(root, "tgl", "tgl_GEN_Genesis_001.txt")
+	if err := types.WriteChapterFile(genesis, []types.Verse{
+		{Number: types.VerseNumber{Start: 1, End: 1}, Text: "Nang pasimula."},
+		{Number: types.VerseNumber{Start: 2, End: 3}, Text: "At sinabi ng Dios."},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2024, 6, 1, 8, 0, 0, 0, time.FixedZone("PHT", 8*60*60))
+	if err := os.Chtimes(genesis, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	john := filepath.Join(root, "ceb", "ceb_JHN_John_011.txt")
+	if err := types.WriteChapterFile(john, []types.Verse{
+		{Number: types.VerseNumber{Start: 35, End: 35}, Text: "Mihilak si Jesus."},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sidecar := types.ChapterAnnotations{URL: "https://www.bible.com/bible/562/JHN.11.RCPV", ScrapedAt: scrapedAt}
+	if err := types.WriteAnnotationFile(filepath.Join(annotationRoot, "ceb", "ceb_JHN_John_011.json"), sidecar); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	out := NewJSONLWriter(&buf)
+	n, err := ConvertTree(root, annotationRoot, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := collect(func(fn func(Record) error) error { return ReadJSONL(&buf, fn) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Record{
+		{Lang: "ceb", Book: "JHN", Chapter: 11, Verse: types.VerseNumber{Start: 35, End: 35}, Text: "Mihilak si Jesus.", SourceURL: sidecar.URL, ScrapedAt: scrapedAt},
+		{Lang: "tgl", Book: "GEN", Chapter: 1, Verse: types.VerseNumber{Start: 1, End: 1}, Text: "Nang pasimula.", ScrapedAt: modified},
+		{Lang: "tgl", Book: "GEN", Chapter: 1, Verse: types.VerseNumber{Start: 2, End: 3}, Text: "At sinabi ng Dios.", ScrapedAt: modified},
+	}
+	if n != len(want) || !sameRecords(got, want) {
+		t.Fatalf("converted %d\n%+v\nwant\n%+v", n, got, want)
+	}
+	if got[1].ScrapedAt.Location() != time.UTC {
+		t.Errorf("modification time in %v, want UTC", got[1].ScrapedAt.Location())
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "tgl", "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ConvertTree(root, annotationRoot, NewJSONLWriter(&buf)); err == nil || !strings.Contains(err.Error(), "notes.txt") {
+		t.Errorf("error %v, want one naming notes.txt", err)
+	}
+}
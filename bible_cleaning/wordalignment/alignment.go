@@ -0,0 +1,149 @@
+package wordalignment
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Link aligns the source word at index Source to the target word at index Target, from 0.
+type Link struct {
+	Source, Target int
+}
+
+// Alignment is the links of a pair, in Pharaoh order: by source index, then target index.
+type Alignment []Link
+
+// String writes the alignment in Pharaoh format: "0-0 1-2 2-1".
+func (a Alignment) String() string {
+	parts := make([]string, len(a))
+	for i, link := range a {
+		parts[i] = fmt.Sprintf("%d-%d", link.Source, link.Target)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParsePharaoh reads a line written by Alignment.String.
+func ParsePharaoh(line string) (Alignment, error) {
+	var a Alignment
+	for _, field := range strings.Fields(line) {
+		s, t, ok := strings.Cut(field, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid link %q", field)
+		}
+		source, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link %q", field)
+		}
+		target, err := strconv.Atoi(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link %q", field)
+		}
+		a = append(a, Link{source, target})
+	}
+	return a, nil
+}
+
+// Reverse swaps the sides of every link, for an alignment trained target to source.
+func (a Alignment) Reverse() Alignment {
+	reversed := make(Alignment, len(a))
+	for i, link := range a {
+		reversed[i] = Link{Source: link.Target, Target: link.Source}
+	}
+	reversed.sort()
+	return reversed
+}
+
+func (a Alignment) sort() {
+	slices.SortFunc(a, func(x, y Link) int {
+		return cmp.Or(cmp.Compare(x.Source, y.Source), cmp.Compare(x.Target, y.Target))
+	})
+}
+
+/*
+GrowDiagFinal symmetrizes the alignments of both directions of a pair of srcLen
+and tgtLen words: it starts from their intersection, grows it with the links of
+their union next to it (diagonals included) that align a word not yet aligned,
+then adds the remaining links of each direction that do. Both alignments are
+source to target; reverse the target to source one first.
+As in "Statistical Phrase-Based Translation" by Philipp Koehn, Franz Josef Och
+and Daniel Marcu. https://aclanthology.org/N03-1017.pdf
+*/
+func GrowDiagFinal(forward, reverse Alignment, srcLen, tgtLen int) Alignment {
+	inForward := make(map[Link]bool, len(forward))
+	for _, link := range forward {
+		inForward[link] = true
+	}
+	union := make(map[Link]bool, len(forward)+len(reverse))
+	for _, link := range forward {
+		union[link] = true
+	}
+	for _, link := range reverse {
+		union[link] = true
+	}
+
+	aligned := make(map[Link]bool)
+	srcAligned := make([]bool, srcLen)
+	tgtAligned := make([]bool, tgtLen)
+	add := func(link Link) {
+		aligned[link] = true
+		srcAligned[link.Source] = true
+		tgtAligned[link.Target] = true
+	}
+	for _, link := range reverse {
+		if inForward[link] {
+			add(link)
+		}
+	}
+
+	// grow-diag: neighbours of aligned links, until nothing is added
+	neighbours := []Link{{-1, 0}, {0, -1}, {1, 0}, {0, 1}, {-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+	for grown := true; grown; {
+		grown = false
+		for s := range srcLen {
+			for t := range tgtLen {
+				if !aligned[Link{s, t}] {
+					continue
+				}
+				for _, d := range neighbours {
+					next := Link{s + d.Source, t + d.Target}
+					if next.Source < 0 || next.Source >= srcLen || next.Target < 0 || next.Target >= tgtLen {
+						continue
+					}
+					if !aligned[next] && union[next] && (!srcAligned[next.Source] || !tgtAligned[next.Target]) {
+						add(next)
+						grown = true
+					}
+				}
+			}
+		}
+	}
+
+	// final: links of either direction for words still unaligned
+	for _, direction := range []Alignment{forward, reverse} {
+		for _, link := range direction {
+			if !aligned[link] && (!srcAligned[link.Source] || !tgtAligned[link.Target]) {
+				add(link)
+			}
+		}
+	}
+
+	symmetric := make(Alignment, 0, len(aligned))
+	for link := range aligned {
+		symmetric = append(symmetric, link)
+	}
+	symmetric.sort()
+	return symmetric
+}
+
+func sortLexicalEntries(entries []LexicalEntry) {
+	slices.SortFunc(entries, func(a, b LexicalEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.Source, b.Source),
+			cmp.Compare(b.Prob, a.Prob),
+			cmp.Compare(a.Target, b.Target),
+		)
+	})
+}
@@ -0,0 +1,117 @@
+package wordalignment
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// AlignedCorpus is the word alignment of a parallel corpus entry.
+type AlignedCorpus struct {
+	SourceLang, TargetLang string
+	Forward                *Translation // p(t|s)
+	Backward               *Translation // p(s|t), nil without symmetrization
+	Alignments             []Alignment  // one per pair of the entry, in order
+}
+
+/*
+AlignEntry trains the model on the pairs of an entry and aligns them. With
+grow-diag-final it trains a model in each direction and symmetrizes their
+alignments; otherwise the alignments are the source to target model's.
+*/
+func AlignEntry(entry *types.ParallelCorpusEntry, cfg Config) *AlignedCorpus {
+	pairs := SentencePairs(entry)
+	aligned := &AlignedCorpus{
+		SourceLang: entry.SourceLang,
+		TargetLang: entry.TargetLang,
+		Forward:    Train(pairs, cfg),
+		Alignments: make([]Alignment, len(pairs)),
+	}
+
+	if cfg.Symmetrization == SymmetrizeGrowDiagFinal {
+		reversed := make([]SentencePair, len(pairs))
+		for i, pair := range pairs {
+			reversed[i] = SentencePair{Source: pair.Target, Target: pair.Source}
+		}
+		aligned.Backward = Train(reversed, cfg)
+	}
+
+	for i, pair := range pairs {
+		forward := aligned.Forward.Align(pair)
+		if aligned.Backward == nil {
+			aligned.Alignments[i] = forward
+			continue
+		}
+		backward := aligned.Backward.Align(SentencePair{Source: pair.Target, Target: pair.Source}).Reverse()
+		aligned.Alignments[i] = GrowDiagFinal(forward, backward, len(pair.Source), len(pair.Target))
+	}
+	return aligned
+}
+
+/*
+Save writes to outDir "src_tgt.align", the Pharaoh alignments line by line in
+the order of the entry's pairs, and "src_tgt.lex.tsv" with p(t|s) at or above
+minProb; "tgt_src.lex.tsv" with p(s|t) too when both directions were trained.
+A reverse table left from an earlier run is removed otherwise.
+*/
+func (c *AlignedCorpus) Save(outDir string, minProb float64) error {
+	if err := os.MkdirAll(outDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s", c.SourceLang, c.TargetLang)
+	if err := writeLines(filepath.Join(outDir, name+".align"), func(w *bufio.Writer) error {
+		for _, a := range c.Alignments {
+			if _, err := w.WriteString(a.String() + "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := writeLexicalTable(filepath.Join(outDir, name+".lex.tsv"), c.Forward, minProb); err != nil {
+		return err
+	}
+	backwardPath := filepath.Join(outDir, fmt.Sprintf("%s_%s.lex.tsv", c.TargetLang, c.SourceLang))
+	if c.Backward == nil {
+		// a table from an earlier, symmetrized run would no longer match the alignments
+		if err := os.Remove(backwardPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return writeLexicalTable(backwardPath, c.Backward, minProb)
+}
+
+func writeLexicalTable(path string, m *Translation, minProb float64) error {
+	return writeLines(path, func(w *bufio.Writer) error {
+		if _, err := w.WriteString("source\ttarget\tprob\n"); err != nil {
+			return err
+		}
+		for _, e := range m.LexicalTable(minProb) {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%.6f\n", e.Source, e.Target, e.Prob); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func writeLines(path string, write func(*bufio.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if err := write(w); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,317 @@
+package wordalignment
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// Model is the alignment model trained, by its name on the command line.
+type Model string
+
+const (
+	ModelIBM1 Model = "ibm1" // every source position equally likely
+	ModelIBM2 Model = "ibm2" // fast_align's reparameterization: a prior favouring the diagonal
+)
+
+// Symmetrization combines the alignments of both directions.
+type Symmetrization string
+
+const (
+	SymmetrizeGrowDiagFinal Symmetrization = "grow-diag-final"
+	SymmetrizeNone          Symmetrization = "none" // source to target only
+)
+
+// Config chooses the model, its training and the symmetrization.
+type Config struct {
+	Model          Model
+	Iterations     int     // EM iterations
+	Tension        float64 // IBM2: how sharply the prior favours the diagonal
+	NullProb       float64 // IBM2: prior probability of aligning a word to NULL
+	Symmetrization Symmetrization
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Model:          config.WORD_ALIGNMENT_MODEL,
+		Iterations:     config.WORD_ALIGNMENT_ITERATIONS,
+		Tension:        config.WORD_ALIGNMENT_TENSION,
+		NullProb:       config.WORD_ALIGNMENT_NULL_PROB,
+		Symmetrization: config.WORD_ALIGNMENT_SYMMETRIZATION,
+	}
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Model != ModelIBM1 && cfg.Model != ModelIBM2 {
+		return fmt.Errorf("unknown word alignment model %q", cfg.Model)
+	}
+	if cfg.Iterations < 1 {
+		return fmt.Errorf("iterations must be at least 1, got %d", cfg.Iterations)
+	}
+	if cfg.Tension <= 0 {
+		return fmt.Errorf("tension must be positive, got %g", cfg.Tension)
+	}
+	if cfg.NullProb < 0 || cfg.NullProb >= 1 {
+		return fmt.Errorf("NULL probability must be from 0 to below 1, got %g", cfg.NullProb)
+	}
+	if cfg.Symmetrization != SymmetrizeGrowDiagFinal && cfg.Symmetrization != SymmetrizeNone {
+		return fmt.Errorf("unknown symmetrization %q", cfg.Symmetrization)
+	}
+	return nil
+}
+
+/*
+Tokenize splits a text into the words the aligner aligns: lowercased, with
+the punctuation around them trimmed. Pharaoh indices count these words.
+*/
+func Tokenize(text string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		if word := strings.TrimFunc(field, unicode.IsPunct); word != "" {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// SentencePair is a tokenized pair of a parallel corpus.
+type SentencePair struct {
+	Source, Target []string
+}
+
+/*
+SentencePairs tokenizes the pairs of an entry in order. A pair with a side
+labelled as a missing translation is left empty, so it keeps its place but
+has nothing to align.
+*/
+func SentencePairs(entry *types.ParallelCorpusEntry) []SentencePair {
+	pairs := make([]SentencePair, len(entry.Pairs))
+	for i, pair := range entry.Pairs {
+		if pair.SourceText == config.TOKEN_MISSING_TRANSLATION || pair.TargetText == config.TOKEN_MISSING_TRANSLATION {
+			continue
+		}
+		pairs[i] = SentencePair{Tokenize(pair.SourceText), Tokenize(pair.TargetText)}
+	}
+	return pairs
+}
+
+// vocabulary numbers the words of one side; 0 is NULL.
+type vocabulary struct {
+	ids   map[string]int
+	words []string
+}
+
+func newVocabulary() *vocabulary {
+	return &vocabulary{ids: map[string]int{"": 0}, words: []string{""}}
+}
+
+func (v *vocabulary) id(word string) int {
+	id, ok := v.ids[word]
+	if !ok {
+		id = len(v.words)
+		v.ids[word] = id
+		v.words = append(v.words, word)
+	}
+	return id
+}
+
+/*
+Translation is a word alignment model of p(t|s): every target word is generated
+by one source word or by NULL. Based on "A Simple, Fast, and Effective
+Reparameterization of IBM Model 2" by Chris Dyer, Victor Chahuneau and Noah A. Smith.
+https://aclanthology.org/N13-1073.pdf
+*/
+type Translation struct {
+	Config         Config
+	source, target *vocabulary
+	table          map[uint64]float64 // p(t|s) by source and target word ID
+}
+
+func tableKey(s, t int) uint64 {
+	return uint64(s)<<32 | uint64(t)
+}
+
+func (m *Translation) prob(s, t int) float64 {
+	if p, ok := m.table[tableKey(s, t)]; ok {
+		return p
+	}
+	return 1 / float64(len(m.target.words)-1) // uniform before the first iteration
+}
+
+// Prob is p(t|s) of two words, s "" for NULL.
+func (m *Translation) Prob(s, t string) float64 {
+	sid, ok := m.source.ids[s]
+	if !ok {
+		return 0
+	}
+	tid, ok := m.target.ids[t]
+	if !ok {
+		return 0
+	}
+	return m.table[tableKey(sid, tid)]
+}
+
+/*
+diagonalPrior is the probability of aligning target position i (1-based, of m)
+to each source position j of n, NULL at 0. Under IBM1 every position, NULL
+included, is equally likely.
+*/
+func (cfg Config) diagonalPrior(i, m, n int, prior []float64) {
+	if cfg.Model == ModelIBM1 {
+		for j := range prior {
+			prior[j] = 1 / float64(n+1)
+		}
+		return
+	}
+
+	z := 0.0
+	for j := 1; j <= n; j++ {
+		prior[j] = math.Exp(-cfg.Tension * math.Abs(float64(i)/float64(m)-float64(j)/float64(n)))
+		z += prior[j]
+	}
+	prior[0] = cfg.NullProb
+	for j := 1; j <= n; j++ {
+		prior[j] *= (1 - cfg.NullProb) / z
+	}
+}
+
+// Train fits p(t|s) on the pairs by expectation maximization.
+func Train(pairs []SentencePair, cfg Config) *Translation {
+	m := &Translation{
+		Config: cfg,
+		source: newVocabulary(),
+		target: newVocabulary(),
+		table:  make(map[uint64]float64),
+	}
+
+	encoded := make([][2][]int, len(pairs))
+	for k, pair := range pairs {
+		if len(pair.Source) == 0 || len(pair.Target) == 0 {
+			continue
+		}
+		src := []int{0} // NULL
+		for _, w := range pair.Source {
+			src = append(src, m.source.id(w))
+		}
+		tgt := make([]int, len(pair.Target))
+		for i, w := range pair.Target {
+			tgt[i] = m.target.id(w)
+		}
+		encoded[k] = [2][]int{src, tgt}
+	}
+
+	counts := make(map[uint64]float64)
+	totals := make([]float64, len(m.source.words))
+	var prior, posterior []float64
+
+	for range cfg.Iterations {
+		clear(counts)
+		clear(totals)
+
+		// E-step: expected counts of every target word generated by every source word
+		for _, pair := range encoded {
+			src, tgt := pair[0], pair[1]
+			if len(tgt) == 0 {
+				continue
+			}
+			prior = resize(prior, len(src))
+			posterior = resize(posterior, len(src))
+
+			for i, t := range tgt {
+				cfg.diagonalPrior(i+1, len(tgt), len(src)-1, prior)
+				z := 0.0
+				for j, s := range src {
+					posterior[j] = prior[j] * m.prob(s, t)
+					z += posterior[j]
+				}
+				if z == 0 {
+					continue
+				}
+				for j, s := range src {
+					c := posterior[j] / z
+					counts[tableKey(s, t)] += c
+					totals[s] += c
+				}
+			}
+		}
+
+		// M-step
+		clear(m.table)
+		for key, c := range counts {
+			m.table[key] = c / totals[key>>32]
+		}
+	}
+	return m
+}
+
+func resize(s []float64, n int) []float64 {
+	if cap(s) < n {
+		return make([]float64, n)
+	}
+	return s[:n]
+}
+
+/*
+Align links every target word to its most probable source word under the
+model, leaving it unaligned if that is NULL.
+*/
+func (m *Translation) Align(pair SentencePair) Alignment {
+	n := len(pair.Source)
+	if n == 0 {
+		return nil
+	}
+	prior := make([]float64, n+1)
+
+	var links Alignment
+	for i, word := range pair.Target {
+		t, ok := m.target.ids[word]
+		if !ok {
+			continue
+		}
+		m.Config.diagonalPrior(i+1, len(pair.Target), n, prior)
+
+		best, bestProb := 0, prior[0]*m.Prob("", word)
+		for j, sourceWord := range pair.Source {
+			s, ok := m.source.ids[sourceWord]
+			if !ok {
+				continue
+			}
+			if p := prior[j+1] * m.table[tableKey(s, t)]; p > bestProb {
+				best, bestProb = j+1, p
+			}
+		}
+		if best > 0 {
+			links = append(links, Link{Source: best - 1, Target: i})
+		}
+	}
+	links.sort()
+	return links
+}
+
+// LexicalEntry is a row of a lexical translation table.
+type LexicalEntry struct {
+	Source, Target string
+	Prob           float64 // p(target|source)
+}
+
+/*
+LexicalTable lists p(t|s) of every word pair at or above minProb, NULL left
+out, by source word and then most probable target first.
+*/
+func (m *Translation) LexicalTable(minProb float64) []LexicalEntry {
+	var entries []LexicalEntry
+	for key, p := range m.table {
+		s, t := int(key>>32), int(key&math.MaxUint32)
+		if s == 0 || p < minProb {
+			continue
+		}
+		entries = append(entries, LexicalEntry{m.source.words[s], m.target.words[t], p})
+	}
+
+	sortLexicalEntries(entries)
+	return entries
+}
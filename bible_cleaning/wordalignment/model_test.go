@@ -0,0 +1,97 @@
+package wordalignment
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/zrygan.nlp/bible_cleaning/types"
+)
+
+// The corpus of Koehn's "Statistical Machine Translation", 4.2: each word is only told apart by the pairs it shares.
+var (
+	toyCorpus = []SentencePair{
+		{Source: []string{"das", "haus"}, Target: []string{"the", "house"}},
+		{Source: []string{"das", "buch"}, Target: []string{"the", "book"}},
+		{Source: []string{"ein", "buch"}, Target: []string{"a", "book"}},
+	}
+	toyTranslations = map[string]string{"das": "the", "haus": "house", "buch": "book", "ein": "a"}
+	toyTargets      = []string{"the", "house", "book", "a"}
+)
+
+func toyConfig(model Model) Config {
+	cfg := DefaultConfig()
+	cfg.Model = model
+	cfg.Iterations = 5
+	return cfg
+}
+
+func TestTrainLearnsTranslations(t *testing.T) {
+	for _, model := range []Model{ModelIBM1, ModelIBM2} {
+		t.Run(string(model), func(t *testing.T) {
+			m := Train(toyCorpus, toyConfig(model))
+			for s, want := range toyTranslations {
+				for _, other := range toyTargets {
+					if other != want && m.Prob(s, want) <= m.Prob(s, other) {
+						t.Errorf("p(%s|%s) = %.3f, not above p(%s|%s) = %.3f", want, s, m.Prob(s, want), other, s, m.Prob(s, other))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAlignEntryPharaoh(t *testing.T) {
+	entry := &types.ParallelCorpusEntry{SourceLang: "deu", TargetLang: "eng"}
+	verse := func(v int) types.PairID {
+		return types.PairID{Book: "GEN", Chapter: 1, Verse: types.VerseNumber{Start: v, End: v}}
+	}
+	for i, pair := range toyCorpus {
+		entry.Pairs = append(entry.Pairs, types.NewTextPair(verse(i+1), strings.Join(pair.Source, " "), strings.Join(pair.Target, " ")))
+	}
+	entry.Pairs = append(entry.Pairs, types.NewTextPair(verse(4), "Das Buch, das Haus.", "The book, the house."))
+
+	want := []string{"0-0 1-1", "0-0 1-1", "0-0 1-1", "0-0 1-1 2-2 3-3"}
+	for _, symmetrization := range []Symmetrization{SymmetrizeNone, SymmetrizeGrowDiagFinal} {
+		t.Run(string(symmetrization), func(t *testing.T) {
+			cfg := toyConfig(ModelIBM2) // the diagonal tells the two "das" apart
+			cfg.Symmetrization = symmetrization
+
+			aligned := AlignEntry(entry, cfg)
+			got := make([]string, len(aligned.Alignments))
+			for i, a := range aligned.Alignments {
+				got[i] = a.String()
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("alignments %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+/*
+A hand-worked grow-diag-final of 4 source and 5 target words:
+
+  - the intersection is 0-0 1-1 3-3;
+  - grow-diag adds 2-1 next to 1-1 for the unaligned source word 2, then 3-2
+    diagonal to 2-1 for the unaligned target word 2;
+  - 1-0 is next to 0-0 but both its words are aligned by then, so it is left out;
+  - final adds 0-4, next to no link, for the unaligned target word 4.
+*/
+func TestGrowDiagFinal(t *testing.T) {
+	forward, err := ParsePharaoh("0-0 0-4 1-1 2-1 3-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reverse, err := ParsePharaoh("0-0 1-0 1-1 3-2 3-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := GrowDiagFinal(forward, reverse, 4, 5).String(), "0-0 0-4 1-1 2-1 3-2 3-3"; got != want {
+		t.Errorf("grow-diag-final %q, want %q", got, want)
+	}
+	if got, want := GrowDiagFinal(forward, forward, 4, 5).String(), forward.String(); got != want {
+		t.Errorf("grow-diag-final of an alignment with itself %q, want it unchanged %q", got, want)
+	}
+}
@@ -2,4 +2,9 @@ module language_similarity
 
 go 1.24.1
 
-require github.com/twuillemin/doublemetaphone v0.2.0 // indirect
+require (
+	github.com/twuillemin/doublemetaphone v0.2.0
+	github.com/zrygan.nlp/bible_cleaning v0.0.0
+)
+
+replace github.com/zrygan.nlp/bible_cleaning => ../bible_cleaning
@@ -1,85 +1,127 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 
+	"github.com/zrygan.nlp/bible_cleaning/biblecorpus"
+	"github.com/zrygan.nlp/bible_cleaning/canon"
+	"github.com/zrygan.nlp/bible_cleaning/config"
+	"github.com/zrygan.nlp/bible_cleaning/manifest"
 	similaritymatrix "language_similarity/similaritymatrix"
 )
 
-// i stole this function from the bible
-func IndexLanguageFileMap(root string) (map[string]map[string]string, error) {
-	files, err := filepath.Glob(root + "/*/*.txt")
+// similarityOptions are the flags shared by the similarity subcommands
+type similarityOptions struct {
+	corpus   *biblecorpus.Index
+	outPath  string
+	manifest *manifest.Manifest
+	build    *manifest.Build // the matrix as the manifest records it
+	force    bool
+}
+
+// parseSimilarityFlags opens the corpus for a similarity subcommand
+func parseSimilarityFlags(name string, args []string) *similarityOptions {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	corpusDir := fs.String("corpus", filepath.Join("..", "bible_cleaning", config.CORPUS_VERSES_FOLDER), "by_verses corpus of bible_cleaning")
+	bookSpec := fs.String("books", "all", "books to compare: codes or ot, nt, dc, protestant, all")
+	force := fs.Bool("force", false, "rebuild the matrix even if the manifest has it up to date")
+	fs.Parse(args)
+
+	books, err := canon.Select(*bookSpec)
 	if err != nil {
-		return nil, err
+		panic(err)
 	}
 
-	re := regexp.MustCompile(`^([a-z]+)_([A-Z]+)_[^_]+_(\d+)\.txt$`)
-	index := make(map[string]map[string]string)
+	corpus, err := biblecorpus.Open(*corpusDir)
+	if err != nil {
+		panic(err)
+	}
+	for _, path := range corpus.Skipped {
+		fmt.Println("Skipping:", path)
+	}
 
-	for _, file := range files {
-		base := filepath.Base(file)
-		lang := filepath.Base(filepath.Dir(file))
+	corpus = corpus.WithBooks(books)
+	if len(corpus.Languages()) == 0 {
+		panic(fmt.Sprintf("No chapters of the selected books found in %s", *corpusDir))
+	}
 
-		matches := re.FindStringSubmatch(base)
-		if matches == nil {
-			continue
-		}
-		book := matches[2]
-		verse := matches[3]
-		verseID := book + "_" + verse
+	outPath := "similaritymatrix/" + name + "_similarity_matrix.tsv"
 
-		if _, ok := index[lang]; !ok {
-			index[lang] = make(map[string]string)
-		}
-		index[lang][verseID] = file
+	m, err := manifest.Load(config.MANIFEST_FILE)
+	if err != nil {
+		panic(err)
+	}
+	build, err := m.Plan(name+" similarity", outPath, []string{*corpusDir}, struct {
+		Books string `json:"books"`
+	}{*bookSpec})
+	if err != nil {
+		panic(err)
 	}
 
-	return index, nil
+	return &similarityOptions{corpus: corpus, outPath: outPath, manifest: m, build: build, force: *force}
 }
 
-func buildOrthographicSimilarityMatrix() {
-    fmt.Println("Loading trigram counts...")
-    index, err := IndexLanguageFileMap("../bible_cleaning/corpus/by_verses")
-    if err != nil {
-        panic(err)
-    }
-	fmt.Println("Building trigram counts...")
+// upToDate reports whether the matrix can be kept as it is, saying why it is rebuilt otherwise
+func (opts *similarityOptions) upToDate() bool {
+	reason := opts.manifest.Stale(opts.build)
+	if reason == "" && !opts.force {
+		fmt.Printf("Skipping %s: up to date\n", opts.outPath)
+		return true
+	}
+	if reason != "" {
+		fmt.Printf("Building %s: %s\n", opts.outPath, reason)
+	}
+	return false
+}
 
-    trigramCounts, err := similaritymatrix.BuildTrigramCounts(index)
-    if err != nil {
-        panic(err)
-    }
+// record notes the saved matrix in the manifest
+func (opts *similarityOptions) record() {
+	if err := opts.manifest.Record(opts.build); err != nil {
+		panic(err)
+	}
+	if err := opts.manifest.Save(); err != nil {
+		panic(err)
+	}
+}
 
-	fmt.Println("Building cosine similarity matrix...")
+func buildOrthographicSimilarityMatrix(opts *similarityOptions) {
+	if opts.upToDate() {
+		return
+	}
+	fmt.Println("Building trigram counts...")
 
-    matrix := similaritymatrix.BuildCosineSimilarityMatrix(trigramCounts)
+	trigramCounts, err := similaritymatrix.BuildTrigramCounts(opts.corpus)
+	if err != nil {
+		panic(err)
+	}
 
-    outPath := "similaritymatrix/orthographic_similarity_matrix.tsv"
-    if err := similaritymatrix.SaveOrthographicMatrix(matrix, outPath); err != nil {
-        panic(err)
-    }
+	matrix := similaritymatrix.BuildCosineSimilarityMatrix(trigramCounts)
+
+	if err := similaritymatrix.SaveOrthographicMatrix(matrix, opts.outPath); err != nil {
+		panic(err)
+	}
+	opts.record()
 }
 
-func buildPhoneticSimilarityMatrix() {
-    index, err := IndexLanguageFileMap("../bible_cleaning/corpus/by_verses")
-    if err != nil {
-        panic(err)
-    }
+func buildPhoneticSimilarityMatrix(opts *similarityOptions) {
+	if opts.upToDate() {
+		return
+	}
+	trigramCounts, err := similaritymatrix.BuildTrigramCounts(opts.corpus)
+	if err != nil {
+		panic(err)
+	}
 
-	trigramCounts, err := similaritymatrix.BuildTrigramCounts(index)
-    if err != nil {
-        panic(err)
-    }
 	phoneticSets := similaritymatrix.BuildPhoneticCountsFromTrigrams(trigramCounts)
-    matrix := similaritymatrix.BuildPhoneticSimilarityMatrix(phoneticSets)
+	matrix := similaritymatrix.BuildPhoneticSimilarityMatrix(phoneticSets)
 
-    outPath := "similaritymatrix/phonetic_similarity_matrix.tsv"
-    if err := similaritymatrix.SavePhoneticMatrix(matrix, outPath); err != nil {
-        panic(err)
-    }
+	if err := similaritymatrix.SavePhoneticMatrix(matrix, opts.outPath); err != nil {
+		panic(err)
+	}
+	opts.record()
 }
 
 func main() {
@@ -90,9 +132,9 @@ func main() {
 
 	switch os.Args[1] {
 	case "orthographic":
-		buildOrthographicSimilarityMatrix()
+		buildOrthographicSimilarityMatrix(parseSimilarityFlags("orthographic", os.Args[2:]))
 	case "phonetic":
-		buildPhoneticSimilarityMatrix()
+		buildPhoneticSimilarityMatrix(parseSimilarityFlags("phonetic", os.Args[2:]))
 	default:
 		panic("Non-exaustive switch-case or argument not found.")
 	}
@@ -1,33 +1,31 @@
 package similaritymatrix
 
 import (
-	"bufio"
 	"fmt"
+	"iter"
 	"math"
-	"os"
 	"strings"
-)
 
-// load all words from the corpus
-func LoadCorpusWords(index map[string]map[string]string) map[string][]string {
-	langs := make(map[string][]string)
+	"github.com/zrygan.nlp/bible_cleaning/biblecorpus"
+)
 
-	for lang, verseMap := range index {
-		for _, path := range verseMap {
-			content, err := os.ReadFile(path)
+// streams the words of a language, one verse at a time
+func CorpusWords(corpus *biblecorpus.Index, lang string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for verse, err := range corpus.Verses(lang) {
 			if err != nil {
-				continue
+				yield("", err)
+				return
+			}
+			for _, word := range strings.Fields(verse.Text) {
+				if !yield(word, nil) {
+					return
+				}
 			}
-			text := string(content)
-			words := strings.Fields(text)
-			langs[lang] = append(langs[lang], words...)
 		}
 	}
-
-	return langs
 }
 
-
 // gets trigrams of a word and returns it in an array
 func GetTrigrams(word string) []string {
 	padded := "  " + strings.ToLower(word) + " "
@@ -38,45 +36,29 @@ func GetTrigrams(word string) []string {
 	return trigrams
 }
 
-// traverses the index and builds trigram frequencies per language
-func BuildTrigramCounts(index map[string]map[string]string) (map[string]map[string]int, error) {
-	fmt.Printf("Starting trigram count build. Total languages: %d\n", len(index))
-    trigramCounts := make(map[string]map[string]int)
-    
-    for lang, fileMap := range index {
-        fmt.Printf("Processing language: %s (%d files)\n", lang, len(fileMap))
-        trigramCounts[lang] = make(map[string]int)
-
-        for _, filePath := range fileMap {
-			fmt.Printf("  -> Reading file: (%s)\n", filePath)
-
-            file, err := os.Open(filePath)
-            if err != nil {
-                return nil, fmt.Errorf("failed to open %s: %v", filePath, err)
-            }
-
-            scanner := bufio.NewScanner(file)
-            for scanner.Scan() {
-                line := strings.TrimSpace(scanner.Text())
-                if line == "" {
-                    continue
-                }
-
-                words := strings.Fields(line)
-                for _, word := range words {
-                    for _, tri := range GetTrigrams(word) {
-                        trigramCounts[lang][tri]++
-                    }
-                }
-            }
-
-            file.Close()
-        }
+// traverses the corpus and builds trigram frequencies per language
+func BuildTrigramCounts(corpus *biblecorpus.Index) (map[string]map[string]int, error) {
+	langs := corpus.Languages()
+	fmt.Printf("Starting trigram count build. Total languages: %d\n", len(langs))
+	trigramCounts := make(map[string]map[string]int)
 
-        fmt.Printf("Done %s: %d unique trigrams\n", lang, len(trigramCounts[lang]))
-    }
+	for _, lang := range langs {
+		fmt.Printf("Processing language: %s (%d files)\n", lang, len(corpus.Chapters(lang)))
+		trigramCounts[lang] = make(map[string]int)
+
+		for word, err := range CorpusWords(corpus, lang) {
+			if err != nil {
+				return nil, err
+			}
+			for _, tri := range GetTrigrams(word) {
+				trigramCounts[lang][tri]++
+			}
+		}
+
+		fmt.Printf("Done %s: %d unique trigrams\n", lang, len(trigramCounts[lang]))
+	}
 
-    return trigramCounts, nil
+	return trigramCounts, nil
 }
 
 // computes the Jaccard similarity